@@ -0,0 +1,49 @@
+package actions
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/dag"
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/stores/builds"
+)
+
+// newTestRepo writes files below a new repo root and loads its config and
+// target graph.
+func newTestRepo(t *testing.T, files map[string]string) (*config.Config, *dag.Graph) {
+	t.Helper()
+	root := t.TempDir()
+	files["repo.yml"] = "shell: /bin/sh\n"
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	cfg := config.Load(root)
+	graph := dag.NewGraph()
+	for _, bundle := range cfg.Bundles() {
+		for _, target := range bundle.Targets {
+			graph.AddTarget(target)
+		}
+	}
+	require.NoError(t, graph.Resolve(cfg.Bundles()))
+	return cfg, graph
+}
+
+func newTestStore(t *testing.T, cfg *config.Config) *builds.Store {
+	t.Helper()
+	store := builds.NewStore(cfg.BuildsPath())
+	require.NoError(t, store.Load())
+	return store
+}
+
+func discardLogger() logger.Logger {
+	return logger.NewWithWriter(logger.ErrorLevel, io.Discard)
+}
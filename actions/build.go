@@ -7,10 +7,16 @@ import (
 
 	"github.com/vcnkl/rpm/config"
 	"github.com/vcnkl/rpm/dag"
+	"github.com/vcnkl/rpm/events"
 	"github.com/vcnkl/rpm/exec"
 	"github.com/vcnkl/rpm/logger"
 	"github.com/vcnkl/rpm/models"
+	"github.com/vcnkl/rpm/profile"
+	"github.com/vcnkl/rpm/secrets"
 	"github.com/vcnkl/rpm/stores/builds"
+	"github.com/vcnkl/rpm/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type BuildAction struct {
@@ -18,20 +24,24 @@ type BuildAction struct {
 	graph     *dag.Graph
 	store     *builds.Store
 	validator *builds.Validator
+	secrets   *secrets.Resolver
 	log       logger.Logger
+	output    *Output
 	parallel  int
 	force     bool
 	rebuilt   map[string]bool
 	rebuiltMu sync.RWMutex
 }
 
-func NewBuildAction(cfg *config.Config, graph *dag.Graph, store *builds.Store, log logger.Logger, parallel int, force bool) *BuildAction {
+func NewBuildAction(cfg *config.Config, graph *dag.Graph, store *builds.Store, log logger.Logger, output *Output, parallel int, force bool) *BuildAction {
 	return &BuildAction{
 		config:    cfg,
 		graph:     graph,
 		store:     store,
-		validator: builds.NewValidator(cfg.RepoRoot(), store),
+		validator: builds.NewValidator(cfg.RepoRoot(), store, cfg.Hasher()),
+		secrets:   secrets.NewResolver(cfg.RepoRoot(), cfg.Repo(), log),
 		log:       log,
+		output:    output,
 		parallel:  parallel,
 		force:     force,
 		rebuilt:   make(map[string]bool),
@@ -40,7 +50,7 @@ func NewBuildAction(cfg *config.Config, graph *dag.Graph, store *builds.Store, l
 
 func (a *BuildAction) Execute(ctx context.Context, targetIDs []string) (*models.Result, error) {
 	start := time.Now()
-	result := &models.Result{}
+	result := &models.Result{Durations: make(map[string]time.Duration)}
 
 	subgraph := a.graph.SubgraphFor(targetIDs)
 
@@ -49,16 +59,44 @@ func (a *BuildAction) Execute(ctx context.Context, targetIDs []string) (*models.
 		return nil, err
 	}
 
+	for _, node := range sorted {
+		a.output.Events().TargetQueued(node.ID, node.Target.Deps)
+	}
+
+	skipped := make(map[string]bool)
+	var resultMu sync.Mutex
+
 	executor := exec.NewParallelExecutor(a.parallel)
 	results := executor.Execute(ctx, sorted, func(ctx context.Context, node *dag.Node) error {
-		return a.buildTarget(ctx, node)
+		start := time.Now()
+		a.profileScheduling(ctx, node.ID)
+		defer func() { a.output.Profile().Add("busy workers", -1, time.Now()) }()
+
+		ctx, span := tracing.StartTarget(ctx, node.ID)
+		cached, err := a.buildTarget(ctx, node)
+		span.SetAttributes(attribute.Bool("rpm.cached", cached))
+		tracing.End(span, err)
+		resultMu.Lock()
+		result.Durations[node.ID] = time.Since(start)
+		skipped[node.ID] = cached
+		resultMu.Unlock()
+		if cached {
+			a.output.Events().TargetFinished(node.ID, events.StatusSkipped, 0, time.Since(start), nil)
+			return nil
+		}
+		a.output.targetFinished(node.ID, start, err)
+		return err
 	})
 
 	for id, err := range results {
-		if err != nil {
+		if skipped[id] {
+			result.Skipped = append(result.Skipped, id)
+		} else if err != nil {
+			a.output.notRun(id, err)
 			result.Failed = append(result.Failed, models.FailedTarget{
-				ID:    id,
-				Error: err,
+				ID:       id,
+				Error:    err,
+				ExitCode: exec.ExitCode(err),
 			})
 		} else {
 			result.Executed = append(result.Executed, id)
@@ -69,24 +107,38 @@ func (a *BuildAction) Execute(ctx context.Context, targetIDs []string) (*models.
 	return result, nil
 }
 
-func (a *BuildAction) buildTarget(ctx context.Context, node *dag.Node) error {
+func (a *BuildAction) buildTarget(ctx context.Context, node *dag.Node) (bool, error) {
 	target := node.Target
 	targetLog := a.log.WithPrefix(target.ID())
 
 	targetLog.Debug("checking cache", logger.String("input_hash_in_progress", "calculating"))
 
-	shouldBuild, inputHash, err := a.validator.ShouldBuild(target)
+	task, _ := exec.TaskFromContext(ctx)
+	prof := a.output.Profile()
+
+	hashStart := time.Now()
+	_, hashSpan := tracing.StartPhase(ctx, profile.PhaseHashing)
+	inputHash, err := a.validator.HashInputs(target)
+	tracing.End(hashSpan, err)
+	lookupStart := time.Now()
+	prof.Span(task.Slot, target.ID(), profile.PhaseHashing, hashStart, lookupStart)
+
+	shouldBuild := true
 	if err != nil {
 		targetLog.Warn("cache check failed", logger.Err(err))
-		shouldBuild = true
+	} else {
+		_, lookupSpan := tracing.StartPhase(ctx, profile.PhaseCacheLookup)
+		shouldBuild = !a.validator.IsCached(target, inputHash)
+		lookupSpan.End()
 	}
+	prof.Span(task.Slot, target.ID(), profile.PhaseCacheLookup, lookupStart, time.Now())
 
 	if !shouldBuild {
 		a.rebuiltMu.RLock()
-		for _, depID := range target.Deps {
-			if a.rebuilt[depID] {
+		for _, dep := range node.Deps {
+			if a.rebuilt[dep.ID] {
 				shouldBuild = true
-				targetLog.Debug("dependency was rebuilt", logger.String("dep", depID))
+				targetLog.Debug("dependency was rebuilt", logger.String("dep", dep.ID))
 				break
 			}
 		}
@@ -98,29 +150,46 @@ func (a *BuildAction) buildTarget(ctx context.Context, node *dag.Node) error {
 		logger.Bool("should_build", shouldBuild),
 		logger.Bool("force", a.force))
 
-	if !shouldBuild && !a.force {
+	cached := !shouldBuild && !a.force
+	a.output.Events().Cache(target.ID(), cached, inputHash)
+
+	if cached {
 		targetLog.Info("skipped (cached)")
-		return nil
+		return true, nil
 	}
 
 	targetLog.Info("building...")
 	buildStart := time.Now()
 
 	bundle := a.config.Bundles()[target.BundleName]
-	env := exec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target)
+	env, err := exec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target, a.secrets)
+	if err != nil {
+		targetLog.Error("failed to load environment", logger.Err(err))
+		return false, err
+	}
 	workDir := exec.ResolveWorkDir(a.config.RepoRoot(), target)
 
-	err = exec.RunCommand(ctx, target.Cmd, &exec.ShellOptions{
+	output := a.output.Open(target.ID(), targetLog)
+	cmdStart := time.Now()
+	prof.Add("running commands", 1, cmdStart)
+	cmdCtx, cmdSpan := tracing.StartPhase(ctx, profile.PhaseCommand)
+	err = exec.RunCommand(cmdCtx, target.Cmd, &exec.ShellOptions{
 		WorkDir: workDir,
-		Env:     env,
+		Env:     append(env, tracing.Environ(cmdCtx)...),
 		Shell:   a.config.Repo().Shell,
-		Stdout:  targetLog.Writer(),
-		Stderr:  targetLog.Writer(),
+		Stdout:  output.Stdout(),
+		Stderr:  output.Stderr(),
+		OnStart: a.output.onStart(target.ID(), target.Cmd, workDir),
 	})
+	output.Close()
+	tracing.End(cmdSpan, err)
+	cmdEnd := time.Now()
+	prof.Add("running commands", -1, cmdEnd)
+	prof.Span(task.Slot, target.ID(), profile.PhaseCommand, cmdStart, cmdEnd)
 
 	if err != nil {
-		targetLog.Error("build failed", logger.Err(err))
-		return err
+		targetLog.Error("build failed", logger.Err(err), logger.String("log", a.output.LogPath(target.ID())))
+		return false, err
 	}
 
 	duration := time.Since(buildStart)
@@ -141,7 +210,18 @@ func (a *BuildAction) buildTarget(ctx context.Context, node *dag.Node) error {
 
 	targetLog.Info("completed", logger.Duration("duration", duration))
 
-	return nil
+	return false, nil
+}
+
+func (a *BuildAction) profileScheduling(ctx context.Context, targetID string) {
+	task, ok := exec.TaskFromContext(ctx)
+	if !ok {
+		return
+	}
+	prof := a.output.Profile()
+	prof.Wait(targetID, profile.PhaseDependencyWait, task.Queued, task.Ready)
+	prof.Wait(targetID, profile.PhaseSlotWait, task.Ready, task.Started)
+	prof.Add("busy workers", 1, task.Started)
 }
 
 func (a *BuildAction) DryRun(targetIDs []string) {
@@ -156,7 +236,11 @@ func (a *BuildAction) DryRun(targetIDs []string) {
 	for _, node := range sorted {
 		target := node.Target
 		bundle := a.config.Bundles()[target.BundleName]
-		env := exec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target)
+		env, err := exec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target, secrets.NewUnresolved(a.config.Repo().Secrets))
+		if err != nil {
+			a.log.Error("failed to load environment", logger.String("id", target.ID()), logger.Err(err))
+			continue
+		}
 		workDir := exec.ResolveWorkDir(a.config.RepoRoot(), target)
 
 		a.log.Info("target", logger.String("id", target.ID()))
@@ -2,12 +2,8 @@ package actions
 
 import (
 	"context"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"strings"
+	"fmt"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/vcnkl/rpm/config"
@@ -15,20 +11,41 @@ import (
 	rpmexec "github.com/vcnkl/rpm/exec"
 	"github.com/vcnkl/rpm/logger"
 	"github.com/vcnkl/rpm/models"
-	"github.com/vcnkl/rpm/watcher"
+	"github.com/vcnkl/rpm/secrets"
+	"github.com/vcnkl/rpm/stores/builds"
+	"github.com/vcnkl/rpm/tracing"
 )
 
+type DevOptions struct {
+	Parallel int
+	// NoDeps starts only the requested targets, not the dev targets they
+	// depend on.
+	NoDeps bool
+}
+
 type DevAction struct {
-	config *config.Config
-	graph  *dag.Graph
-	log    logger.Logger
+	config     *config.Config
+	graph      *dag.Graph
+	store      *builds.Store
+	secrets    *secrets.Resolver
+	log        logger.Logger
+	output     *Output
+	opts       DevOptions
+	services   map[string]*devService
+	servicesMu sync.Mutex
+	errCh      chan models.FailedTarget
+	rebuildMu  sync.Mutex
 }
 
-func NewDevAction(cfg *config.Config, graph *dag.Graph, log logger.Logger) *DevAction {
+func NewDevAction(cfg *config.Config, graph *dag.Graph, store *builds.Store, log logger.Logger, output *Output, opts DevOptions) *DevAction {
 	return &DevAction{
-		config: cfg,
-		graph:  graph,
-		log:    log,
+		config:  cfg,
+		graph:   graph,
+		store:   store,
+		secrets: secrets.NewResolver(cfg.RepoRoot(), cfg.Repo(), log),
+		log:     log,
+		output:  output,
+		opts:    opts,
 	}
 }
 
@@ -36,24 +53,50 @@ func (a *DevAction) Execute(ctx context.Context, targetIDs []string) (*models.Re
 	start := time.Now()
 	result := &models.Result{}
 
-	var wg sync.WaitGroup
-	errCh := make(chan error, len(targetIDs))
+	devNodes, err := a.devTargets(targetIDs)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, id := range targetIDs {
-		node, ok := a.graph.Nodes[id]
-		if !ok {
-			return nil, &dag.TargetNotFoundError{ID: id}
-		}
+	services, err := a.newServices(devNodes)
+	if err != nil {
+		return nil, err
+	}
 
+	if err = a.buildDependencies(ctx, services); err != nil {
+		result.Failed = append(result.Failed, models.FailedTarget{Error: err})
+		result.Duration = time.Since(start)
+		return result, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	a.errCh = make(chan models.FailedTarget, 1)
+
+	var wg sync.WaitGroup
+	for _, svc := range services {
 		wg.Add(1)
-		go func(n *dag.Node) {
+		go func(s *devService) {
 			defer wg.Done()
-			if err := a.runDevTarget(ctx, n); err != nil {
-				errCh <- err
+			defer close(s.done)
+			if err := a.runDevTarget(ctx, s); err != nil {
+				a.fail(s.node.ID, err)
 			}
-		}(node)
+		}(svc)
 	}
 
+	go func() {
+		for _, svc := range services {
+			select {
+			case <-svc.ready:
+			case <-ctx.Done():
+				return
+			}
+		}
+		a.log.Info("all services ready", logger.Int("services", len(services)), logger.Duration("after", time.Since(start)))
+	}()
+
 	done := make(chan struct{})
 	go func() {
 		wg.Wait()
@@ -62,210 +105,331 @@ func (a *DevAction) Execute(ctx context.Context, targetIDs []string) (*models.Re
 
 	select {
 	case <-ctx.Done():
-		wg.Wait()
-	case err := <-errCh:
-		result.Failed = append(result.Failed, models.FailedTarget{Error: err})
+	case failed := <-a.errCh:
+		result.Failed = append(result.Failed, failed)
+		cancel()
 	case <-done:
 	}
+	wg.Wait()
 
 	result.Duration = time.Since(start)
 	return result, nil
 }
 
-func (a *DevAction) runDevTarget(ctx context.Context, node *dag.Node) error {
-	target := node.Target
-	targetLog := a.log.WithPrefix(target.ID())
-
-	if err := a.RunDependencies(ctx, node); err != nil {
-		return err
+// devTargets returns the requested targets and, unless NoDeps is set, the
+// dev targets they depend on, in dependency order.
+func (a *DevAction) devTargets(targetIDs []string) ([]*dag.Node, error) {
+	requested := make(map[string]bool, len(targetIDs))
+	for _, id := range targetIDs {
+		if _, ok := a.graph.Nodes[id]; !ok {
+			return nil, &dag.TargetNotFoundError{ID: id}
+		}
+		requested[id] = true
 	}
 
-	bundle := a.config.Bundles()[target.BundleName]
-	env := rpmexec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target)
-	workDir := rpmexec.ResolveWorkDir(a.config.RepoRoot(), target)
+	sorted, err := a.graph.SubgraphFor(targetIDs).TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
 
-	if !target.Config.Reload {
-		targetLog.Info("starting (reload disabled)...")
-		return rpmexec.RunCommand(ctx, target.Cmd, &rpmexec.ShellOptions{
-			WorkDir: workDir,
-			Env:     env,
-			Shell:   a.config.Repo().Shell,
-			Stdout:  targetLog.Writer(),
-			Stderr:  targetLog.Writer(),
-		})
+	var nodes []*dag.Node
+	for _, node := range sorted {
+		if requested[node.ID] || (!a.opts.NoDeps && node.Target.HasSuffix("_dev")) {
+			nodes = append(nodes, node)
+		}
 	}
+	return nodes, nil
+}
 
-	if err := a.runBundleBuildTargets(ctx, target.BundleName, targetLog); err != nil {
-		targetLog.Error("build failed, cannot start", logger.Err(err))
+// buildDependencies builds every non-dev target the dev targets depend on,
+// and the watched build targets of reloading ones, through the build cache,
+// so unchanged dependencies are not rebuilt.
+func (a *DevAction) buildDependencies(ctx context.Context, services []*devService) error {
+	seen := make(map[string]bool)
+	var buildIDs []string
+	add := func(node *dag.Node) {
+		if node.Target.HasSuffix("_dev") || seen[node.ID] {
+			return
+		}
+		seen[node.ID] = true
+		buildIDs = append(buildIDs, node.ID)
+	}
+	for _, svc := range services {
+		for _, dep := range a.graph.Ancestors(svc.node.ID) {
+			add(dep)
+		}
+		if svc.watch != nil {
+			for _, node := range svc.watch.builds {
+				add(node)
+			}
+		}
+	}
+	if len(buildIDs) == 0 {
+		return nil
 	}
 
-	bundleRoot := filepath.Join(a.config.RepoRoot(), target.BundlePath)
-	w, err := watcher.NewWatcher([]string{bundleRoot}, target.Config.Ignore)
+	build := NewBuildAction(a.config, a.graph, a.store, a.log, a.output, a.opts.Parallel, false)
+	result, err := build.Execute(ctx, buildIDs)
 	if err != nil {
 		return err
 	}
-	defer w.Stop()
-
-	var cmd *exec.Cmd
-	var cmdMu sync.Mutex
-
-	startCmd := func() {
-		cmdMu.Lock()
-		defer cmdMu.Unlock()
-
-		if cmd != nil && cmd.Process != nil {
-			syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
-			time.Sleep(100 * time.Millisecond)
-			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
-			cmd.Wait()
-		}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("dependency %s failed: %w", result.Failed[0].ID, result.Failed[0].Error)
+	}
+	return nil
+}
 
-		targetLog.Info("starting...")
-		shellParts := strings.Fields(a.config.Repo().Shell)
-		shellArgs := append(shellParts[1:], "-c", target.Cmd)
-		cmd = exec.Command(shellParts[0], shellArgs...)
-		cmd.Dir = workDir
-		cmd.Env = env
-		cmd.Stdout = targetLog.Writer()
-		cmd.Stderr = targetLog.Writer()
-		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-
-		if err = cmd.Start(); err != nil {
-			targetLog.Error("failed to start", logger.Err(err))
-			return
+// newServices creates the session's services; each depends on the dev
+// targets among its ancestors.
+func (a *DevAction) newServices(devNodes []*dag.Node) ([]*devService, error) {
+	a.servicesMu.Lock()
+	defer a.servicesMu.Unlock()
+
+	a.services = make(map[string]*devService, len(devNodes))
+	services := make([]*devService, 0, len(devNodes))
+	for _, node := range devNodes {
+		svc := newDevService(node, a.log.WithPrefix(node.ID))
+		if node.Target.Config.Reload {
+			watch, err := a.newWatchSet(node)
+			if err != nil {
+				return nil, err
+			}
+			svc.watch = watch
 		}
-
-		go func() {
-			cmd.Wait()
-		}()
+		a.services[node.ID] = svc
+		services = append(services, svc)
 	}
 
-	w.OnChange(func(path string) {
-		targetLog.Info("file changed, rebuilding...", logger.String("path", path))
-		if err = a.runBundleBuildTargets(ctx, target.BundleName, targetLog); err != nil {
-			targetLog.Error("build failed, not restarting", logger.Err(err))
-			return
+	for _, svc := range services {
+		for _, dep := range a.graph.Ancestors(svc.node.ID) {
+			if depSvc, ok := a.services[dep.ID]; ok {
+				svc.deps = append(svc.deps, depSvc)
+				depSvc.dependents = append(depSvc.dependents, svc)
+			}
 		}
-		targetLog.Info("restarting...")
-		startCmd()
-	})
-
-	startCmd()
-
-	go func() {
-		w.Start(ctx)
-	}()
+	}
+	return services, nil
+}
 
-	<-ctx.Done()
+// runDevTarget starts the target once its dev dependencies are ready and
+// runs it until ctx is done.
+func (a *DevAction) runDevTarget(ctx context.Context, svc *devService) error {
+	target := svc.node.Target
+	targetLog := svc.log
 
-	cmdMu.Lock()
-	if cmd != nil && cmd.Process != nil {
-		syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
-		time.Sleep(100 * time.Millisecond)
-		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if err := svc.waitForDeps(ctx); err != nil {
+		return nil
 	}
-	cmdMu.Unlock()
 
-	return nil
-}
+	bundle := a.config.Bundles()[target.BundleName]
+	env, err := rpmexec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target, a.secrets)
+	if err != nil {
+		targetLog.Error("failed to load environment", logger.Err(err))
+		svc.setStatus(ServiceFailed, 0)
+		return err
+	}
+	workDir := rpmexec.ResolveWorkDir(a.config.RepoRoot(), target)
 
-func (a *DevAction) DryRun(targetIDs []string) {
-	for _, id := range targetIDs {
-		node, ok := a.graph.Nodes[id]
-		if !ok {
-			a.log.Error("target not found", logger.String("id", id))
-			continue
+	probes, logProbe, err := a.readyProbes(target, env, workDir)
+	if err != nil {
+		targetLog.Error("invalid ready check", logger.Err(err))
+		svc.setStatus(ServiceFailed, 0)
+		return err
+	}
+	watchOutput := func(output *logger.TargetOutput) {
+		if logProbe != nil {
+			output.OnLine(func(_ logger.Stream, line string) {
+				logProbe.Observe(line)
+			})
 		}
+	}
 
-		target := node.Target
-		bundle := a.config.Bundles()[target.BundleName]
-		env := rpmexec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target)
-		workDir := rpmexec.ResolveWorkDir(a.config.RepoRoot(), target)
+	policy, err := models.ParseRestartPolicy(string(target.Restart))
+	if err != nil {
+		targetLog.Error("invalid restart policy", logger.Err(err))
+		svc.setStatus(ServiceFailed, 0)
+		return err
+	}
 
-		a.log.Info("target", logger.String("id", target.ID()))
-		a.log.Info("workdir", logger.String("path", workDir))
-		a.log.Info("command", logger.String("cmd", target.Cmd))
-		for _, e := range env {
-			a.log.Info("env", logger.String("var", e))
-		}
+	svc.stop, err = rpmexec.TargetStopOptions(target)
+	if err != nil {
+		targetLog.Error("invalid stop_signal", logger.Err(err))
+		svc.setStatus(ServiceFailed, 0)
+		return err
 	}
-}
 
-func (a *DevAction) RunDependencies(ctx context.Context, node *dag.Node) error {
-	deps := a.graph.Ancestors(node.ID)
+	rules, err := a.changeRules(target)
+	if err != nil {
+		targetLog.Error("invalid on_change", logger.Err(err))
+		svc.setStatus(ServiceFailed, 0)
+		return err
+	}
 
-	for _, dep := range deps {
-		if dep.Target.HasSuffix("_dev") {
-			continue
+	var changes *changeQueue
+	var changed <-chan struct{}
+	if svc.watch != nil {
+		w, err := newWatcher(a.config, svc.watch.roots(), target.Config.Ignore, targetLog)
+		if err != nil {
+			return err
 		}
+		defer w.Stop()
+
+		changes = newChangeQueue()
+		changed = changes.ready
+		w.OnChange(changes.add)
+		go w.Start(ctx)
+	} else {
+		targetLog.Info("reload disabled")
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
 
-		targetLog := a.log.WithPrefix(dep.ID)
-		targetLog.Info("running dependency...")
-
-		bundle := a.config.Bundles()[dep.Target.BundleName]
-		env := rpmexec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, dep.Target)
-		workDir := rpmexec.ResolveWorkDir(a.config.RepoRoot(), dep.Target)
-
-		err := rpmexec.RunCommand(ctx, dep.Target.Cmd, &rpmexec.ShellOptions{
+	exited := make(chan *devProcess)
+	start := func() *devProcess {
+		if logProbe != nil {
+			logProbe.Reset()
+		}
+		return a.startProcess(ctx, svc, &rpmexec.ShellOptions{
 			WorkDir: workDir,
-			Env:     env,
+			Env:     append(env, tracing.Environ(ctx)...),
 			Shell:   a.config.Repo().Shell,
-			Stdout:  os.Stdout,
-			Stderr:  os.Stderr,
-		})
+		}, watchOutput, probes, exited)
+	}
 
+	proc := start()
+	var restartTimer <-chan time.Time
+	stopped := false
+	restart := func() {
+		targetLog.Info("restarting...")
+		stopped = false
+		restartTimer = nil
+		svc.resetRestarts()
+		svc.stopProcess(proc)
+		proc = start()
+	}
+	build := func(ids []string, force bool) bool {
+		svc.setBuilding(true)
+		err := a.rebuild(ctx, ids, force)
+		svc.setBuilding(false)
 		if err != nil {
-			return err
+			targetLog.Error("build failed, not restarting", logger.Err(err))
+			return false
 		}
+		return true
 	}
 
-	return nil
-}
+	for {
+		select {
+		case <-ctx.Done():
+			svc.waitForDependents()
+			if proc.running() {
+				targetLog.Info("stopping...")
+			}
+			svc.stopProcess(proc)
+			return nil
 
-func (a *DevAction) runBundleBuildTargets(ctx context.Context, bundleName string, targetLog logger.Logger) error {
-	bundle := a.config.Bundles()[bundleName]
-	if bundle == nil {
-		return nil
-	}
+		case p := <-exited:
+			if p != proc {
+				continue
+			}
+			delay, ok := svc.nextRestart(policy, p.err != nil, p.uptime())
+			if ok {
+				targetLog.Info("restarting", logger.Duration("in", delay), logger.Int("restart", svc.restartCount()))
+				svc.setStatus(ServiceRestarting, 0)
+				restartTimer = time.After(delay)
+				continue
+			}
+			if policy != models.RestartNever {
+				targetLog.Error("not restarting", logger.Int("restarts", svc.restartCount()))
+			}
+			if !svc.isReady() {
+				return fmt.Errorf("exited before it was ready")
+			}
+			if target.Critical && p.err != nil {
+				return p.err
+			}
+			if changed == nil {
+				return nil
+			}
 
-	buildTargets := bundle.TargetsByType("_build")
-	if len(buildTargets) == 0 {
-		return nil
-	}
+		case cmd := <-svc.commands:
+			if cmd == devStop {
+				targetLog.Info("stopping...")
+				stopped = true
+				restartTimer = nil
+				svc.stopProcess(proc)
+				svc.setStatus(ServiceStopped, 0)
+				continue
+			}
+			if cmd == devRebuild && !build(a.buildIDs(svc), true) {
+				continue
+			}
+			restart()
+
+		case <-restartTimer:
+			restartTimer = nil
+			proc = start()
+
+		case <-changed:
+			paths, rescan := changes.take()
+			matched := svc.watch.match(paths)
+			rule := pickChangeRule(rules, paths)
+			if rescan {
+				targetLog.Warn("file changes lost, rebuilding all watched targets")
+				matched = svc.watch.all()
+				rule = changeRule{action: models.ChangeRestart}
+			} else if len(matched) == 0 {
+				continue
+			} else {
+				targetLog.Info("files changed", logger.String("path", paths[0]), logger.Int("files", len(paths)), logger.Int("targets", len(matched)))
+			}
+			if !build(svc.watch.affected(matched), false) {
+				continue
+			}
 
-	var targetIds []string
-	for _, t := range buildTargets {
-		targetIds = append(targetIds, t.ID())
+			if stopped {
+				targetLog.Info("stopped, not restarting")
+				continue
+			}
+			if rule.action == models.ChangeRebuildOnly {
+				targetLog.Info("rebuilt, not restarting")
+				continue
+			}
+			if rule.action != models.ChangeRestart && proc.running() {
+				if err = a.reload(ctx, svc, proc, rule, workDir, env); err == nil {
+					continue
+				}
+				targetLog.Error("reload failed", logger.Err(err))
+			}
+			restart()
+		}
 	}
+}
 
-	subgraph := a.graph.SubgraphFor(targetIds)
-	sorted, err := subgraph.TopologicalSort()
+func (a *DevAction) DryRun(targetIDs []string) {
+	nodes, err := a.devTargets(targetIDs)
 	if err != nil {
-		return err
+		a.log.Error("failed to resolve dev targets", logger.Err(err))
+		return
 	}
 
-	for _, node := range sorted {
+	for _, node := range nodes {
+		id := node.ID
 		target := node.Target
-		buildLog := a.log.WithPrefix(target.ID())
-		buildLog.Info("building...")
-
-		b := a.config.Bundles()[target.BundleName]
-		env := rpmexec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), b, target)
+		bundle := a.config.Bundles()[target.BundleName]
+		env, err := rpmexec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target, secrets.NewUnresolved(a.config.Repo().Secrets))
+		if err != nil {
+			a.log.Error("failed to load environment", logger.String("id", id), logger.Err(err))
+			continue
+		}
 		workDir := rpmexec.ResolveWorkDir(a.config.RepoRoot(), target)
 
-		err = rpmexec.RunCommand(ctx, target.Cmd, &rpmexec.ShellOptions{
-			WorkDir: workDir,
-			Env:     env,
-			Shell:   a.config.Repo().Shell,
-			Stdout:  buildLog.Writer(),
-			Stderr:  buildLog.Writer(),
-		})
-
-		if err != nil {
-			buildLog.Error("build failed", logger.Err(err))
-			return err
+		a.log.Info("target", logger.String("id", target.ID()))
+		a.log.Info("workdir", logger.String("path", workDir))
+		a.log.Info("command", logger.String("cmd", target.Cmd))
+		for _, e := range env {
+			a.log.Info("env", logger.String("var", e))
 		}
 	}
-
-	return nil
 }
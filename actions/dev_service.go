@@ -0,0 +1,460 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vcnkl/rpm/dag"
+	"github.com/vcnkl/rpm/events"
+	rpmexec "github.com/vcnkl/rpm/exec"
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/models"
+	"github.com/vcnkl/rpm/ready"
+)
+
+type ServiceStatus string
+
+const (
+	// ServiceWaiting services wait for their dev dependencies to be ready.
+	ServiceWaiting ServiceStatus = "waiting"
+	// ServiceBuilding services wait for their build dependencies to be
+	// rebuilt.
+	ServiceBuilding ServiceStatus = "building"
+	ServiceStarting ServiceStatus = "starting"
+	ServiceReady    ServiceStatus = "ready"
+	// ServiceRestarting services wait out their backoff before restarting.
+	ServiceRestarting ServiceStatus = "restarting"
+	ServiceExited     ServiceStatus = "exited"
+	ServiceFailed     ServiceStatus = "failed"
+	// ServiceStopped services were stopped on request and stay down until
+	// restarted.
+	ServiceStopped ServiceStatus = "stopped"
+)
+
+// restartBackoff spaces out restarts of crashing dev targets. A process that
+// stayed up for at least Max resets the count.
+var restartBackoff = rpmexec.Backoff{Initial: time.Second, Max: 30 * time.Second}
+
+type ServiceState struct {
+	ID     string
+	Status ServiceStatus
+	PID    int
+	Since  time.Time
+}
+
+// devService tracks one dev target of a session. ready is closed the first
+// time the target passes its readiness checks and done once its process has
+// been stopped for good.
+type devService struct {
+	node       *dag.Node
+	log        logger.Logger
+	deps       []*devService
+	dependents []*devService
+	ready      chan struct{}
+	done       chan struct{}
+	readyOnce  sync.Once
+	mu         sync.Mutex
+	status     ServiceStatus
+	pid        int
+	since      time.Time
+	restarts   int
+	building   bool
+	// watch is set for targets that reload on change.
+	watch    *watchSet
+	commands chan devCommand
+	stop     rpmexec.StopOptions
+}
+
+type devCommand int
+
+const (
+	devRestart devCommand = iota
+	devRebuild
+	devStop
+)
+
+func newDevService(node *dag.Node, log logger.Logger) *devService {
+	return &devService{
+		node:     node,
+		log:      log,
+		ready:    make(chan struct{}),
+		done:     make(chan struct{}),
+		status:   ServiceWaiting,
+		since:    time.Now(),
+		commands: make(chan devCommand, 1),
+		stop:     rpmexec.DefaultStopOptions,
+	}
+}
+
+// waitForDependents blocks until every dev target depending on s has
+// stopped, so that s outlives them on shutdown.
+func (s *devService) waitForDependents() {
+	for _, dep := range s.dependents {
+		<-dep.done
+	}
+}
+
+func (s *devService) setStatus(status ServiceStatus, pid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status != status {
+		s.since = time.Now()
+	}
+	s.status = status
+	s.pid = pid
+}
+
+// exited records that the process with pid (0 for the current one) ended,
+// unless a newer process has replaced it.
+func (s *devService) exited(pid int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pid != 0 && s.pid != pid {
+		return
+	}
+	status := ServiceExited
+	if err != nil {
+		status = ServiceFailed
+	}
+	if s.status != status {
+		s.since = time.Now()
+	}
+	s.status = status
+	s.pid = 0
+}
+
+// nextRestart applies the restart policy to a process that exited and
+// returns how long to wait before restarting it.
+func (s *devService) nextRestart(policy models.RestartPolicy, failed bool, uptime time.Duration) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !policy.ShouldRestart(failed) {
+		return 0, false
+	}
+	if uptime >= restartBackoff.Max {
+		s.restarts = 0
+	}
+	if max := s.node.Target.MaxRestarts; max > 0 && s.restarts >= max {
+		return 0, false
+	}
+	delay := restartBackoff.Delay(s.restarts)
+	s.restarts++
+	return delay, true
+}
+
+func (s *devService) restartCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restarts
+}
+
+func (s *devService) resetRestarts() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.restarts = 0
+}
+
+func (s *devService) setBuilding(building bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.building = building
+	s.since = time.Now()
+}
+
+func (s *devService) state() ServiceState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	if s.building {
+		status = ServiceBuilding
+	}
+	return ServiceState{
+		ID:     s.node.ID,
+		Status: status,
+		PID:    s.pid,
+		Since:  s.since,
+	}
+}
+
+func (s *devService) markReady() {
+	s.readyOnce.Do(func() { close(s.ready) })
+}
+
+func (s *devService) isReady() bool {
+	select {
+	case <-s.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitForDeps blocks until every dev dependency is ready.
+func (s *devService) waitForDeps(ctx context.Context) error {
+	for _, dep := range s.deps {
+		if dep.isReady() {
+			continue
+		}
+		s.log.Info("waiting for dependency", logger.String("dep", dep.node.ID))
+		select {
+		case <-dep.ready:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Services returns the state of every dev target of the running session,
+// sorted by ID.
+func (a *DevAction) Services() []ServiceState {
+	a.servicesMu.Lock()
+	defer a.servicesMu.Unlock()
+
+	states := make([]ServiceState, 0, len(a.services))
+	for _, s := range a.services {
+		states = append(states, s.state())
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].ID < states[j].ID
+	})
+	return states
+}
+
+// Restart restarts a dev target of the running session.
+func (a *DevAction) Restart(targetID string) error {
+	return a.send(targetID, devRestart)
+}
+
+// Rebuild rebuilds the build targets a dev target of the running session
+// depends on, bypassing the cache, and restarts it.
+func (a *DevAction) Rebuild(targetID string) error {
+	return a.send(targetID, devRebuild)
+}
+
+// Stop stops a dev target of the running session until it is restarted.
+func (a *DevAction) Stop(targetID string) error {
+	return a.send(targetID, devStop)
+}
+
+func (a *DevAction) send(targetID string, cmd devCommand) error {
+	a.servicesMu.Lock()
+	svc, ok := a.services[targetID]
+	a.servicesMu.Unlock()
+	if !ok {
+		return fmt.Errorf("%s is not running in this session", targetID)
+	}
+
+	select {
+	case svc.commands <- cmd:
+	default:
+	}
+	return nil
+}
+
+// buildIDs returns the build targets the service is rebuilt from.
+func (a *DevAction) buildIDs(svc *devService) []string {
+	var ids []string
+	if svc.watch != nil {
+		for _, node := range svc.watch.builds {
+			ids = append(ids, node.ID)
+		}
+		return ids
+	}
+	for _, node := range a.graph.Ancestors(svc.node.ID) {
+		if !node.Target.HasSuffix("_dev") {
+			ids = append(ids, node.ID)
+		}
+	}
+	return ids
+}
+
+// readyProbes builds the target's readiness checks. The log check, if any,
+// is also returned so it can be fed the target's output.
+func (a *DevAction) readyProbes(target *models.Target, env []string, workDir string) ([]ready.Probe, *ready.Log, error) {
+	cfg := target.Ready
+	var probes []ready.Probe
+	var logProbe *ready.Log
+
+	if cfg.Log != "" {
+		var err error
+		if logProbe, err = ready.NewLog(cfg.Log); err != nil {
+			return nil, nil, err
+		}
+		probes = append(probes, logProbe)
+	}
+	if cfg.TCP != "" {
+		probes = append(probes, &ready.TCP{Addr: cfg.TCP})
+	}
+	if cfg.HTTP != "" {
+		probes = append(probes, &ready.HTTP{URL: cfg.HTTP})
+	}
+	if cfg.Cmd != "" {
+		probes = append(probes, &ready.Cmd{Cmd: cfg.Cmd, Opts: rpmexec.ShellOptions{
+			WorkDir: workDir,
+			Env:     env,
+			Shell:   a.config.Repo().Shell,
+		}})
+	}
+	return probes, logProbe, nil
+}
+
+// awaitReady runs the readiness checks of a process that has just started.
+// procCtx is cancelled when the process exits. A service that never became
+// ready fails the session; one that was ready before a restart only logs.
+func (a *DevAction) awaitReady(procCtx context.Context, svc *devService, probes []ready.Probe, pid int) {
+	start := time.Now()
+	target := svc.node.Target
+
+	err := ready.Wait(procCtx, probes, target.Ready.Interval, target.Ready.Timeout)
+	if err != nil && procCtx.Err() != nil {
+		// The process exited or the session is shutting down; whoever
+		// noticed reports it.
+		return
+	}
+	if err != nil {
+		svc.setStatus(ServiceFailed, pid)
+		svc.log.Error("not ready", logger.Err(err))
+		if !svc.isReady() {
+			a.fail(target.ID(), err)
+		}
+		return
+	}
+
+	svc.setStatus(ServiceReady, pid)
+	if len(probes) > 0 {
+		svc.log.Info("ready", logger.Duration("after", time.Since(start)))
+	}
+	svc.markReady()
+}
+
+// fail ends the session because targetID failed.
+func (a *DevAction) fail(targetID string, err error) {
+	select {
+	case a.errCh <- models.FailedTarget{ID: targetID, Error: err}:
+	default:
+	}
+}
+
+// devProcess is one run of a dev target's command. done is closed once the
+// process has exited and err holds its result.
+type devProcess struct {
+	cmd     *exec.Cmd
+	started time.Time
+	done    chan struct{}
+	err     error
+	stopped atomic.Bool
+}
+
+func (p *devProcess) running() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (p *devProcess) uptime() time.Duration {
+	return time.Since(p.started)
+}
+
+// startProcess starts the target's command and reports its exit on exited,
+// unless it was stopped by rpm. A process that fails to start is reported
+// as exited immediately.
+func (a *DevAction) startProcess(ctx context.Context, svc *devService, opts *rpmexec.ShellOptions, watchOutput func(*logger.TargetOutput), probes []ready.Probe, exited chan<- *devProcess) *devProcess {
+	target := svc.node.Target
+	targetLog := svc.log
+
+	targetLog.Info("starting...")
+	svc.setStatus(ServiceStarting, 0)
+
+	output := a.output.OpenStream(target.ID(), targetLog)
+	watchOutput(output)
+	cmd := rpmexec.ShellCommand(target.Cmd, &rpmexec.ShellOptions{
+		WorkDir: opts.WorkDir,
+		Env:     opts.Env,
+		Shell:   opts.Shell,
+		Stdout:  output.Stdout(),
+		Stderr:  output.Stderr(),
+	})
+
+	p := &devProcess{cmd: cmd, started: time.Now(), done: make(chan struct{})}
+
+	if p.err = cmd.Start(); p.err != nil {
+		output.Close()
+		targetLog.Error("failed to start", logger.Err(p.err))
+		svc.exited(0, p.err)
+		close(p.done)
+		go func() {
+			select {
+			case exited <- p:
+			case <-ctx.Done():
+			}
+		}()
+		return p
+	}
+
+	pid := cmd.Process.Pid
+	a.output.Events().CommandStarted(target.ID(), target.Cmd, opts.WorkDir, pid)
+	svc.setStatus(ServiceStarting, pid)
+	procCtx, procDone := context.WithCancel(ctx)
+	go a.awaitReady(procCtx, svc, probes, pid)
+
+	go func() {
+		p.err = rpmexec.ExitErr(cmd.Wait())
+		procDone()
+		output.Close()
+		close(p.done)
+
+		exitCode := cmd.ProcessState.ExitCode()
+		status := events.StatusSuccess
+		if p.err != nil {
+			status = events.StatusFailed
+		}
+		a.output.Events().TargetFinished(target.ID(), status, exitCode, p.uptime(), p.err)
+
+		if p.stopped.Load() || ctx.Err() != nil {
+			return
+		}
+
+		svc.exited(pid, p.err)
+		if p.err != nil {
+			targetLog.Error("exited", logger.Int("exit_code", exitCode), logger.Duration("uptime", p.uptime()), logger.Err(p.err))
+		} else {
+			targetLog.Info("exited", logger.Int("exit_code", exitCode), logger.Duration("uptime", p.uptime()))
+		}
+
+		select {
+		case exited <- p:
+		case <-ctx.Done():
+		}
+	}()
+
+	return p
+}
+
+// stopProcess stops the process group of p with the target's stop signal
+// and waits for every process in it to exit, killing them after the stop
+// timeout.
+func (s *devService) stopProcess(p *devProcess) {
+	if p == nil {
+		return
+	}
+	p.stopped.Store(true)
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	if rpmexec.StopGroup(p.cmd.Process.Pid, p.done, s.stop) {
+		s.log.Warn("did not stop in time, killed", logger.Duration("stop_timeout", s.stop.Timeout))
+	}
+}
@@ -0,0 +1,166 @@
+package actions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vcnkl/rpm/dag"
+	rpmexec "github.com/vcnkl/rpm/exec"
+	"github.com/vcnkl/rpm/models"
+)
+
+func TestDevService_NextRestart(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      models.RestartPolicy
+		failed      bool
+		maxRestarts int
+		restarts    int
+		uptime      time.Duration
+		expected    time.Duration
+		restart     bool
+		after       int
+	}{
+		{
+			name:   "never",
+			policy: models.RestartNever,
+			failed: true,
+		},
+		{
+			name:   "on-failure after a clean exit",
+			policy: models.RestartOnFailure,
+		},
+		{
+			name:     "on-failure after a failure",
+			policy:   models.RestartOnFailure,
+			failed:   true,
+			expected: restartBackoff.Delay(0),
+			restart:  true,
+			after:    1,
+		},
+		{
+			name:     "always backs off",
+			policy:   models.RestartAlways,
+			restarts: 2,
+			expected: restartBackoff.Delay(2),
+			restart:  true,
+			after:    3,
+		},
+		{
+			name:        "max restarts reached",
+			policy:      models.RestartAlways,
+			maxRestarts: 3,
+			restarts:    3,
+			after:       3,
+		},
+		{
+			name:        "stable uptime resets the count",
+			policy:      models.RestartAlways,
+			maxRestarts: 3,
+			restarts:    3,
+			uptime:      restartBackoff.Max,
+			expected:    restartBackoff.Delay(0),
+			restart:     true,
+			after:       1,
+		},
+		{
+			name:     "no limit",
+			policy:   models.RestartOnFailure,
+			failed:   true,
+			restarts: 100,
+			expected: restartBackoff.Max,
+			restart:  true,
+			after:    101,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &dag.Node{ID: "api:server_dev", Target: &models.Target{MaxRestarts: tt.maxRestarts}}
+			svc := newDevService(node, discardLogger())
+			svc.restarts = tt.restarts
+
+			delay, restart := svc.nextRestart(tt.policy, tt.failed, tt.uptime)
+			assert.Equal(t, tt.restart, restart)
+			assert.Equal(t, tt.expected, delay)
+			assert.Equal(t, tt.after, svc.restartCount())
+		})
+	}
+}
+
+func TestDevAction_Exit(t *testing.T) {
+	backoff := restartBackoff
+	restartBackoff = rpmexec.Backoff{Initial: time.Millisecond, Max: time.Second}
+	t.Cleanup(func() { restartBackoff = backoff })
+
+	tests := []struct {
+		name     string
+		target   string
+		failed   string
+		errorMsg string
+		runs     int
+	}{
+		{
+			name: "critical failure ends the session",
+			target: `    cmd: sleep 0.2; exit 3
+    critical: true
+`,
+			failed:   "api:server_dev",
+			errorMsg: "command exited with status 3",
+		},
+		{
+			name: "non-critical failure",
+			target: `    cmd: sleep 0.2; exit 3
+`,
+		},
+		{
+			name: "exited before ready",
+			target: `    cmd: exit 0
+    ready: {log: listening}
+`,
+			failed:   "api:server_dev",
+			errorMsg: "exited before it was ready",
+		},
+		{
+			name: "max restarts",
+			target: `    cmd: echo api:server_dev >> "$REPO_ROOT/runs.log"; sleep 0.1
+    restart: always
+    max_restarts: 2
+`,
+			runs: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestDevAction(t, map[string]string{
+				"api/rpm.yml": `name: api
+targets:
+  - name: server_dev
+    config: {reload: false}
+` + tt.target,
+			}, DevOptions{})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			result, err := a.Execute(ctx, []string{"api:server_dev"})
+			require.NoError(t, err)
+			require.NoError(t, ctx.Err(), "session did not end")
+
+			if tt.failed == "" {
+				assert.Empty(t, result.Failed)
+			} else {
+				require.Len(t, result.Failed, 1)
+				assert.Equal(t, tt.failed, result.Failed[0].ID)
+				assert.EqualError(t, result.Failed[0].Error, tt.errorMsg)
+			}
+			if tt.runs > 0 {
+				assert.Equal(t, tt.runs, runCount(t, a.config, "api:server_dev"))
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package actions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/dag"
+	"github.com/vcnkl/rpm/logger"
+)
+
+// devRepo is a session of a web app on an API server, which needs a
+// database and a library build.
+var devRepo = map[string]string{
+	"lib/rpm.yml": `name: lib
+targets:
+  - name: lib_build
+    cmd: echo lib:lib_build >> "$REPO_ROOT/runs.log"
+`,
+	"db/rpm.yml": `name: db
+targets:
+  - name: db_dev
+    cmd: sleep 10
+    config: {reload: false}
+`,
+	"api/rpm.yml": `name: api
+targets:
+  - name: server_dev
+    deps: ["db:db_dev", "lib:lib_build"]
+    cmd: sleep 10
+    config: {reload: false}
+`,
+	"web/rpm.yml": `name: web
+targets:
+  - name: app_dev
+    deps: ["api:server_dev"]
+    cmd: sleep 10
+    config: {reload: false}
+`,
+}
+
+func newTestDevAction(t *testing.T, files map[string]string, opts DevOptions) *DevAction {
+	t.Helper()
+	cfg, graph := newTestRepo(t, files)
+	output := NewOutput(logger.OutputStream, nil, nil, nil)
+	return NewDevAction(cfg, graph, newTestStore(t, cfg), discardLogger(), output, opts)
+}
+
+// runCount returns how many times targetID's command ran, as logged to
+// runs.log by the test repos.
+func runCount(t *testing.T, cfg *config.Config, targetID string) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(cfg.RepoRoot(), "runs.log"))
+	if os.IsNotExist(err) {
+		return 0
+	}
+	require.NoError(t, err)
+
+	count := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == targetID {
+			count++
+		}
+	}
+	return count
+}
+
+func nodeIDs(nodes []*dag.Node) []string {
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
+
+func TestDevAction_DevTargets(t *testing.T) {
+	tests := []struct {
+		name      string
+		targetIDs []string
+		noDeps    bool
+		expected  []string
+		notFound  bool
+	}{
+		{
+			name:      "dev dependencies first",
+			targetIDs: []string{"web:app_dev"},
+			expected:  []string{"db:db_dev", "api:server_dev", "web:app_dev"},
+		},
+		{
+			name:      "no deps",
+			targetIDs: []string{"web:app_dev"},
+			noDeps:    true,
+			expected:  []string{"web:app_dev"},
+		},
+		{
+			name:      "no deps keeps requested targets in dependency order",
+			targetIDs: []string{"web:app_dev", "db:db_dev"},
+			noDeps:    true,
+			expected:  []string{"db:db_dev", "web:app_dev"},
+		},
+		{
+			name:      "unknown target",
+			targetIDs: []string{"web:missing_dev"},
+			notFound:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestDevAction(t, devRepo, DevOptions{NoDeps: tt.noDeps})
+
+			nodes, err := a.devTargets(tt.targetIDs)
+			if tt.notFound {
+				var notFound *dag.TargetNotFoundError
+				require.ErrorAs(t, err, &notFound)
+				assert.Equal(t, tt.targetIDs[0], notFound.ID)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, nodeIDs(nodes))
+		})
+	}
+}
+
+func TestDevAction_BuildDependencies(t *testing.T) {
+	a := newTestDevAction(t, devRepo, DevOptions{Parallel: 2})
+	nodes, err := a.devTargets([]string{"web:app_dev"})
+	require.NoError(t, err)
+	services, err := a.newServices(nodes)
+	require.NoError(t, err)
+
+	require.NoError(t, a.buildDependencies(context.Background(), services))
+	assert.Equal(t, 1, runCount(t, a.config, "lib:lib_build"))
+
+	entry, ok := newTestStore(t, a.config).Get("lib:lib_build")
+	require.True(t, ok)
+	assert.NotEmpty(t, entry.InputHash)
+
+	// Unchanged dependencies are cache hits on the next session.
+	require.NoError(t, a.buildDependencies(context.Background(), services))
+	assert.Equal(t, 1, runCount(t, a.config, "lib:lib_build"))
+}
+
+func TestDevAction_BuildDependencies_Failure(t *testing.T) {
+	a := newTestDevAction(t, map[string]string{
+		"lib/rpm.yml": `name: lib
+targets:
+  - name: lib_build
+    cmd: exit 1
+  - name: server_dev
+    deps: [":lib_build"]
+    cmd: sleep 10
+    config: {reload: false}
+`,
+	}, DevOptions{})
+	nodes, err := a.devTargets([]string{"lib:server_dev"})
+	require.NoError(t, err)
+	services, err := a.newServices(nodes)
+	require.NoError(t, err)
+
+	err = a.buildDependencies(context.Background(), services)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency lib:lib_build failed")
+}
@@ -0,0 +1,295 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/vcnkl/rpm/cache/hashing"
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/dag"
+	rpmexec "github.com/vcnkl/rpm/exec"
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/models"
+	"github.com/vcnkl/rpm/tracing"
+	"github.com/vcnkl/rpm/watcher"
+)
+
+// watchSet holds the inputs a reloading dev target watches: its own, those
+// of the `_build` targets of its bundle and those of every build target they
+// depend on, across bundles.
+type watchSet struct {
+	// builds are the watched build targets in dependency order.
+	builds []*dag.Node
+	inputs map[string][]string
+}
+
+func (a *DevAction) newWatchSet(node *dag.Node) (*watchSet, error) {
+	ids := []string{node.ID}
+	if bundle := a.config.Bundles()[node.Target.BundleName]; bundle != nil {
+		for _, t := range bundle.TargetsByType("_build") {
+			ids = append(ids, t.ID())
+		}
+	}
+
+	sorted, err := a.graph.SubgraphFor(ids).TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &watchSet{inputs: make(map[string][]string)}
+	for _, n := range sorted {
+		if n.ID != node.ID && n.Target.HasSuffix("_dev") {
+			continue
+		}
+		if n.ID != node.ID {
+			w.builds = append(w.builds, n)
+		}
+		w.inputs[n.ID] = targetInputs(a.config.RepoRoot(), n.Target)
+	}
+	return w, nil
+}
+
+// targetInputs returns the absolute input patterns of target. A target
+// without `in` depends on its whole bundle directory.
+func targetInputs(repoRoot string, target *models.Target) []string {
+	bundleRoot := filepath.Join(repoRoot, target.BundlePath)
+	if len(target.In) == 0 {
+		return []string{filepath.Join(bundleRoot, "**")}
+	}
+	inputs := make([]string, 0, len(target.In))
+	for _, pattern := range target.In {
+		inputs = append(inputs, hashing.ResolveInput(repoRoot, bundleRoot, pattern))
+	}
+	return inputs
+}
+
+// roots returns the directories to watch.
+func (w *watchSet) roots() []string {
+	seen := make(map[string]bool)
+	var roots []string
+	for _, patterns := range w.inputs {
+		for _, pattern := range patterns {
+			root := hashing.InputRoot(pattern)
+			if !seen[root] {
+				seen[root] = true
+				roots = append(roots, root)
+			}
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// match returns the IDs of the targets whose inputs include any of paths.
+func (w *watchSet) match(paths []string) map[string]bool {
+	matched := make(map[string]bool)
+	for id, patterns := range w.inputs {
+		for _, pattern := range patterns {
+			for _, path := range paths {
+				if hashing.MatchInput(pattern, path) {
+					matched[id] = true
+				}
+			}
+		}
+	}
+	return matched
+}
+
+// affected returns the build targets to rebuild when the inputs of matched
+// changed: the matched ones and the watched targets depending on them.
+func (w *watchSet) affected(matched map[string]bool) []string {
+	dirty := make(map[string]bool)
+	var ids []string
+	for _, node := range w.builds {
+		isDirty := matched[node.ID]
+		for _, dep := range node.Deps {
+			isDirty = isDirty || dirty[dep.ID]
+		}
+		if isDirty {
+			dirty[node.ID] = true
+			ids = append(ids, node.ID)
+		}
+	}
+	return ids
+}
+
+// newWatcher watches roots with the repo's watch settings, logging errors
+// the watcher recovers from.
+func newWatcher(cfg *config.Config, roots []string, ignore []string, log logger.Logger) (*watcher.Watcher, error) {
+	settings := cfg.Repo().Watch
+	w, err := watcher.NewWatcher(roots, ignore, watcher.Options{
+		Debounce:     settings.Debounce,
+		Poll:         settings.Poll,
+		PollInterval: settings.PollInterval,
+		SkipDirs:     settings.SkipDirs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if w.Polling() && !settings.Poll {
+		log.Warn("file notifications unavailable, polling", logger.Duration("interval", settings.PollInterval))
+	}
+	w.OnError(func(err error) {
+		log.Warn("file watcher error", logger.Err(err), logger.Bool("polling", w.Polling()))
+	})
+	return w, nil
+}
+
+// all returns the IDs of every watched target, for when changes were lost.
+func (w *watchSet) all() map[string]bool {
+	matched := make(map[string]bool, len(w.inputs))
+	for id := range w.inputs {
+		matched[id] = true
+	}
+	return matched
+}
+
+// changeQueue collects the batches a dev target's watcher reports until the
+// target picks them up.
+type changeQueue struct {
+	mu     sync.Mutex
+	paths  []string
+	rescan bool
+	ready  chan struct{}
+}
+
+func newChangeQueue() *changeQueue {
+	return &changeQueue{ready: make(chan struct{}, 1)}
+}
+
+func (q *changeQueue) add(batch watcher.Batch) {
+	q.mu.Lock()
+	q.paths = append(q.paths, batch.Paths...)
+	q.rescan = q.rescan || batch.Rescan
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the changed paths and whether changes were lost since the
+// last call.
+func (q *changeQueue) take() ([]string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	paths, rescan := q.paths, q.rescan
+	q.paths = nil
+	q.rescan = false
+	return paths, rescan
+}
+
+// rebuild builds the given targets through the build cache, unless force
+// is set, and records them in the builds store. Dev targets sharing a
+// dependency see the same change; whichever gets here second finds it
+// cached.
+func (a *DevAction) rebuild(ctx context.Context, ids []string, force bool) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	a.rebuildMu.Lock()
+	defer a.rebuildMu.Unlock()
+
+	build := NewBuildAction(a.config, a.graph, a.store, a.log, a.output, a.opts.Parallel, force)
+	result, err := build.Execute(ctx, ids)
+	if err != nil {
+		return err
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%s failed: %w", result.Failed[0].ID, result.Failed[0].Error)
+	}
+	return nil
+}
+
+type changeRule struct {
+	patterns []string
+	action   models.ChangeAction
+	signal   syscall.Signal
+	exec     string
+}
+
+// changeRules resolves the target's on_change rules.
+func (a *DevAction) changeRules(target *models.Target) ([]changeRule, error) {
+	bundleRoot := filepath.Join(a.config.RepoRoot(), target.BundlePath)
+
+	rules := make([]changeRule, 0, len(target.OnChange))
+	for _, r := range target.OnChange {
+		action, err := r.ResolveAction()
+		if err != nil {
+			return nil, err
+		}
+		rule := changeRule{action: action, exec: r.Exec}
+		if action == models.ChangeSignal {
+			if rule.signal, err = rpmexec.ParseSignal(r.Signal); err != nil {
+				return nil, err
+			}
+		}
+		for _, pattern := range r.Paths {
+			rule.patterns = append(rule.patterns, hashing.ResolveInput(a.config.RepoRoot(), bundleRoot, pattern))
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// pickChangeRule returns the most disruptive rule any of paths asks for.
+// Each path uses the first rule matching it; paths no rule matches restart.
+func pickChangeRule(rules []changeRule, paths []string) changeRule {
+	var picked changeRule
+	for _, path := range paths {
+		rule := changeRule{action: models.ChangeRestart}
+		for _, r := range rules {
+			if r.matches(path) {
+				rule = r
+				break
+			}
+		}
+		if rule.action.Weight() > picked.action.Weight() {
+			picked = rule
+		}
+	}
+	return picked
+}
+
+func (r changeRule) matches(path string) bool {
+	if len(r.patterns) == 0 {
+		return true
+	}
+	for _, pattern := range r.patterns {
+		if hashing.MatchInput(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// reload applies a signal or exec rule to the running process instead of
+// restarting it.
+func (a *DevAction) reload(ctx context.Context, svc *devService, p *devProcess, rule changeRule, workDir string, env []string) error {
+	target := svc.node.Target
+
+	if rule.action == models.ChangeSignal {
+		svc.log.Info("reloading", logger.String("signal", rule.signal.String()))
+		if err := syscall.Kill(-p.cmd.Process.Pid, rule.signal); err != nil {
+			return fmt.Errorf("failed to send %s: %w", rule.signal, err)
+		}
+		return nil
+	}
+
+	svc.log.Info("reloading", logger.String("cmd", rule.exec))
+	output := a.output.OpenStream(target.ID(), svc.log)
+	defer output.Close()
+	return rpmexec.RunCommand(ctx, rule.exec, &rpmexec.ShellOptions{
+		WorkDir: workDir,
+		Env:     append(env, tracing.Environ(ctx)...),
+		Shell:   a.config.Repo().Shell,
+		Stdout:  output.Stdout(),
+		Stderr:  output.Stderr(),
+	})
+}
@@ -0,0 +1,115 @@
+package actions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vcnkl/rpm/dag"
+)
+
+func TestWatchSet_Affected(t *testing.T) {
+	proto := &dag.Node{ID: "lib:proto_build"}
+	lib := &dag.Node{ID: "lib:lib_build", Deps: []*dag.Node{proto}}
+	assets := &dag.Node{ID: "web:assets_build"}
+	app := &dag.Node{ID: "web:app_build", Deps: []*dag.Node{lib, assets}}
+	w := &watchSet{builds: []*dag.Node{proto, lib, assets, app}}
+
+	tests := []struct {
+		name     string
+		matched  []string
+		expected []string
+	}{
+		{
+			name:     "dependents are rebuilt",
+			matched:  []string{"lib:proto_build"},
+			expected: []string{"lib:proto_build", "lib:lib_build", "web:app_build"},
+		},
+		{
+			name:     "dependencies are not rebuilt",
+			matched:  []string{"lib:lib_build"},
+			expected: []string{"lib:lib_build", "web:app_build"},
+		},
+		{
+			name:     "several changes",
+			matched:  []string{"web:assets_build", "lib:lib_build"},
+			expected: []string{"lib:lib_build", "web:assets_build", "web:app_build"},
+		},
+		{
+			name:    "only the dev target's own inputs",
+			matched: []string{"web:app_dev"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched := make(map[string]bool)
+			for _, id := range tt.matched {
+				matched[id] = true
+			}
+			assert.Equal(t, tt.expected, w.affected(matched))
+		})
+	}
+}
+
+func TestDevAction_Rebuild(t *testing.T) {
+	a := newTestDevAction(t, map[string]string{
+		"lib/rpm.yml": `name: lib
+targets:
+  - name: proto_build
+    in: ["proto/*.txt"]
+    cmd: echo lib:proto_build >> "$REPO_ROOT/runs.log"
+  - name: lib_build
+    in: ["src/*.txt"]
+    deps: [":proto_build"]
+    cmd: echo lib:lib_build >> "$REPO_ROOT/runs.log"
+  - name: server_dev
+    in: ["cmd/*.txt"]
+    deps: [":lib_build"]
+    cmd: sleep 10
+`,
+		"lib/proto/api.txt": "v1",
+		"lib/src/a.txt":     "v1",
+		"lib/cmd/main.txt":  "v1",
+	}, DevOptions{})
+	ctx := context.Background()
+
+	nodes, err := a.devTargets([]string{"lib:server_dev"})
+	require.NoError(t, err)
+	services, err := a.newServices(nodes)
+	require.NoError(t, err)
+	svc := services[0]
+	require.NotNil(t, svc.watch)
+	assert.Equal(t, []string{"lib:proto_build", "lib:lib_build"}, nodeIDs(svc.watch.builds))
+
+	require.NoError(t, a.buildDependencies(ctx, services))
+	entry, ok := newTestStore(t, a.config).Get("lib:lib_build")
+	require.True(t, ok)
+	built := entry.InputHash
+
+	src := filepath.Join(a.config.RepoRoot(), "lib/src/a.txt")
+	require.NoError(t, os.WriteFile(src, []byte("v2"), 0644))
+
+	ids := svc.watch.affected(svc.watch.match([]string{src}))
+	assert.Equal(t, []string{"lib:lib_build"}, ids)
+	require.NoError(t, a.rebuild(ctx, ids, false))
+
+	assert.Equal(t, 2, runCount(t, a.config, "lib:lib_build"))
+	assert.Equal(t, 1, runCount(t, a.config, "lib:proto_build"))
+
+	entry, ok = newTestStore(t, a.config).Get("lib:lib_build")
+	require.True(t, ok)
+	assert.NotEqual(t, built, entry.InputHash)
+
+	// A change to the dev target's own inputs rebuilds nothing, and watched
+	// targets that did not change are cache hits.
+	cmdFile := filepath.Join(a.config.RepoRoot(), "lib/cmd/main.txt")
+	assert.Empty(t, svc.watch.affected(svc.watch.match([]string{cmdFile})))
+	require.NoError(t, a.rebuild(ctx, nodeIDs(svc.watch.builds), false))
+	assert.Equal(t, 2, runCount(t, a.config, "lib:lib_build"))
+	assert.Equal(t, 1, runCount(t, a.config, "lib:proto_build"))
+}
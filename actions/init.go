@@ -10,6 +10,7 @@ import (
 	rpmexec "github.com/vcnkl/rpm/exec"
 	"github.com/vcnkl/rpm/logger"
 	"github.com/vcnkl/rpm/models"
+	"github.com/vcnkl/rpm/secrets"
 	"github.com/vcnkl/rpm/stores/dags"
 )
 
@@ -17,16 +18,20 @@ type InitAction struct {
 	config   *config.Config
 	graph    *dag.Graph
 	dagStore *dags.Store
+	secrets  *secrets.Resolver
 	log      logger.Logger
+	output   *Output
 	force    bool
 }
 
-func NewInitAction(cfg *config.Config, graph *dag.Graph, log logger.Logger, force bool) *InitAction {
+func NewInitAction(cfg *config.Config, graph *dag.Graph, log logger.Logger, output *Output, force bool) *InitAction {
 	return &InitAction{
 		config:   cfg,
 		graph:    graph,
 		dagStore: dags.NewStore(cfg.DagPath()),
+		secrets:  secrets.NewResolver(cfg.RepoRoot(), cfg.Repo(), log),
 		log:      log,
+		output:   output,
 		force:    force,
 	}
 }
@@ -53,11 +58,14 @@ func (a *InitAction) Execute(ctx context.Context) (*models.Result, error) {
 
 		if !checkPassed || a.force {
 			depLog.Info("installing...")
+			output := a.output.Open(dep.Label, depLog)
 			cmd := exec.CommandContext(ctx, "sh", "-c", dep.InstallCmd)
-			cmd.Stdout = depLog.Writer()
-			cmd.Stderr = depLog.Writer()
+			cmd.Stdout = output.Stdout()
+			cmd.Stderr = output.Stderr()
 
-			if err := cmd.Run(); err != nil {
+			err := cmd.Run()
+			output.Close()
+			if err != nil {
 				depLog.Error("install failed", logger.Err(err))
 				result.Failed = append(result.Failed, models.FailedTarget{
 					ID:    dep.Label,
@@ -87,16 +95,26 @@ func (a *InitAction) Execute(ctx context.Context) (*models.Result, error) {
 		targetLog.Info("running...")
 
 		bundle := a.config.Bundles()[target.BundleName]
-		env := rpmexec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target)
+		env, err := rpmexec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target, a.secrets)
+		if err != nil {
+			targetLog.Error("failed to load environment", logger.Err(err))
+			result.Failed = append(result.Failed, models.FailedTarget{
+				ID:    target.ID(),
+				Error: err,
+			})
+			continue
+		}
 		workDir := rpmexec.ResolveWorkDir(a.config.RepoRoot(), target)
 
+		output := a.output.Open(target.ID(), targetLog)
 		err = rpmexec.RunCommand(ctx, target.Cmd, &rpmexec.ShellOptions{
 			WorkDir: workDir,
 			Env:     env,
 			Shell:   a.config.Repo().Shell,
-			Stdout:  targetLog.Writer(),
-			Stderr:  targetLog.Writer(),
+			Stdout:  output.Stdout(),
+			Stderr:  output.Stderr(),
 		})
+		output.Close()
 
 		if err != nil {
 			targetLog.Error("failed", logger.Err(err))
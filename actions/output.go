@@ -0,0 +1,118 @@
+package actions
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vcnkl/rpm/events"
+	"github.com/vcnkl/rpm/exec"
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/profile"
+	"github.com/vcnkl/rpm/stores/logs"
+)
+
+type Output struct {
+	mode    logger.OutputMode
+	logs    *logs.Store
+	events  *events.Emitter
+	profile *profile.Profile
+}
+
+func NewOutput(mode logger.OutputMode, logs *logs.Store, events *events.Emitter, profile *profile.Profile) *Output {
+	return &Output{
+		mode:    mode,
+		logs:    logs,
+		events:  events,
+		profile: profile,
+	}
+}
+
+func (o *Output) Open(targetID string, log logger.Logger) *logger.TargetOutput {
+	return o.open(targetID, log, o.mode)
+}
+
+// OpenStream is used for long-running processes that never finish and so
+// cannot be grouped.
+func (o *Output) OpenStream(targetID string, log logger.Logger) *logger.TargetOutput {
+	mode := o.mode
+	if mode == logger.OutputGrouped {
+		mode = logger.OutputStream
+	}
+	return o.open(targetID, log, mode)
+}
+
+func (o *Output) RunID() string {
+	if o.logs == nil {
+		return ""
+	}
+	return o.logs.RunID()
+}
+
+func (o *Output) LogPath(targetID string) string {
+	if o.logs == nil {
+		return ""
+	}
+	return o.logs.Path(targetID)
+}
+
+// Events returns the run's event emitter, which is nil (and discards
+// everything) when --events was not given.
+func (o *Output) Events() *events.Emitter {
+	return o.events
+}
+
+// Profile returns the run's trace recorder, which is nil (and records
+// nothing) when --profile was not given.
+func (o *Output) Profile() *profile.Profile {
+	return o.profile
+}
+
+func (o *Output) Close() error {
+	return o.events.Close()
+}
+
+func (o *Output) open(targetID string, log logger.Logger, mode logger.OutputMode) *logger.TargetOutput {
+	output := o.newTargetOutput(targetID, log, mode)
+	if o.events != nil {
+		output.OnLine(func(stream logger.Stream, line string) {
+			o.events.Output(targetID, stream.String(), line)
+		})
+	}
+	return output
+}
+
+func (o *Output) newTargetOutput(targetID string, log logger.Logger, mode logger.OutputMode) *logger.TargetOutput {
+	if o.logs == nil {
+		return logger.NewTargetOutput(targetID, log, mode, nil)
+	}
+
+	file, err := o.logs.Create(targetID)
+	if err != nil {
+		log.Warn("failed to create log file", logger.Err(err))
+		return logger.NewTargetOutput(targetID, log, mode, nil)
+	}
+
+	return logger.NewTargetOutput(targetID, log, mode, file)
+}
+
+func (o *Output) targetFinished(targetID string, start time.Time, err error) {
+	status := events.StatusSuccess
+	if err != nil {
+		status = events.StatusFailed
+	}
+	o.events.TargetFinished(targetID, status, exec.ExitCode(err), time.Since(start), err)
+}
+
+// notRun reports targets the executor skipped because a dependency failed.
+func (o *Output) notRun(targetID string, err error) {
+	var depErr *exec.DependencyFailedError
+	if errors.As(err, &depErr) {
+		o.events.TargetFinished(targetID, events.StatusSkipped, -1, 0, err)
+	}
+}
+
+func (o *Output) onStart(targetID, cmd, workDir string) func(pid int) {
+	return func(pid int) {
+		o.events.CommandStarted(targetID, cmd, workDir, pid)
+	}
+}
@@ -9,25 +9,34 @@ import (
 	"github.com/vcnkl/rpm/exec"
 	"github.com/vcnkl/rpm/logger"
 	"github.com/vcnkl/rpm/models"
+	"github.com/vcnkl/rpm/profile"
+	"github.com/vcnkl/rpm/secrets"
+	"github.com/vcnkl/rpm/tracing"
 )
 
 type RunAction struct {
-	config *config.Config
-	graph  *dag.Graph
-	log    logger.Logger
+	config      *config.Config
+	graph       *dag.Graph
+	secrets     *secrets.Resolver
+	log         logger.Logger
+	output      *Output
+	interactive bool
 }
 
-func NewRunAction(cfg *config.Config, graph *dag.Graph, log logger.Logger) *RunAction {
+func NewRunAction(cfg *config.Config, graph *dag.Graph, log logger.Logger, output *Output, interactive bool) *RunAction {
 	return &RunAction{
-		config: cfg,
-		graph:  graph,
-		log:    log,
+		config:      cfg,
+		graph:       graph,
+		secrets:     secrets.NewResolver(cfg.RepoRoot(), cfg.Repo(), log),
+		log:         log,
+		output:      output,
+		interactive: interactive,
 	}
 }
 
 func (a *RunAction) Execute(ctx context.Context, targetID string) (*models.Result, error) {
 	start := time.Now()
-	result := &models.Result{}
+	result := &models.Result{Durations: make(map[string]time.Duration)}
 
 	node, ok := a.graph.Nodes[targetID]
 	if !ok {
@@ -41,10 +50,22 @@ func (a *RunAction) Execute(ctx context.Context, targetID string) (*models.Resul
 	}
 
 	for _, n := range sorted {
-		if err = a.runTarget(ctx, n); err != nil {
+		a.output.Events().TargetQueued(n.ID, n.Target.Deps)
+	}
+
+	for _, n := range sorted {
+		interactive := a.interactive && n.ID == node.ID
+		targetStart := time.Now()
+		targetCtx, span := tracing.StartTarget(ctx, n.ID)
+		err = a.runTarget(targetCtx, n, interactive)
+		tracing.End(span, err)
+		a.output.targetFinished(n.ID, targetStart, err)
+		result.Durations[n.ID] = time.Since(targetStart)
+		if err != nil {
 			result.Failed = append(result.Failed, models.FailedTarget{
-				ID:    n.ID,
-				Error: err,
+				ID:       n.ID,
+				Error:    err,
+				ExitCode: exec.ExitCode(err),
 			})
 			result.Duration = time.Since(start)
 			return result, err
@@ -52,28 +73,54 @@ func (a *RunAction) Execute(ctx context.Context, targetID string) (*models.Resul
 		result.Executed = append(result.Executed, n.ID)
 	}
 
-	_ = node
 	result.Duration = time.Since(start)
 	return result, nil
 }
 
-func (a *RunAction) runTarget(ctx context.Context, node *dag.Node) error {
+func (a *RunAction) runTarget(ctx context.Context, node *dag.Node, interactive bool) error {
 	target := node.Target
 	targetLog := a.log.WithPrefix(target.ID())
 
 	targetLog.Info("running...")
 
 	bundle := a.config.Bundles()[target.BundleName]
-	env := exec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target)
+	env, err := exec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target, a.secrets)
+	if err != nil {
+		targetLog.Error("failed to load environment", logger.Err(err))
+		return err
+	}
 	workDir := exec.ResolveWorkDir(a.config.RepoRoot(), target)
 
-	err := exec.RunCommand(ctx, target.Cmd, &exec.ShellOptions{
-		WorkDir: workDir,
-		Env:     env,
-		Shell:   a.config.Repo().Shell,
-		Stdout:  targetLog.Writer(),
-		Stderr:  targetLog.Writer(),
-	})
+	ctx, cmdSpan := tracing.StartPhase(ctx, profile.PhaseCommand)
+	env = append(env, tracing.Environ(ctx)...)
+
+	if interactive {
+		targetLog.Debug("attaching terminal")
+		err = exec.RunInteractive(ctx, target.Cmd, &exec.ShellOptions{
+			WorkDir: workDir,
+			Env:     env,
+			Shell:   a.config.Repo().Shell,
+			OnStart: a.output.onStart(target.ID(), target.Cmd, workDir),
+		})
+	} else {
+		var stop exec.StopOptions
+		if stop, err = exec.TargetStopOptions(target); err != nil {
+			targetLog.Error("invalid stop_signal", logger.Err(err))
+			return err
+		}
+		output := a.output.Open(target.ID(), targetLog)
+		err = exec.RunCommand(ctx, target.Cmd, &exec.ShellOptions{
+			WorkDir: workDir,
+			Env:     env,
+			Shell:   a.config.Repo().Shell,
+			Stdout:  output.Stdout(),
+			Stderr:  output.Stderr(),
+			OnStart: a.output.onStart(target.ID(), target.Cmd, workDir),
+			Stop:    stop,
+		})
+		output.Close()
+	}
+	tracing.End(cmdSpan, err)
 
 	if err != nil {
 		targetLog.Error("failed", logger.Err(err))
@@ -2,34 +2,79 @@ package actions
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/coverage"
 	"github.com/vcnkl/rpm/dag"
+	"github.com/vcnkl/rpm/events"
 	"github.com/vcnkl/rpm/exec"
+	"github.com/vcnkl/rpm/junit"
 	"github.com/vcnkl/rpm/logger"
 	"github.com/vcnkl/rpm/models"
+	"github.com/vcnkl/rpm/profile"
+	"github.com/vcnkl/rpm/secrets"
+	"github.com/vcnkl/rpm/stores/builds"
+	"github.com/vcnkl/rpm/stores/logs"
+	"github.com/vcnkl/rpm/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
+type TestOptions struct {
+	Parallel int
+	// Force reruns targets with cached results and refreshes the cache.
+	Force bool
+	// NoCache neither reads nor writes cached results.
+	NoCache bool
+	// CoverageDir enables coverage when set: each target gets RPM_COVERAGE=1
+	// and its own RPM_COVERAGE_DIR below it. Results are not cached.
+	CoverageDir string
+}
+
 type TestAction struct {
-	config   *config.Config
-	graph    *dag.Graph
-	log      logger.Logger
-	parallel int
+	config     *config.Config
+	graph      *dag.Graph
+	store      *builds.Store
+	validator  *builds.Validator
+	secrets    *secrets.Resolver
+	report     *junit.Report
+	coverage   *coverage.Profile
+	coverageMu sync.Mutex
+	keys       map[string]string
+	keysMu     sync.Mutex
+	log        logger.Logger
+	output     *Output
+	opts       TestOptions
 }
 
-func NewTestAction(cfg *config.Config, graph *dag.Graph, log logger.Logger, parallel int) *TestAction {
+func NewTestAction(cfg *config.Config, graph *dag.Graph, store *builds.Store, log logger.Logger, output *Output, opts TestOptions) *TestAction {
 	return &TestAction{
-		config:   cfg,
-		graph:    graph,
-		log:      log,
-		parallel: parallel,
+		config:    cfg,
+		graph:     graph,
+		store:     store,
+		validator: builds.NewValidator(cfg.RepoRoot(), store, cfg.Hasher()),
+		secrets:   secrets.NewResolver(cfg.RepoRoot(), cfg.Repo(), log),
+		report:    junit.NewReport(),
+		coverage:  coverage.NewProfile(),
+		keys:      make(map[string]string),
+		log:       log,
+		output:    output,
+		opts:      opts,
 	}
 }
 
 func (a *TestAction) Execute(ctx context.Context, targetIDs []string) (*models.Result, error) {
 	start := time.Now()
-	result := &models.Result{}
+	result := &models.Result{Durations: make(map[string]time.Duration)}
+	var resultMu sync.Mutex
 
 	subgraph := a.graph.SubgraphFor(targetIDs)
 
@@ -38,16 +83,70 @@ func (a *TestAction) Execute(ctx context.Context, targetIDs []string) (*models.R
 		return nil, err
 	}
 
-	executor := exec.NewParallelExecutor(a.parallel)
+	for _, node := range sorted {
+		a.output.Events().TargetQueued(node.ID, node.Target.Deps)
+	}
+
+	requested := make(map[string]bool, len(targetIDs))
+	caching := false
+	for _, id := range targetIDs {
+		requested[id] = true
+		if node, ok := subgraph.Nodes[id]; ok && node.Target.Config.Cache {
+			caching = !a.opts.NoCache && a.opts.CoverageDir == ""
+		}
+	}
+	skipped := make(map[string]bool)
+	cacheKeys := make(map[string]string)
+
+	executor := exec.NewParallelExecutor(a.opts.Parallel)
 	results := executor.Execute(ctx, sorted, func(ctx context.Context, node *dag.Node) error {
-		return a.runTest(ctx, node)
+		start := time.Now()
+		ctx, span := tracing.StartTarget(ctx, node.ID)
+		cached, key, err := a.testTarget(ctx, node, caching, requested[node.ID])
+		span.SetAttributes(attribute.Bool("rpm.cached", cached))
+		tracing.End(span, err)
+		resultMu.Lock()
+		result.Durations[node.ID] = time.Since(start)
+		skipped[node.ID] = cached
+		if key != "" {
+			cacheKeys[node.ID] = key
+		}
+		resultMu.Unlock()
+		if cached {
+			a.output.Events().TargetFinished(node.ID, events.StatusSkipped, 0, time.Since(start), nil)
+			if requested[node.ID] {
+				a.report.AddResult(node.ID, time.Since(start), nil, a.output.LogPath(node.ID))
+			}
+			return nil
+		}
+		a.output.targetFinished(node.ID, start, err)
+		if requested[node.ID] {
+			a.collectReports(node.Target, start, err)
+		}
+		a.collectCoverage(node.Target, start)
+		return err
 	})
 
+	a.recordResults(targetIDs, results, skipped, cacheKeys, result.Durations)
+
+	// Requested targets the executor never started still get a test case.
+	for _, id := range targetIDs {
+		if _, ran := result.Durations[id]; ran || results[id] == nil {
+			continue
+		}
+		var depErr *exec.DependencyFailedError
+		a.report.AddNotRun(id, results[id], errors.As(results[id], &depErr))
+	}
+
 	for id, err := range results {
-		if err != nil {
+		if skipped[id] {
+			result.Skipped = append(result.Skipped, id)
+		} else if err != nil {
+			a.output.notRun(id, err)
 			result.Failed = append(result.Failed, models.FailedTarget{
-				ID:    id,
-				Error: err,
+				ID:       id,
+				Error:    err,
+				ExitCode: exec.ExitCode(err),
 			})
 		} else {
 			result.Executed = append(result.Executed, id)
@@ -58,26 +157,343 @@ func (a *TestAction) Execute(ctx context.Context, targetIDs []string) (*models.R
 	return result, nil
 }
 
-func (a *TestAction) runTest(ctx context.Context, node *dag.Node) error {
+// recordResults stores how long each passing test target took, which test
+// sharding uses to balance shards, along with the cache key of its result.
+// A failed target loses its cached result.
+func (a *TestAction) recordResults(targetIDs []string, results map[string]error, skipped map[string]bool, cacheKeys map[string]string, durations map[string]time.Duration) {
+	recorded := false
+	for _, id := range targetIDs {
+		duration, ran := durations[id]
+		if !ran || skipped[id] {
+			continue
+		}
+		entry := &builds.Entry{}
+		if existing, ok := a.store.Get(id); ok {
+			*entry = *existing
+		}
+		if results[id] != nil {
+			if entry.InputHash == "" {
+				continue
+			}
+			entry.InputHash = ""
+		} else {
+			entry.Timestamp = time.Now()
+			entry.DurationMs = duration.Milliseconds()
+			if key, ok := cacheKeys[id]; ok {
+				entry.InputHash = key
+			}
+		}
+		a.store.Set(id, entry)
+		recorded = true
+	}
+
+	if !recorded {
+		return
+	}
+	if err := a.store.Save(); err != nil {
+		a.log.Warn("failed to save test results", logger.Err(err))
+	}
+}
+
+// Report holds the test reports collected from every target run so far.
+func (a *TestAction) Report() *junit.Report {
+	return a.report
+}
+
+// collectReports reads the target's declared report files written since
+// start. Targets without reports, and failed targets whose reports show no
+// failing case, get a synthesized test case for the target itself.
+func (a *TestAction) collectReports(target *models.Target, start time.Time, runErr error) {
+	targetLog := a.log.WithPrefix(target.ID())
+	duration := time.Since(start)
+
+	var suites []junit.Suite
+	for _, path := range a.outputFiles(target, target.Reports, start) {
+		parsed, err := junit.ParseFile(path)
+		if err != nil {
+			targetLog.Warn("failed to read test report", logger.Err(err))
+			continue
+		}
+		suites = append(suites, parsed...)
+	}
+
+	if len(suites) == 0 {
+		if len(target.Reports) > 0 {
+			targetLog.Debug("no test reports found")
+		}
+		a.report.AddResult(target.ID(), duration, runErr, a.output.LogPath(target.ID()))
+		return
+	}
+
+	a.report.Add(target.ID(), suites)
+	if runErr != nil && !hasFailedCase(suites) {
+		a.report.AddResult(target.ID(), duration, runErr, a.output.LogPath(target.ID()))
+	}
+}
+
+// Coverage holds the coverage merged from every target run so far, with
+// file paths relative to the repo root where they could be resolved.
+func (a *TestAction) Coverage() *coverage.Profile {
+	return a.coverage
+}
+
+// collectCoverage merges the target's declared coverage files and anything
+// it wrote to its RPM_COVERAGE_DIR.
+func (a *TestAction) collectCoverage(target *models.Target, start time.Time) {
+	if a.opts.CoverageDir == "" {
+		return
+	}
+	targetLog := a.log.WithPrefix(target.ID())
+
+	files := a.outputFiles(target, target.Coverage, start)
+	dir := a.targetCoverageDir(target)
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if e.IsDir() || slices.Contains(files, path) {
+			continue
+		}
+		if !coverage.IsCoverageFile(path) {
+			targetLog.Debug("skipping file in coverage directory", logger.String("file", e.Name()))
+			continue
+		}
+		files = append(files, path)
+	}
+
+	repoRoot := a.config.RepoRoot()
+	dirs := []string{
+		exec.ResolveWorkDir(repoRoot, target),
+		filepath.Join(repoRoot, target.BundlePath),
+		repoRoot,
+	}
+	for _, path := range files {
+		profile, err := coverage.ParseFile(path)
+		if err != nil {
+			targetLog.Warn("failed to read coverage", logger.Err(err))
+			continue
+		}
+		profile = profile.Rewrite(func(file string) string {
+			return coverage.ResolvePath(file, repoRoot, dirs...)
+		})
+
+		a.coverageMu.Lock()
+		a.coverage.Merge(profile)
+		a.coverageMu.Unlock()
+	}
+
+	if len(files) == 0 {
+		targetLog.Debug("no coverage found")
+	}
+}
+
+func (a *TestAction) targetCoverageDir(target *models.Target) string {
+	name := strings.NewReplacer(":", "__", "/", "_").Replace(target.ID())
+	return filepath.Join(a.opts.CoverageDir, name)
+}
+
+// outputFiles resolves patterns like out paths (relative to the bundle, or
+// to the repo root with a // prefix) and skips files left over from earlier
+// runs.
+func (a *TestAction) outputFiles(target *models.Target, patterns []string, start time.Time) []string {
+	since := start.Truncate(time.Second)
+
+	var files []string
+	for _, pattern := range patterns {
+		var path string
+		switch {
+		case strings.HasPrefix(pattern, "//"):
+			path = filepath.Join(a.config.RepoRoot(), pattern[2:])
+		case filepath.IsAbs(pattern):
+			path = pattern
+		default:
+			path = filepath.Join(a.config.RepoRoot(), target.BundlePath, pattern)
+		}
+
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || info.IsDir() || info.ModTime().Before(since) {
+				continue
+			}
+			files = append(files, match)
+		}
+	}
+	return files
+}
+
+func hasFailedCase(suites []junit.Suite) bool {
+	for _, s := range suites {
+		for _, c := range s.Cases {
+			if c.Failed() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// testTarget runs the target unless it is cacheable and its last passing
+// result has the same cache key, in which case its stored log is replayed.
+// When caching, every target gets a key so its dependents can derive
+// theirs. The returned key is set when a passing result should be cached
+// under it.
+func (a *TestAction) testTarget(ctx context.Context, node *dag.Node, caching, requested bool) (bool, string, error) {
+	target := node.Target
+	targetLog := a.log.WithPrefix(target.ID())
+
+	bundle := a.config.Bundles()[target.BundleName]
+	env, err := exec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target, a.secrets)
+	if err != nil {
+		targetLog.Error("failed to load environment", logger.Err(err))
+		return false, "", err
+	}
+
+	useCache := caching && requested && target.Config.Cache
+	var key string
+	if caching {
+		if key, err = a.cacheKey(node, env); err != nil && useCache {
+			targetLog.Warn("cache check failed", logger.Err(err))
+			useCache = false
+		}
+	}
+
+	if useCache {
+		_, lookupSpan := tracing.StartPhase(ctx, profile.PhaseCacheLookup)
+		hit := !a.opts.Force && a.validator.IsCached(target, key)
+		if hit {
+			if err := a.replay(target, targetLog); err != nil {
+				targetLog.Debug("cached result has no log", logger.Err(err))
+				hit = false
+			}
+		}
+		lookupSpan.End()
+
+		a.output.Events().Cache(target.ID(), hit, key)
+		if hit {
+			targetLog.Info("passed (cached)")
+			return true, "", nil
+		}
+	}
+
+	err = a.runTest(ctx, node, env)
+	if err != nil || !useCache {
+		return false, "", err
+	}
+
+	if err = a.saveLog(target); err != nil {
+		targetLog.Warn("failed to cache test result", logger.Err(err))
+		return false, "", nil
+	}
+	return false, key, nil
+}
+
+// cacheKey combines the target's inputs, command and env with the keys of
+// its dependencies, which always run first.
+func (a *TestAction) cacheKey(node *dag.Node, env []string) (string, error) {
+	a.keysMu.Lock()
+	depKeys := make(map[string]string, len(node.Deps))
+	var missing error
+	for _, dep := range node.Deps {
+		key, ok := a.keys[dep.ID]
+		if !ok {
+			missing = fmt.Errorf("no cache key for dependency %s", dep.ID)
+		}
+		depKeys[dep.ID] = key
+	}
+	a.keysMu.Unlock()
+
+	if missing != nil {
+		return "", missing
+	}
+
+	key, err := a.validator.CacheKey(node.Target, env, depKeys)
+	if err != nil {
+		return "", err
+	}
+
+	a.keysMu.Lock()
+	a.keys[node.ID] = key
+	a.keysMu.Unlock()
+	return key, nil
+}
+
+func (a *TestAction) resultLogPath(targetID string) string {
+	return filepath.Join(a.config.ResultsPath(), logs.FileName(targetID))
+}
+
+// saveLog keeps a copy of the target's log from this run to replay when its
+// result is reused.
+func (a *TestAction) saveLog(target *models.Target) error {
+	src := a.output.LogPath(target.ID())
+	if src == "" {
+		return fmt.Errorf("no log file for %s", target.ID())
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read log file %s: %w", src, err)
+	}
+
+	if err = os.MkdirAll(a.config.ResultsPath(), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", a.config.ResultsPath(), err)
+	}
+
+	path := a.resultLogPath(target.ID())
+	tmpPath := path + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", tmpPath, err)
+	}
+	return nil
+}
+
+func (a *TestAction) replay(target *models.Target, targetLog logger.Logger) error {
+	data, err := os.ReadFile(a.resultLogPath(target.ID()))
+	if err != nil {
+		return err
+	}
+
+	output := a.output.Open(target.ID(), targetLog)
+	_, _ = output.Stdout().Write(data)
+	return output.Close()
+}
+
+func (a *TestAction) runTest(ctx context.Context, node *dag.Node, env []string) error {
 	target := node.Target
 	targetLog := a.log.WithPrefix(target.ID())
 
 	targetLog.Info("testing...")
 
-	bundle := a.config.Bundles()[target.BundleName]
-	env := exec.ComposeEnv(a.config.RepoRoot(), a.config.Repo(), bundle, target)
 	workDir := exec.ResolveWorkDir(a.config.RepoRoot(), target)
 
-	err := exec.RunCommand(ctx, target.Cmd, &exec.ShellOptions{
+	if a.opts.CoverageDir != "" {
+		dir := a.targetCoverageDir(target)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			targetLog.Error("failed to create coverage directory", logger.Err(err))
+			return err
+		}
+		env = append(env, "RPM_COVERAGE=1", "RPM_COVERAGE_DIR="+dir)
+	}
+
+	output := a.output.Open(target.ID(), targetLog)
+	cmdCtx, cmdSpan := tracing.StartPhase(ctx, profile.PhaseCommand)
+	err := exec.RunCommand(cmdCtx, target.Cmd, &exec.ShellOptions{
 		WorkDir: workDir,
-		Env:     env,
+		Env:     append(env, tracing.Environ(cmdCtx)...),
 		Shell:   a.config.Repo().Shell,
-		Stdout:  targetLog.Writer(),
-		Stderr:  targetLog.Writer(),
+		Stdout:  output.Stdout(),
+		Stderr:  output.Stderr(),
+		OnStart: a.output.onStart(target.ID(), target.Cmd, workDir),
 	})
+	output.Close()
+	tracing.End(cmdSpan, err)
 
 	if err != nil {
-		targetLog.Error("test failed", logger.Err(err))
+		targetLog.Error("test failed", logger.Err(err), logger.String("log", a.output.LogPath(target.ID())))
 		return err
 	}
 
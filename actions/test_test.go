@@ -0,0 +1,130 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/stores/builds"
+)
+
+func TestTestAction_RecordResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *builds.Entry
+		result   error
+		skipped  bool
+		key      string
+		expected *builds.Entry
+	}{
+		{
+			name:     "passing result is cached",
+			key:      "sha256:new",
+			expected: &builds.Entry{InputHash: "sha256:new", DurationMs: 1500},
+		},
+		{
+			name:     "passing result replaces the previous key",
+			existing: &builds.Entry{InputHash: "sha256:old", DurationMs: 100},
+			key:      "sha256:new",
+			expected: &builds.Entry{InputHash: "sha256:new", DurationMs: 1500},
+		},
+		{
+			name:   "failed result is never cached",
+			result: errors.New("exit status 1"),
+			key:    "sha256:new",
+		},
+		{
+			name:     "failure clears the previous entry",
+			existing: &builds.Entry{InputHash: "sha256:old", DurationMs: 100},
+			result:   errors.New("exit status 1"),
+			expected: &builds.Entry{InputHash: "", DurationMs: 100},
+		},
+		{
+			name:     "cached result is left alone",
+			existing: &builds.Entry{InputHash: "sha256:old", DurationMs: 100},
+			skipped:  true,
+			expected: &builds.Entry{InputHash: "sha256:old", DurationMs: 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "builds.json")
+			store := builds.NewStore(path)
+			if tt.existing != nil {
+				store.Set("core:unit_test", tt.existing)
+			}
+			a := &TestAction{store: store, log: logger.NewWithWriter(logger.InfoLevel, io.Discard)}
+
+			cacheKeys := map[string]string{}
+			if tt.key != "" && tt.result == nil {
+				cacheKeys["core:unit_test"] = tt.key
+			}
+			a.recordResults(
+				[]string{"core:unit_test"},
+				map[string]error{"core:unit_test": tt.result},
+				map[string]bool{"core:unit_test": tt.skipped},
+				cacheKeys,
+				map[string]time.Duration{"core:unit_test": 1500 * time.Millisecond},
+			)
+
+			stores := []*builds.Store{store}
+			if !tt.skipped {
+				loaded := builds.NewStore(path)
+				require.NoError(t, loaded.Load())
+				stores = append(stores, loaded)
+			}
+			for _, s := range stores {
+				entry, ok := s.Get("core:unit_test")
+				if tt.expected == nil {
+					assert.False(t, ok && entry.InputHash != "", "failed result must not be cached")
+					continue
+				}
+				require.True(t, ok)
+				assert.Equal(t, tt.expected.InputHash, entry.InputHash)
+				assert.Equal(t, tt.expected.DurationMs, entry.DurationMs)
+			}
+		})
+	}
+}
+
+func TestTestAction_Report(t *testing.T) {
+	cfg, graph := newTestRepo(t, map[string]string{
+		"lib/rpm.yml": `name: lib
+targets:
+  - name: lib_build
+    cmd: "true"
+  - name: bad_build
+    cmd: "exit 1"
+  - name: unit_test
+    deps: [":lib_build"]
+    cmd: "true"
+  - name: blocked_test
+    deps: [":bad_build"]
+    cmd: "true"
+`,
+	})
+	output := NewOutput(logger.OutputStream, nil, nil, nil)
+	a := NewTestAction(cfg, graph, newTestStore(t, cfg), discardLogger(), output, TestOptions{Parallel: 2})
+
+	result, err := a.Execute(context.Background(), []string{"lib:unit_test", "lib:blocked_test"})
+	require.NoError(t, err)
+	assert.Len(t, result.Failed, 2)
+
+	// Dependencies are not test cases; a test blocked by one is skipped.
+	suites := a.Report().Suites()
+	require.Len(t, suites.Suites, 2)
+	assert.Equal(t, "lib:blocked_test", suites.Suites[0].Name)
+	require.NotNil(t, suites.Suites[0].Cases[0].Skipped)
+	assert.Equal(t, "lib:unit_test", suites.Suites[1].Name)
+	assert.False(t, suites.Suites[1].Cases[0].Failed())
+	assert.Equal(t, 1, suites.Skipped)
+	assert.Zero(t, suites.Failures)
+}
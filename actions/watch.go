@@ -0,0 +1,198 @@
+package actions
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/vcnkl/rpm/cache/hashing"
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/dag"
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/models"
+	"github.com/vcnkl/rpm/stores/builds"
+)
+
+type WatchMode string
+
+const (
+	WatchBuild WatchMode = "build"
+	WatchTest  WatchMode = "test"
+)
+
+type WatchOptions struct {
+	Mode     WatchMode
+	Parallel int
+	// Clear is called before each run, e.g. to clear the terminal.
+	Clear func()
+}
+
+// WatchAction builds or tests targets, then runs them again whenever their
+// inputs or those of the targets they depend on change, keeping the graph
+// and builds store loaded in between.
+type WatchAction struct {
+	config *config.Config
+	graph  *dag.Graph
+	store  *builds.Store
+	log    logger.Logger
+	output *Output
+	opts   WatchOptions
+}
+
+func NewWatchAction(cfg *config.Config, graph *dag.Graph, store *builds.Store, log logger.Logger, output *Output, opts WatchOptions) *WatchAction {
+	return &WatchAction{
+		config: cfg,
+		graph:  graph,
+		store:  store,
+		log:    log,
+		output: output,
+		opts:   opts,
+	}
+}
+
+// Execute runs targetIDs once and then on every change until ctx is done.
+func (a *WatchAction) Execute(ctx context.Context, targetIDs []string) error {
+	set, outputs, ignore, err := a.watchSet(targetIDs)
+	if err != nil {
+		return err
+	}
+
+	w, err := newWatcher(a.config, set.roots(), ignore, a.log)
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+
+	changes := newChangeQueue()
+	w.OnChange(changes.add)
+	go w.Start(ctx)
+
+	selected := make(map[string]bool, len(targetIDs))
+	for _, id := range targetIDs {
+		selected[id] = true
+	}
+
+	a.run(ctx, targetIDs)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changes.ready:
+		}
+
+		paths, rescan := changes.take()
+		ids := rerunTargets(set, outputs, selected, paths, rescan)
+		if len(ids) == 0 {
+			continue
+		}
+		a.run(ctx, ids)
+	}
+}
+
+// watchSet returns the inputs of the targets and of every non-dev target
+// they depend on, the outputs, reports and coverage files those targets
+// write, which are not treated as changes, and their ignore patterns.
+func (a *WatchAction) watchSet(targetIDs []string) (*watchSet, []string, []string, error) {
+	sorted, err := a.graph.SubgraphFor(targetIDs).TopologicalSort()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	set := &watchSet{inputs: make(map[string][]string)}
+	var outputs, ignore []string
+	for _, n := range sorted {
+		if n.Target.HasSuffix("_dev") {
+			continue
+		}
+		set.builds = append(set.builds, n)
+		set.inputs[n.ID] = targetInputs(a.config.RepoRoot(), n.Target)
+
+		bundleRoot := filepath.Join(a.config.RepoRoot(), n.Target.BundlePath)
+		for _, patterns := range [][]string{n.Target.Out, n.Target.Reports, n.Target.Coverage} {
+			for _, pattern := range patterns {
+				outputs = append(outputs, hashing.ResolveInput(a.config.RepoRoot(), bundleRoot, pattern))
+			}
+		}
+		ignore = append(ignore, n.Target.Config.Ignore...)
+	}
+	return set, outputs, ignore, nil
+}
+
+// rerunTargets returns the selected targets affected by changes to paths,
+// or by any change when changes were lost.
+func rerunTargets(set *watchSet, outputs []string, selected map[string]bool, paths []string, rescan bool) []string {
+	matched := set.match(withoutOutputs(paths, outputs))
+	if rescan {
+		matched = set.all()
+	}
+
+	var ids []string
+	for _, id := range set.affected(matched) {
+		if selected[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func withoutOutputs(paths []string, outputs []string) []string {
+	var kept []string
+	for _, path := range paths {
+		isOutput := false
+		for _, pattern := range outputs {
+			if hashing.MatchInput(pattern, path) {
+				isOutput = true
+				break
+			}
+		}
+		if !isOutput {
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
+
+func (a *WatchAction) run(ctx context.Context, targetIDs []string) {
+	if a.opts.Clear != nil {
+		a.opts.Clear()
+	}
+
+	a.output.Events().RunStarted("watch "+string(a.opts.Mode), nil, targetIDs)
+
+	var result *models.Result
+	var err error
+	if a.opts.Mode == WatchTest {
+		action := NewTestAction(a.config, a.graph, a.store, a.log, a.output, TestOptions{Parallel: a.opts.Parallel})
+		result, err = action.Execute(ctx, targetIDs)
+		if err == nil {
+			for _, f := range action.Report().Failures() {
+				a.log.WithPrefix(f.Target).Error("test case failed",
+					logger.String("case", f.Case),
+					logger.String("reason", f.Message))
+			}
+		}
+	} else {
+		result, err = NewBuildAction(a.config, a.graph, a.store, a.log, a.output, a.opts.Parallel, false).Execute(ctx, targetIDs)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		a.log.Error(string(a.opts.Mode)+" failed", logger.Err(err))
+		return
+	}
+	a.output.Events().RunFinished(result)
+
+	for _, f := range result.Failed {
+		a.log.WithPrefix(f.ID).Error("failed", logger.Err(f.Error))
+	}
+	status := "passed"
+	if len(result.Failed) > 0 {
+		status = "failed"
+	}
+	a.log.Info(string(a.opts.Mode)+" "+status,
+		logger.Int("executed", len(result.Executed)),
+		logger.Int("cached", len(result.Skipped)),
+		logger.Int("failed", len(result.Failed)),
+		logger.Duration("duration", result.Duration))
+	a.log.Info("watching for changes...")
+}
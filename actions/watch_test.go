@@ -0,0 +1,162 @@
+package actions
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vcnkl/rpm/logger"
+)
+
+var watchRepo = map[string]string{
+	"lib/rpm.yml": `name: lib
+targets:
+  - name: proto_build
+    in: ["proto/*.txt"]
+    out: ["src/gen.txt"]
+    cmd: "true"
+  - name: lib_build
+    in: ["src/*.txt"]
+    out: ["dist/**"]
+    deps: [":proto_build"]
+    cmd: "true"
+  - name: unit_test
+    in: ["src/*.txt", "test/*.txt"]
+    deps: [":lib_build"]
+    reports: ["test/junit.txt"]
+    coverage: ["test/coverage.txt"]
+    cmd: "true"
+    config: {ignore: ["*.tmp"]}
+  - name: server_dev
+    deps: [":lib_build"]
+    cmd: sleep 10
+`,
+}
+
+func newTestWatchAction(t *testing.T) *WatchAction {
+	t.Helper()
+	cfg, graph := newTestRepo(t, watchRepo)
+	output := NewOutput(logger.OutputStream, nil, nil, nil)
+	return NewWatchAction(cfg, graph, newTestStore(t, cfg), discardLogger(), output, WatchOptions{Mode: WatchTest})
+}
+
+func TestWatchAction_WatchSet(t *testing.T) {
+	a := newTestWatchAction(t)
+	lib := filepath.Join(a.config.RepoRoot(), "lib")
+
+	set, outputs, ignore, err := a.watchSet([]string{"lib:unit_test", "lib:server_dev"})
+	require.NoError(t, err)
+
+	// Dev targets are not run by watch, so their inputs are not watched.
+	assert.Equal(t, []string{"lib:proto_build", "lib:lib_build", "lib:unit_test"}, nodeIDs(set.builds))
+	assert.Equal(t, map[string][]string{
+		"lib:proto_build": {filepath.Join(lib, "proto/*.txt")},
+		"lib:lib_build":   {filepath.Join(lib, "src/*.txt")},
+		"lib:unit_test":   {filepath.Join(lib, "src/*.txt"), filepath.Join(lib, "test/*.txt")},
+	}, set.inputs)
+	assert.Equal(t, []string{
+		filepath.Join(lib, "src/gen.txt"),
+		filepath.Join(lib, "dist/**"),
+		filepath.Join(lib, "test/junit.txt"),
+		filepath.Join(lib, "test/coverage.txt"),
+	}, outputs)
+	assert.Equal(t, []string{"*.tmp"}, ignore)
+}
+
+func TestWithoutOutputs(t *testing.T) {
+	outputs := []string{"/repo/lib/gen", "/repo/lib/dist/**", "/repo/lib/reports/junit.xml"}
+
+	tests := []struct {
+		name     string
+		paths    []string
+		expected []string
+	}{
+		{
+			name:     "inputs are kept",
+			paths:    []string{"/repo/lib/src/a.txt"},
+			expected: []string{"/repo/lib/src/a.txt"},
+		},
+		{
+			name:  "outputs below a directory",
+			paths: []string{"/repo/lib/gen/api.go", "/repo/lib/dist/nested/lib.a"},
+		},
+		{
+			name:  "reports",
+			paths: []string{"/repo/lib/reports/junit.xml"},
+		},
+		{
+			name:     "mixed",
+			paths:    []string{"/repo/lib/gen/api.go", "/repo/lib/src/a.txt", "/repo/lib/generated.txt"},
+			expected: []string{"/repo/lib/src/a.txt", "/repo/lib/generated.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, withoutOutputs(tt.paths, outputs))
+		})
+	}
+}
+
+func TestRerunTargets(t *testing.T) {
+	a := newTestWatchAction(t)
+	lib := filepath.Join(a.config.RepoRoot(), "lib")
+
+	tests := []struct {
+		name     string
+		selected []string
+		paths    []string
+		rescan   bool
+		expected []string
+	}{
+		{
+			name:     "dependency inputs rerun the selected target",
+			selected: []string{"lib:unit_test"},
+			paths:    []string{filepath.Join(lib, "proto/api.txt")},
+			expected: []string{"lib:unit_test"},
+		},
+		{
+			name:     "selected dependencies rerun too",
+			selected: []string{"lib:lib_build", "lib:unit_test"},
+			paths:    []string{filepath.Join(lib, "src/a.txt")},
+			expected: []string{"lib:lib_build", "lib:unit_test"},
+		},
+		{
+			name:     "unaffected selected targets are not rerun",
+			selected: []string{"lib:lib_build", "lib:unit_test"},
+			paths:    []string{filepath.Join(lib, "test/a.txt")},
+			expected: []string{"lib:unit_test"},
+		},
+		{
+			name:     "outputs do not retrigger",
+			selected: []string{"lib:unit_test"},
+			paths:    []string{filepath.Join(lib, "src/gen.txt"), filepath.Join(lib, "dist/lib.a")},
+		},
+		{
+			name:     "reports and coverage do not retrigger",
+			selected: []string{"lib:unit_test"},
+			paths:    []string{filepath.Join(lib, "test/junit.txt"), filepath.Join(lib, "test/coverage.txt")},
+		},
+		{
+			name:     "lost changes rerun every selected target",
+			selected: []string{"lib:unit_test"},
+			rescan:   true,
+			expected: []string{"lib:unit_test"},
+		},
+	}
+
+	set, outputs, _, err := a.watchSet([]string{"lib:lib_build", "lib:unit_test"})
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected := make(map[string]bool)
+			for _, id := range tt.selected {
+				selected[id] = true
+			}
+			assert.Equal(t, tt.expected, rerunTargets(set, outputs, selected, tt.paths, tt.rescan))
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package hashing
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// racyWindow covers file systems with coarse timestamps: a file modified
+// this close to when it was hashed may have changed again since without its
+// size or modification time changing, so its hash is not reused.
+const racyWindow = 2 * time.Second
+
+type cachedFile struct {
+	size     int64
+	modTime  time.Time
+	inode    uint64
+	hashedAt time.Time
+	hash     string
+}
+
+// FileCache remembers file hashes along with the size, modification time
+// and inode they were computed for, so unchanged files are not read again.
+// Owners that learn of changes, such as from a file watcher, should Forget
+// the changed paths, as an edit that keeps all of those is not detected.
+type FileCache struct {
+	mu    sync.Mutex
+	files map[string]cachedFile
+}
+
+func NewFileCache() *FileCache {
+	return &FileCache{files: make(map[string]cachedFile)}
+}
+
+// HashInputs is HashInputs using the cached file hashes.
+func (c *FileCache) HashInputs(bundleRoot string, patterns []string) (string, error) {
+	return hashInputs(bundleRoot, patterns, c.HashFile)
+}
+
+// HashFile returns the hash of path, reading it only when it changed since
+// it was last hashed.
+func (c *FileCache) HashFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return HashFile(path)
+	}
+
+	inode := fileInode(info)
+
+	c.mu.Lock()
+	cached, ok := c.files[path]
+	c.mu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) && cached.inode == inode &&
+		cached.hashedAt.Sub(cached.modTime) > racyWindow {
+		return cached.hash, nil
+	}
+
+	hashedAt := time.Now()
+	hash, err := HashFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.files[path] = cachedFile{size: info.Size(), modTime: info.ModTime(), inode: inode, hashedAt: hashedAt, hash: hash}
+	c.mu.Unlock()
+	return hash, nil
+}
+
+func fileInode(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
+
+// Forget drops the hashes of path and of every file below it.
+func (c *FileCache) Forget(path string) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.files, path)
+	for p := range c.files {
+		if strings.HasPrefix(p, prefix) {
+			delete(c.files, p)
+		}
+	}
+}
+
+// Clear drops every hash.
+func (c *FileCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files = make(map[string]cachedFile)
+}
+
+// Len returns the number of cached hashes.
+func (c *FileCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.files)
+}
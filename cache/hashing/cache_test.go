@@ -0,0 +1,111 @@
+package hashing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCache_HashInputs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.go"), []byte("package b"), 0644))
+
+	cache := NewFileCache()
+	patterns := []string{"**/*.go"}
+
+	expected, err := HashInputs(dir, patterns)
+	require.NoError(t, err)
+	hash, err := cache.HashInputs(dir, patterns)
+	require.NoError(t, err)
+	assert.Equal(t, expected, hash)
+	assert.Equal(t, 2, cache.Len())
+
+	path := filepath.Join(dir, "a.go")
+	require.NoError(t, os.WriteFile(path, []byte("package a // changed"), 0644))
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	expected, err = HashInputs(dir, patterns)
+	require.NoError(t, err)
+	hash, err = cache.HashInputs(dir, patterns)
+	require.NoError(t, err)
+	assert.Equal(t, expected, hash)
+}
+
+func TestFileCache_Forget(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "sub/b.txt", "sub/c.txt", "subway.txt"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0644))
+	}
+
+	cache := NewFileCache()
+	_, err := cache.HashInputs(dir, []string{"**"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, cache.Len())
+
+	cache.Forget(filepath.Join(dir, "sub"))
+	assert.Equal(t, 2, cache.Len())
+
+	cache.Forget(filepath.Join(dir, "a.txt"))
+	assert.Equal(t, 1, cache.Len())
+
+	cache.Clear()
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestFileCache_SameSizeEdits(t *testing.T) {
+	tests := []struct {
+		name string
+		// age is how long before the first hash the file was modified.
+		age  time.Duration
+		edit func(t *testing.T, path string, modTime time.Time)
+	}{
+		{
+			name: "recently modified file keeping its mtime",
+			edit: func(t *testing.T, path string, modTime time.Time) {
+				require.NoError(t, os.WriteFile(path, []byte("bbbb"), 0644))
+				require.NoError(t, os.Chtimes(path, modTime, modTime))
+			},
+		},
+		{
+			name: "file replaced keeping its mtime",
+			age:  time.Hour,
+			edit: func(t *testing.T, path string, modTime time.Time) {
+				tmp := path + ".tmp"
+				require.NoError(t, os.WriteFile(tmp, []byte("bbbb"), 0644))
+				require.NoError(t, os.Chtimes(tmp, modTime, modTime))
+				require.NoError(t, os.Rename(tmp, path))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "a.txt")
+			require.NoError(t, os.WriteFile(path, []byte("aaaa"), 0644))
+			modTime := time.Now().Add(-tt.age).Truncate(time.Second)
+			require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+			cache := NewFileCache()
+			before, err := cache.HashFile(path)
+			require.NoError(t, err)
+
+			tt.edit(t, path, modTime)
+
+			expected, err := HashFile(path)
+			require.NoError(t, err)
+			hash, err := cache.HashFile(path)
+			require.NoError(t, err)
+			assert.NotEqual(t, before, hash)
+			assert.Equal(t, expected, hash)
+		})
+	}
+}
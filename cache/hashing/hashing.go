@@ -11,7 +11,26 @@ import (
 	"strings"
 )
 
+// InputHasher hashes the files matching input patterns the way HashInputs
+// does.
+type InputHasher interface {
+	HashInputs(bundleRoot string, patterns []string) (string, error)
+}
+
+type localHasher struct{}
+
+func (localHasher) HashInputs(bundleRoot string, patterns []string) (string, error) {
+	return HashInputs(bundleRoot, patterns)
+}
+
+// Local hashes inputs in-process, reading every file.
+var Local InputHasher = localHasher{}
+
 func HashInputs(bundleRoot string, patterns []string) (string, error) {
+	return hashInputs(bundleRoot, patterns, HashFile)
+}
+
+func hashInputs(bundleRoot string, patterns []string, hashFile func(path string) (string, error)) (string, error) {
 	var allFiles []string
 
 	for _, pattern := range patterns {
@@ -41,7 +60,7 @@ func HashInputs(bundleRoot string, patterns []string) (string, error) {
 			continue
 		}
 
-		fileHash, err := HashFile(file)
+		fileHash, err := hashFile(file)
 		if err != nil {
 			return "", err
 		}
@@ -51,6 +70,57 @@ func HashInputs(bundleRoot string, patterns []string) (string, error) {
 	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// CombineHashes derives a key from base and the keys of dependencies, so a
+// change to any dependency changes the result.
+func CombineHashes(base string, deps map[string]string) string {
+	ids := make([]string, 0, len(deps))
+	for id := range deps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	h := sha256.New()
+	h.Write([]byte(base))
+	for _, id := range ids {
+		fmt.Fprintf(h, "\n%s=%s", id, deps[id])
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// HashEnv hashes the variables of env that are not inherited unchanged, so
+// the result follows what rpm sets for a command but not unrelated shell
+// state. Later entries override earlier ones, as in exec.Cmd.
+func HashEnv(env, inherited []string) string {
+	parent := envMap(inherited)
+	vars := envMap(env)
+
+	keys := make([]string, 0, len(vars))
+	for k, v := range vars {
+		if pv, ok := parent[k]; ok && pv == v {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\x00", k, vars[k])
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+func envMap(env []string) map[string]string {
+	vars := make(map[string]string, len(env))
+	for _, e := range env {
+		if k, v, ok := strings.Cut(e, "="); ok {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
 func expandGlob(pattern string) ([]string, error) {
 	if strings.Contains(pattern, "**") {
 		return expandDoubleStarGlob(pattern)
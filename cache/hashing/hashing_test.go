@@ -3,6 +3,7 @@ package hashing
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -357,3 +358,38 @@ func TestExpandDoubleStarGlob(t *testing.T) {
 		})
 	}
 }
+
+func TestCombineHashes(t *testing.T) {
+	base := CombineHashes("sha256:abc", map[string]string{"a:build": "sha256:1", "b:build": "sha256:2"})
+
+	assert.Equal(t, base, CombineHashes("sha256:abc", map[string]string{"b:build": "sha256:2", "a:build": "sha256:1"}))
+	assert.NotEqual(t, base, CombineHashes("sha256:abd", map[string]string{"a:build": "sha256:1", "b:build": "sha256:2"}))
+	assert.NotEqual(t, base, CombineHashes("sha256:abc", map[string]string{"a:build": "sha256:1", "b:build": "sha256:3"}))
+	assert.NotEqual(t, base, CombineHashes("sha256:abc", map[string]string{"a:build": "sha256:1"}))
+	assert.NotEqual(t, CombineHashes("sha256:abc", nil), "sha256:abc")
+	assert.True(t, strings.HasPrefix(base, "sha256:"))
+}
+
+func TestHashEnv(t *testing.T) {
+	inherited := []string{"HOME=/home/u", "TERM=xterm"}
+	base := HashEnv([]string{"HOME=/home/u", "TERM=xterm", "PORT=8080", "TOKEN=a"}, inherited)
+
+	tests := []struct {
+		name  string
+		env   []string
+		equal bool
+	}{
+		{name: "order does not matter", env: []string{"TOKEN=a", "PORT=8080", "TERM=xterm", "HOME=/home/u"}, equal: true},
+		{name: "inherited vars are ignored", env: []string{"PORT=8080", "TOKEN=a"}, equal: true},
+		{name: "later entries win", env: []string{"PORT=1", "PORT=8080", "TOKEN=a"}, equal: true},
+		{name: "changed value", env: []string{"PORT=8080", "TOKEN=b"}, equal: false},
+		{name: "overridden inherited var", env: []string{"HOME=/tmp", "PORT=8080", "TOKEN=a"}, equal: false},
+		{name: "removed var", env: []string{"PORT=8080"}, equal: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.equal, HashEnv(tt.env, inherited) == base)
+		})
+	}
+}
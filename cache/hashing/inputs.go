@@ -0,0 +1,73 @@
+package hashing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveInput returns the absolute form of an input pattern. Patterns
+// starting with // are relative to repoRoot, others to bundleRoot.
+func ResolveInput(repoRoot, bundleRoot, pattern string) string {
+	if startsWithRepoRoot(pattern) {
+		return filepath.Join(repoRoot, pattern[2:])
+	}
+	if filepath.IsAbs(pattern) {
+		return filepath.Clean(pattern)
+	}
+	return filepath.Join(bundleRoot, pattern)
+}
+
+// InputRoot returns the deepest directory containing every file an absolute
+// input pattern can match.
+func InputRoot(pattern string) string {
+	parts := strings.Split(pattern, string(filepath.Separator))
+	for i, part := range parts {
+		if hasMeta(part) {
+			root := strings.Join(parts[:i], string(filepath.Separator))
+			if root == "" {
+				return string(filepath.Separator)
+			}
+			return root
+		}
+	}
+
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		return pattern
+	}
+	return filepath.Dir(pattern)
+}
+
+// MatchInput reports whether path is matched by an absolute input pattern,
+// using the same rules HashInputs uses to expand it. A pattern without
+// wildcards also matches the files below it.
+func MatchInput(pattern, path string) bool {
+	if strings.Contains(pattern, "**") {
+		parts := strings.Split(pattern, "**")
+		if len(parts) == 2 {
+			baseDir := strings.TrimSuffix(parts[0], "/")
+			suffix := strings.TrimPrefix(parts[1], "/")
+			if !isWithin(baseDir, path) {
+				return false
+			}
+			if suffix == "" {
+				return true
+			}
+			matched, _ := filepath.Match(suffix, filepath.Base(path))
+			return matched
+		}
+	}
+
+	if matched, _ := filepath.Match(pattern, path); matched {
+		return true
+	}
+	return !hasMeta(pattern) && isWithin(pattern, path)
+}
+
+func isWithin(dir, path string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+func hasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
@@ -0,0 +1,142 @@
+package hashing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		expected string
+	}{
+		{
+			name:     "bundle relative",
+			pattern:  "src/*.go",
+			expected: "/repo/svc/src/*.go",
+		},
+		{
+			name:     "repo root relative",
+			pattern:  "//libs/core/**/*.go",
+			expected: "/repo/libs/core/**/*.go",
+		},
+		{
+			name:     "absolute",
+			pattern:  "/etc/config.yml",
+			expected: "/etc/config.yml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ResolveInput("/repo", "/repo/svc", tt.pattern))
+		})
+	}
+}
+
+func TestInputRoot(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "src"), 0755))
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected string
+	}{
+		{
+			name:     "glob in file name",
+			pattern:  filepath.Join(tmpDir, "src", "*.go"),
+			expected: filepath.Join(tmpDir, "src"),
+		},
+		{
+			name:     "double star",
+			pattern:  filepath.Join(tmpDir, "**", "*.go"),
+			expected: tmpDir,
+		},
+		{
+			name:     "directory",
+			pattern:  filepath.Join(tmpDir, "src"),
+			expected: filepath.Join(tmpDir, "src"),
+		},
+		{
+			name:     "file",
+			pattern:  filepath.Join(tmpDir, "go.mod"),
+			expected: tmpDir,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, InputRoot(tt.pattern))
+		})
+	}
+}
+
+func TestMatchInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{
+			name:     "glob match",
+			pattern:  "/repo/svc/*.go",
+			path:     "/repo/svc/main.go",
+			expected: true,
+		},
+		{
+			name:     "glob does not match subdirectory",
+			pattern:  "/repo/svc/*.go",
+			path:     "/repo/svc/pkg/main.go",
+			expected: false,
+		},
+		{
+			name:     "double star matches nested file",
+			pattern:  "/repo/svc/**/*.go",
+			path:     "/repo/svc/pkg/util/main.go",
+			expected: true,
+		},
+		{
+			name:     "double star filters by name",
+			pattern:  "/repo/svc/**/*.go",
+			path:     "/repo/svc/pkg/README.md",
+			expected: false,
+		},
+		{
+			name:     "double star outside base",
+			pattern:  "/repo/svc/**",
+			path:     "/repo/svc2/main.go",
+			expected: false,
+		},
+		{
+			name:     "exact file",
+			pattern:  "/repo/svc/go.mod",
+			path:     "/repo/svc/go.mod",
+			expected: true,
+		},
+		{
+			name:     "file below directory",
+			pattern:  "/repo/svc/src",
+			path:     "/repo/svc/src/main.go",
+			expected: true,
+		},
+		{
+			name:     "sibling with shared prefix",
+			pattern:  "/repo/svc/src",
+			path:     "/repo/svc/src2/main.go",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, MatchInput(tt.pattern, tt.path))
+		})
+	}
+}
@@ -36,8 +36,11 @@ func NewApp() *cli.App {
 			subcmds.BuildCmd(),
 			subcmds.TestCmd(),
 			subcmds.DevCmd(),
+			subcmds.WatchCmd(),
+			subcmds.DaemonCmd(),
 			subcmds.RunCmd(),
 			subcmds.GraphCmd(),
+			subcmds.HistoryCmd(),
 		},
 	}
 }
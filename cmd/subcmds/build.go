@@ -2,9 +2,9 @@ package subcmds
 
 import (
 	"strings"
+	"time"
 
 	"github.com/vcnkl/rpm/actions"
-	"github.com/vcnkl/rpm/config"
 	"github.com/vcnkl/rpm/dag"
 	"github.com/vcnkl/rpm/git"
 	"github.com/vcnkl/rpm/logger"
@@ -36,6 +36,10 @@ func BuildCmd() *cli.Command {
 				Name:  "dry-run",
 				Usage: "Print what would be built without executing",
 			},
+			outputFlag(),
+			rawFlag(),
+			eventsFlag(),
+			profileFlag(),
 		},
 		Action: func(ctx *cli.Context) error {
 			debug := ctx.Bool("debug")
@@ -49,9 +53,9 @@ func BuildCmd() *cli.Command {
 			if debug {
 				level = logger.DebugLevel
 			}
-			log := logger.New(level)
+			log := logger.NewWithOutput(level, logOutput(ctx))
 
-			cfg := config.NewConfig()
+			cfg := loadConfig()
 
 			graph := dag.NewGraph()
 			for _, bundle := range cfg.Bundles() {
@@ -107,17 +111,40 @@ func BuildCmd() *cli.Command {
 				return nil
 			}
 
-			action := actions.NewBuildAction(cfg, graph, store, log, parallel, force)
+			output, err := newOutput(ctx, cfg, log)
+			if err != nil {
+				return cli.Exit("error: "+err.Error(), 1)
+			}
+			defer output.Close()
+
+			action := actions.NewBuildAction(cfg, graph, store, log, output, parallel, force)
 
 			if dryRun {
 				action.DryRun(targetIDs)
 				return nil
 			}
 
-			result, err := action.Execute(ctx.Context, targetIDs)
+			started := time.Now()
+			runCtx, finish := startRun(ctx, cfg, log)
+			defer finish()
+
+			output.Events().RunStarted(ctx.Command.Name, ctx.Args().Slice(), targetIDs)
+			result, err := action.Execute(runCtx, targetIDs)
 			if err != nil {
+				output.Events().RunError(err, time.Since(started))
 				return cli.Exit("error: "+err.Error(), 1)
 			}
+			output.Events().RunFinished(result)
+			recordResult(runCtx, result)
+			recordHistory(ctx, cfg, log, output, started, targetIDs, result)
+
+			if path := ctx.String("profile"); path != "" {
+				if err = output.Profile().WriteFile(path); err != nil {
+					log.Warn("failed to write profile", logger.Err(err))
+				} else {
+					log.Info("profile written", logger.String("path", path))
+				}
+			}
 
 			log.Info("build completed",
 				logger.Int("executed", len(result.Executed)),
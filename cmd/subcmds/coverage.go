@@ -0,0 +1,49 @@
+package subcmds
+
+import (
+	"fmt"
+
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/coverage"
+	"github.com/vcnkl/rpm/logger"
+)
+
+const keepCoverageRuns = 20
+
+// coverageRunDir returns the directory that holds this run's per-target
+// coverage directories, pruning those of older runs.
+func coverageRunDir(cfg *config.Config, log logger.Logger, runID string) string {
+	dir, err := coverage.RunDir(cfg.CoveragePath(), runID, keepCoverageRuns-1)
+	if err != nil {
+		log.Warn("failed to prune old coverage", logger.Err(err))
+	}
+	return dir
+}
+
+func writeCoverage(cfg *config.Config, log logger.Logger, profile *coverage.Profile, path string) {
+	total, covered := profile.Lines()
+	if total == 0 {
+		log.Warn("no coverage collected")
+		return
+	}
+
+	bundles := make(map[string]string, len(cfg.Bundles()))
+	for name, bundle := range cfg.Bundles() {
+		bundles[name] = bundle.Path
+	}
+	for _, s := range coverage.Summarize(profile, bundles) {
+		log.Info("coverage",
+			logger.String("bundle", s.Name),
+			logger.String("lines", fmt.Sprintf("%d/%d", s.Covered, s.Total)),
+			logger.String("percent", fmt.Sprintf("%.1f%%", s.Percent())))
+	}
+
+	if err := profile.WriteFile(path); err != nil {
+		log.Error("failed to write coverage report", logger.Err(err))
+		return
+	}
+	log.Info("coverage report written",
+		logger.String("path", path),
+		logger.String("lines", fmt.Sprintf("%d/%d", covered, total)),
+		logger.String("percent", fmt.Sprintf("%.1f%%", coverage.Percent(total, covered))))
+}
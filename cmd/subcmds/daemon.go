@@ -0,0 +1,190 @@
+package subcmds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/daemon"
+	"github.com/vcnkl/rpm/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// daemonStartTimeout is how long `rpm daemon start` waits for the daemon to
+// serve its socket.
+const daemonStartTimeout = 10 * time.Second
+
+func DaemonCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "daemon",
+		Usage: "Keep the repository's config and file hashes loaded for faster commands",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "start",
+				Usage: "Start the daemon in the background",
+				Action: func(ctx *cli.Context) error {
+					repoRoot := config.FindRepoRoot()
+					client := daemon.NewClient(config.DaemonPath(repoRoot))
+					if status, err := client.Status(); err == nil {
+						fmt.Printf("daemon already running (pid %d)\n", status.PID)
+						return nil
+					}
+
+					pid, err := startDaemon(repoRoot)
+					if err != nil {
+						return cli.Exit("error: "+err.Error(), 1)
+					}
+
+					deadline := time.Now().Add(daemonStartTimeout)
+					for time.Now().Before(deadline) {
+						if _, err = client.Status(); err == nil {
+							fmt.Printf("daemon started (pid %d)\n", pid)
+							return nil
+						}
+						time.Sleep(50 * time.Millisecond)
+					}
+					return cli.Exit("error: daemon did not start, see .rpm/daemon.log", 1)
+				},
+			},
+			{
+				Name:  "run",
+				Usage: "Run the daemon in the foreground",
+				Action: func(ctx *cli.Context) error {
+					level := logger.InfoLevel
+					if ctx.Bool("debug") {
+						level = logger.DebugLevel
+					}
+					log := logger.NewWithOutput(level, os.Stdout)
+
+					sigCtx, stop := signal.NotifyContext(ctx.Context, syscall.SIGINT, syscall.SIGTERM)
+					defer stop()
+
+					repoRoot := config.FindRepoRoot()
+					d := daemon.New(repoRoot, log)
+					if err := d.Run(sigCtx, config.DaemonPath(repoRoot)); err != nil {
+						return cli.Exit("error: "+err.Error(), 1)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "stop",
+				Usage: "Stop the daemon",
+				Action: func(ctx *cli.Context) error {
+					client := daemon.NewClient(config.DaemonPath(config.FindRepoRoot()))
+					if err := client.Stop(); err != nil {
+						if errors.Is(err, daemon.ErrNotRunning) {
+							fmt.Println("daemon not running")
+							return nil
+						}
+						return cli.Exit("error: "+err.Error(), 1)
+					}
+					fmt.Println("daemon stopped")
+					return nil
+				},
+			},
+			{
+				Name:  "status",
+				Usage: "Show whether the daemon is running and what it holds",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Value: "text",
+						Usage: "Output format: text (default), json",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					client := daemon.NewClient(config.DaemonPath(config.FindRepoRoot()))
+					status, err := client.Status()
+					if errors.Is(err, daemon.ErrNotRunning) {
+						fmt.Println("daemon not running")
+						return cli.Exit("", 1)
+					}
+					if err != nil {
+						return cli.Exit("error: "+err.Error(), 1)
+					}
+					if err = printDaemonStatus(status, ctx.String("format")); err != nil {
+						return cli.Exit("error: "+err.Error(), 1)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// startDaemon runs `rpm daemon run` detached from the terminal, logging to
+// .rpm/daemon.log.
+func startDaemon(repoRoot string) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find rpm executable: %w", err)
+	}
+
+	logPath := filepath.Join(repoRoot, ".rpm", "daemon.log")
+	if err = os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return 0, err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open daemon log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, "daemon", "run")
+	cmd.Dir = repoRoot
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err = cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start daemon: %w", err)
+	}
+	pid := cmd.Process.Pid
+	return pid, cmd.Process.Release()
+}
+
+func printDaemonStatus(status *daemon.Status, format string) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "text":
+		fmt.Printf("pid:      %d\n", status.PID)
+		fmt.Printf("uptime:   %s\n", time.Since(status.Started).Round(time.Second))
+		fmt.Printf("loaded:   %s ago\n", time.Since(status.Loaded).Round(time.Second))
+		if status.Error != "" {
+			fmt.Printf("error:    %s\n", status.Error)
+			return nil
+		}
+		fmt.Printf("bundles:  %d\n", status.Bundles)
+		fmt.Printf("targets:  %d\n", status.Targets)
+		fmt.Printf("hashes:   %d files\n", status.Files)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+	return nil
+}
+
+// loadConfig returns the repository's config from the rpm daemon when one
+// runs for it, hashing inputs through the daemon, and loads it in-process
+// otherwise.
+func loadConfig() *config.Config {
+	repoRoot := config.FindRepoRoot()
+	client := daemon.NewClient(config.DaemonPath(repoRoot))
+	state, err := client.Load()
+	if err != nil {
+		return config.Load(repoRoot)
+	}
+	return config.NewConfigFromState(state.RepoRoot, state.Repo, state.Bundles, client)
+}
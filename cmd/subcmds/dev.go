@@ -1,14 +1,18 @@
 package subcmds
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/vcnkl/rpm/actions"
-	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/control"
 	"github.com/vcnkl/rpm/dag"
 	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/stores/builds"
+	"github.com/vcnkl/rpm/tui"
 
 	"github.com/urfave/cli/v2"
 )
@@ -27,7 +31,15 @@ func DevCmd() *cli.Command {
 				Name:  "dry-run",
 				Usage: "Print what would be executed without running",
 			},
+			&cli.BoolFlag{
+				Name:  "tui",
+				Usage: "Show a full-screen view with a pane per target (falls back to plain output when stdout is not a terminal)",
+			},
+			outputFlag(),
+			rawFlag(),
+			eventsFlag(),
 		},
+		Subcommands: []*cli.Command{devCtlCmd()},
 		Action: func(ctx *cli.Context) error {
 			debug := ctx.Bool("debug")
 			dryRun := ctx.Bool("dry-run")
@@ -36,9 +48,22 @@ func DevCmd() *cli.Command {
 			if debug {
 				level = logger.DebugLevel
 			}
-			log := logger.New(level)
+			log := logger.NewWithOutput(level, logOutput(ctx))
+
+			var ui *tui.UI
+			if ctx.Bool("tui") && !dryRun {
+				switch {
+				case ctx.Bool("raw") || ctx.String("output") == string(logger.OutputRaw):
+					return cli.Exit("error: --tui cannot be combined with raw output", 1)
+				case ctx.String("events") == "-" || !tui.Supported(os.Stdin, os.Stdout):
+					log.Warn("stdout is not a terminal, using plain output")
+				default:
+					ui = tui.New(os.Stdin, os.Stdout)
+					log = logger.NewWithWriter(level, ui)
+				}
+			}
 
-			cfg := config.NewConfig()
+			cfg := loadConfig()
 
 			graph := dag.NewGraph()
 			for _, bundle := range cfg.Bundles() {
@@ -68,16 +93,34 @@ func DevCmd() *cli.Command {
 				return nil
 			}
 
+			output, err := newOutput(ctx, cfg, log)
+			if err != nil {
+				return cli.Exit("error: "+err.Error(), 1)
+			}
+			defer output.Close()
+
+			store := builds.NewStore(cfg.BuildsPath())
+			if err = store.Load(); err != nil {
+				log.Warn("failed to load cache", logger.Err(err))
+			}
+
+			action := actions.NewDevAction(cfg, graph, store, log, output, actions.DevOptions{
+				Parallel: ctx.Int("jobs"),
+				NoDeps:   ctx.Bool("no-deps"),
+			})
+
 			if dryRun {
-				action := actions.NewDevAction(cfg, graph, log)
 				action.DryRun(targetIDs)
 				return nil
 			}
 
+			runCtx, finish := startRun(ctx, cfg, log)
+			defer finish()
+
 			sigCh := make(chan os.Signal, 1)
 			signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-			devCtx, cancel := signal.NotifyContext(ctx.Context, syscall.SIGINT, syscall.SIGTERM)
+			devCtx, cancel := signal.NotifyContext(runCtx, syscall.SIGINT, syscall.SIGTERM)
 			defer cancel()
 
 			go func() {
@@ -86,15 +129,105 @@ func DevCmd() *cli.Command {
 				cancel()
 			}()
 
-			action := actions.NewDevAction(cfg, graph, log)
+			session := devSession{action: action, output: output}
+			serverDone := make(chan struct{})
+			server, err := control.Listen(cfg.ControlPath(), session)
+			if err != nil {
+				log.Warn("control socket disabled", logger.Err(err))
+				close(serverDone)
+			} else {
+				go func() {
+					defer close(serverDone)
+					server.Serve(devCtx)
+				}()
+			}
+
+			uiDone := make(chan struct{})
+			if ui != nil {
+				go func() {
+					defer close(uiDone)
+					if err := ui.Run(devCtx, session, cancel); err != nil {
+						log.Error("failed to start terminal UI", logger.Err(err))
+					}
+				}()
+			} else {
+				close(uiDone)
+			}
+
+			started := time.Now()
+			output.Events().RunStarted(ctx.Command.Name, ctx.Args().Slice(), targetIDs)
 			result, err := action.Execute(devCtx, targetIDs)
+			cancel()
+			<-uiDone
+			<-serverDone
+			if ui != nil {
+				log = logger.NewWithOutput(level, logOutput(ctx))
+			}
 			if err != nil {
+				output.Events().RunError(err, time.Since(started))
 				return cli.Exit("error: "+err.Error(), 1)
 			}
+			output.Events().RunFinished(result)
 
 			log.Info("dev stopped", logger.Duration("duration", result.Duration))
 
+			for _, f := range result.Failed {
+				log.Error("dev target failed", logger.String("target", f.ID), logger.Err(f.Error))
+			}
+			if len(result.Failed) > 0 {
+				return cli.Exit("dev failed", 1)
+			}
+
 			return nil
 		},
 	}
 }
+
+// devSession exposes a running dev session to the terminal UI and the
+// control socket.
+type devSession struct {
+	action *actions.DevAction
+	output *actions.Output
+}
+
+func (s devSession) Statuses() []tui.Status {
+	states := s.action.Services()
+	statuses := make([]tui.Status, len(states))
+	for i, st := range states {
+		statuses[i] = tui.Status{ID: st.ID, Status: string(st.Status), PID: st.PID, Since: st.Since}
+	}
+	return statuses
+}
+
+func (s devSession) Services() []control.Service {
+	states := s.action.Services()
+	services := make([]control.Service, len(states))
+	for i, st := range states {
+		services[i] = control.Service{ID: st.ID, Status: string(st.Status), PID: st.PID, Since: st.Since}
+	}
+	return services
+}
+
+func (s devSession) Restart(targetID string) error {
+	return s.action.Restart(targetID)
+}
+
+func (s devSession) Stop(targetID string) error {
+	return s.action.Stop(targetID)
+}
+
+func (s devSession) Rebuild(targetID string) error {
+	return s.action.Rebuild(targetID)
+}
+
+func (s devSession) LogPath(targetID string) (string, error) {
+	for _, st := range s.action.Services() {
+		if st.ID == targetID {
+			if path := s.output.LogPath(targetID); path != "" {
+				return path, nil
+			}
+			return "", fmt.Errorf("no log file for %s", targetID)
+		}
+	}
+	return "", fmt.Errorf("%s is not running in this session", targetID)
+}
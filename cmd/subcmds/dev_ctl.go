@@ -0,0 +1,132 @@
+package subcmds
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/control"
+
+	"github.com/urfave/cli/v2"
+)
+
+func devCtlCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "ctl",
+		Usage: "Control the dev session running in this repository",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "List the session's targets and their state",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Value: "text",
+						Usage: "Output format: text (default), json",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					services, err := ctlClient().Services()
+					if err != nil {
+						return cli.Exit("error: "+err.Error(), 1)
+					}
+					if err = printServices(services, ctx.String("format")); err != nil {
+						return cli.Exit("error: "+err.Error(), 1)
+					}
+					return nil
+				},
+			},
+			ctlTargetCmd("restart", "Restart targets", (*control.Client).Restart),
+			ctlTargetCmd("stop", "Stop targets until they are restarted", (*control.Client).Stop),
+			ctlTargetCmd("rebuild", "Rebuild the build dependencies of targets, bypassing the cache, and restart them", (*control.Client).Rebuild),
+			{
+				Name:      "logs",
+				Usage:     "Print a target's log",
+				ArgsUsage: "<target>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "follow",
+						Aliases: []string{"f"},
+						Usage:   "Keep printing new lines",
+					},
+					&cli.IntFlag{
+						Name:    "lines",
+						Aliases: []string{"n"},
+						Value:   100,
+						Usage:   "Number of lines to print from the end of the log (0 for all)",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					if ctx.Args().Len() != 1 {
+						return cli.Exit("error: expected a single target", 1)
+					}
+
+					sigCtx, stop := signal.NotifyContext(ctx.Context, syscall.SIGINT, syscall.SIGTERM)
+					defer stop()
+
+					err := ctlClient().Logs(sigCtx, ctx.Args().First(), ctx.Int("lines"), ctx.Bool("follow"), func(line string) {
+						fmt.Println(line)
+					})
+					if err != nil {
+						return cli.Exit("error: "+err.Error(), 1)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func ctlTargetCmd(name, usage string, fn func(*control.Client, string) error) *cli.Command {
+	return &cli.Command{
+		Name:      name,
+		Usage:     usage,
+		ArgsUsage: "<targets...>",
+		Action: func(ctx *cli.Context) error {
+			if ctx.Args().Len() == 0 {
+				return cli.Exit("error: no targets given", 1)
+			}
+			client := ctlClient()
+			for _, id := range ctx.Args().Slice() {
+				if err := fn(client, id); err != nil {
+					return cli.Exit("error: "+err.Error(), 1)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func ctlClient() *control.Client {
+	return control.NewClient(config.NewConfig().ControlPath())
+}
+
+func printServices(services []control.Service, format string) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(services, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "text":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TARGET\tSTATUS\tPID\tSINCE")
+		for _, s := range services {
+			pid := "-"
+			if s.PID != 0 {
+				pid = fmt.Sprint(s.PID)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s ago\n", s.ID, s.Status, pid, time.Since(s.Since).Round(time.Second))
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+	return nil
+}
@@ -40,7 +40,7 @@ func GraphCmd() *cli.Command {
 			log := logger.New(level)
 			_ = log
 
-			cfg := config.NewConfig()
+			cfg := loadConfig()
 
 			graph := dag.NewGraph()
 			for _, bundle := range cfg.Bundles() {
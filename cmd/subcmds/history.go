@@ -0,0 +1,267 @@
+package subcmds
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vcnkl/rpm/actions"
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/git"
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/models"
+	"github.com/vcnkl/rpm/stores/history"
+
+	"github.com/urfave/cli/v2"
+)
+
+const keepHistoryRuns = 1000
+
+func HistoryCmd() *cli.Command {
+	lastFlag := &cli.IntFlag{
+		Name:    "last",
+		Aliases: []string{"n"},
+		Value:   20,
+		Usage:   "Number of most recent runs to consider",
+	}
+	formatFlag := &cli.StringFlag{
+		Name:  "format",
+		Value: "text",
+		Usage: "Output format: text (default), json",
+	}
+
+	return &cli.Command{
+		Name:  "history",
+		Usage: "Show recorded runs of build, test and run",
+		Flags: []cli.Flag{lastFlag, formatFlag},
+		Action: func(ctx *cli.Context) error {
+			runs, err := loadHistory()
+			if err != nil {
+				return cli.Exit("error: "+err.Error(), 1)
+			}
+			return printRuns(history.Last(runs, ctx.Int("last")), ctx.String("format"))
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:      "show",
+				Usage:     "Show a single run in detail",
+				ArgsUsage: "<run-id|last>",
+				Flags:     []cli.Flag{formatFlag},
+				Action: func(ctx *cli.Context) error {
+					id := "last"
+					if ctx.Args().Len() > 0 {
+						id = ctx.Args().First()
+					}
+
+					runs, err := loadHistory()
+					if err != nil {
+						return cli.Exit("error: "+err.Error(), 1)
+					}
+
+					run, ok := history.Find(runs, id)
+					if !ok {
+						return cli.Exit("error: run not found: "+id, 1)
+					}
+					return printRun(run, ctx.String("format"))
+				},
+			},
+			{
+				Name:      "trends",
+				Usage:     "Show per-target duration, cache hit rate and failure rate",
+				ArgsUsage: "[targets...]",
+				Flags:     []cli.Flag{lastFlag, formatFlag},
+				Action: func(ctx *cli.Context) error {
+					runs, err := loadHistory()
+					if err != nil {
+						return cli.Exit("error: "+err.Error(), 1)
+					}
+
+					trends := history.Trends(history.Last(runs, ctx.Int("last")))
+					if ctx.Args().Len() > 0 {
+						trends = filterTrends(trends, ctx.Args().Slice())
+					}
+					return printTrends(trends, ctx.String("format"))
+				},
+			},
+		},
+	}
+}
+
+func loadHistory() ([]*history.Run, error) {
+	cfg := loadConfig()
+	return history.NewStore(cfg.HistoryPath()).Load()
+}
+
+// recordHistory appends the outcome of a build, test or run invocation to
+// .rpm/history.jsonl.
+func recordHistory(ctx *cli.Context, cfg *config.Config, log logger.Logger, output *actions.Output, started time.Time, selected []string, result *models.Result) {
+	run := &history.Run{
+		ID:         output.RunID(),
+		Command:    ctx.Command.Name,
+		Args:       ctx.Args().Slice(),
+		StartedAt:  started,
+		DurationMs: result.Duration.Milliseconds(),
+		Selected:   selected,
+		Targets:    make([]history.TargetRun, 0, len(result.Executed)+len(result.Skipped)+len(result.Failed)),
+	}
+
+	if commit, err := git.HeadCommit(cfg.RepoRoot()); err == nil {
+		run.Commit = commit
+	}
+
+	for _, id := range result.Executed {
+		run.Targets = append(run.Targets, history.TargetRun{
+			ID:         id,
+			Status:     history.StatusSuccess,
+			DurationMs: result.Durations[id].Milliseconds(),
+		})
+	}
+	for _, id := range result.Skipped {
+		run.Targets = append(run.Targets, history.TargetRun{
+			ID:         id,
+			Status:     history.StatusCached,
+			DurationMs: result.Durations[id].Milliseconds(),
+		})
+	}
+	for _, f := range result.Failed {
+		t := history.TargetRun{
+			ID:         f.ID,
+			Status:     history.StatusFailed,
+			DurationMs: result.Durations[f.ID].Milliseconds(),
+			ExitCode:   f.ExitCode,
+		}
+		if f.Error != nil {
+			t.Error = log.Redactor().Redact(f.Error.Error())
+		}
+		run.Targets = append(run.Targets, t)
+	}
+	sort.Slice(run.Targets, func(i, j int) bool {
+		return run.Targets[i].ID < run.Targets[j].ID
+	})
+
+	store := history.NewStore(cfg.HistoryPath())
+	if err := store.Append(run); err != nil {
+		log.Warn("failed to record run history", logger.Err(err))
+		return
+	}
+	if err := store.Prune(keepHistoryRuns); err != nil {
+		log.Warn("failed to prune run history", logger.Err(err))
+	}
+}
+
+func printRuns(runs []*history.Run, format string) error {
+	if format == "json" {
+		return printHistoryJSON(runs)
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("No runs recorded")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN\tSTARTED\tCOMMAND\tCOMMIT\tOK\tCACHED\tFAILED\tDURATION")
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			run.ID,
+			run.StartedAt.Local().Format(time.DateTime),
+			commandLine(run),
+			shortCommit(run.Commit),
+			run.Count(history.StatusSuccess),
+			run.Count(history.StatusCached),
+			run.Count(history.StatusFailed),
+			formatMs(run.DurationMs))
+	}
+	return w.Flush()
+}
+
+func printRun(run *history.Run, format string) error {
+	if format == "json" {
+		return printHistoryJSON(run)
+	}
+
+	fmt.Printf("Run:      %s\n", run.ID)
+	fmt.Printf("Command:  %s\n", commandLine(run))
+	fmt.Printf("Started:  %s\n", run.StartedAt.Local().Format(time.DateTime))
+	fmt.Printf("Duration: %s\n", formatMs(run.DurationMs))
+	if run.Commit != "" {
+		fmt.Printf("Commit:   %s\n", run.Commit)
+	}
+	if len(run.Selected) > 0 {
+		fmt.Printf("Selected: %s\n", strings.Join(run.Selected, ", "))
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tSTATUS\tDURATION\tERROR")
+	for _, t := range run.Targets {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.ID, t.Status, formatMs(t.DurationMs), t.Error)
+	}
+	return w.Flush()
+}
+
+func printTrends(trends []history.Trend, format string) error {
+	if format == "json" {
+		return printHistoryJSON(trends)
+	}
+
+	if len(trends) == 0 {
+		fmt.Println("No runs recorded")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tRUNS\tAVG DURATION\tCACHE HIT\tFAILURE\tLAST")
+	for _, t := range trends {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%.0f%%\t%.0f%%\t%s\n",
+			t.Target,
+			t.Runs,
+			formatMs(t.AvgDurationMs),
+			t.CacheHitRate*100,
+			t.FailureRate*100,
+			t.LastStatus)
+	}
+	return w.Flush()
+}
+
+func filterTrends(trends []history.Trend, targets []string) []history.Trend {
+	var filtered []history.Trend
+	for _, t := range trends {
+		for _, ref := range targets {
+			if t.Target == ref || strings.HasPrefix(t.Target, ref+":") {
+				filtered = append(filtered, t)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+func printHistoryJSON(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func commandLine(run *history.Run) string {
+	return strings.TrimSpace("rpm " + run.Command + " " + strings.Join(run.Args, " "))
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+func formatMs(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
@@ -19,6 +19,8 @@ func InitCmd() *cli.Command {
 				Aliases: []string{"f"},
 				Usage:   "Re-run all install commands even if check passes",
 			},
+			outputFlag(),
+			rawFlag(),
 		},
 		Action: func(ctx *cli.Context) error {
 			debug := ctx.Bool("debug")
@@ -43,7 +45,12 @@ func InitCmd() *cli.Command {
 				return cli.Exit("error: "+err.Error(), 1)
 			}
 
-			action := actions.NewInitAction(cfg, graph, log, force)
+			output, err := newOutput(ctx, cfg, log)
+			if err != nil {
+				return cli.Exit("error: "+err.Error(), 1)
+			}
+
+			action := actions.NewInitAction(cfg, graph, log, output, force)
 			result, err := action.Execute(ctx.Context)
 			if err != nil {
 				return cli.Exit("error: "+err.Error(), 1)
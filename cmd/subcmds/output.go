@@ -0,0 +1,88 @@
+package subcmds
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/vcnkl/rpm/actions"
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/events"
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/profile"
+	"github.com/vcnkl/rpm/stores/logs"
+
+	"github.com/urfave/cli/v2"
+)
+
+const keepLogRuns = 20
+
+func outputFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "output",
+		Value: string(logger.OutputStream),
+		Usage: "Target output mode: stream (default), grouped (print each target's output when it finishes) or raw",
+	}
+}
+
+func rawFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "raw",
+		Usage: "Pass command output through unmodified with a short target prefix (same as --output=raw)",
+	}
+}
+
+func eventsFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "events",
+		Usage: "Write newline-delimited JSON events to `FILE` (\"-\" for stdout, logs then go to stderr)",
+	}
+}
+
+func profileFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "profile",
+		Usage: "Write a Chrome trace-event profile of the run to `FILE` (open in chrome://tracing or Perfetto)",
+	}
+}
+
+// logOutput keeps stdout free for the event stream when --events=- is given.
+func logOutput(ctx *cli.Context) *os.File {
+	if ctx.String("events") == "-" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+func newOutput(ctx *cli.Context, cfg *config.Config, log logger.Logger) (*actions.Output, error) {
+	mode, err := logger.ParseOutputMode(ctx.String("output"))
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Bool("raw") {
+		if ctx.IsSet("output") && mode != logger.OutputRaw {
+			return nil, fmt.Errorf("--raw cannot be combined with --output=%s", mode)
+		}
+		mode = logger.OutputRaw
+	}
+
+	store := logs.NewStore(cfg.LogsPath(), logs.NewRunID())
+	if err = store.Prune(keepLogRuns - 1); err != nil {
+		log.Warn("failed to prune old logs", logger.Err(err))
+	}
+	log.Debug("writing target logs", logger.String("dir", store.RunDir()))
+
+	var emitter *events.Emitter
+	if dest := ctx.String("events"); dest != "" {
+		if emitter, err = events.Open(dest, store.RunID()); err != nil {
+			return nil, err
+		}
+	}
+
+	var prof *profile.Profile
+	if ctx.String("profile") != "" {
+		prof = profile.New()
+	}
+
+	return actions.NewOutput(mode, store, emitter, prof), nil
+}
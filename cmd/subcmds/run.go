@@ -1,11 +1,14 @@
 package subcmds
 
 import (
+	"os"
+	"time"
+
 	"github.com/vcnkl/rpm/actions"
-	"github.com/vcnkl/rpm/config"
 	"github.com/vcnkl/rpm/dag"
 	"github.com/vcnkl/rpm/logger"
 
+	"github.com/mattn/go-isatty"
 	"github.com/urfave/cli/v2"
 )
 
@@ -14,6 +17,15 @@ func RunCmd() *cli.Command {
 		Name:      "run",
 		Usage:     "Run any arbitrary target by exact name",
 		ArgsUsage: "<target>",
+		Flags: []cli.Flag{
+			outputFlag(),
+			rawFlag(),
+			eventsFlag(),
+			&cli.BoolFlag{
+				Name:  "no-tty",
+				Usage: "Don't attach the terminal to the target command even when running interactively",
+			},
+		},
 		Action: func(ctx *cli.Context) error {
 			if ctx.Args().Len() == 0 {
 				return cli.Exit("error: target argument required", 1)
@@ -26,9 +38,9 @@ func RunCmd() *cli.Command {
 			if debug {
 				level = logger.DebugLevel
 			}
-			log := logger.New(level)
+			log := logger.NewWithOutput(level, logOutput(ctx))
 
-			cfg := config.NewConfig()
+			cfg := loadConfig()
 
 			graph := dag.NewGraph()
 			for _, bundle := range cfg.Bundles() {
@@ -41,8 +53,31 @@ func RunCmd() *cli.Command {
 				return cli.Exit("error: "+err.Error(), 1)
 			}
 
-			action := actions.NewRunAction(cfg, graph, log)
-			result, err := action.Execute(ctx.Context, targetID)
+			output, err := newOutput(ctx, cfg, log)
+			if err != nil {
+				return cli.Exit("error: "+err.Error(), 1)
+			}
+			defer output.Close()
+
+			interactive := !ctx.Bool("no-tty") &&
+				ctx.String("events") != "-" &&
+				isatty.IsTerminal(os.Stdin.Fd()) &&
+				isatty.IsTerminal(os.Stdout.Fd())
+
+			action := actions.NewRunAction(cfg, graph, log, output, interactive)
+			started := time.Now()
+			runCtx, finish := startRun(ctx, cfg, log)
+			defer finish()
+
+			output.Events().RunStarted(ctx.Command.Name, ctx.Args().Slice(), []string{targetID})
+			result, err := action.Execute(runCtx, targetID)
+			if result != nil {
+				output.Events().RunFinished(result)
+				recordResult(runCtx, result)
+				recordHistory(ctx, cfg, log, output, started, []string{targetID}, result)
+			} else if err != nil {
+				output.Events().RunError(err, time.Since(started))
+			}
 			if err != nil {
 				return cli.Exit("error: "+err.Error(), 1)
 			}
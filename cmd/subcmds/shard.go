@@ -0,0 +1,79 @@
+package subcmds
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vcnkl/rpm/dag"
+	"github.com/vcnkl/rpm/stores/builds"
+	"github.com/vcnkl/rpm/stores/history"
+)
+
+type ShardJSON struct {
+	Shard       string   `json:"shard"`
+	Index       int      `json:"index"`
+	Total       int      `json:"total"`
+	EstimatedMs int64    `json:"estimated_ms"`
+	Targets     []string `json:"targets"`
+}
+
+// shardDurations returns the durations used to balance shards: those in
+// path, the JSON output of `rpm history trends`, if given, and otherwise
+// those recorded for passing test targets in the builds store. Without any
+// every target weighs the same, so the plan depends on the target list alone.
+func shardDurations(path string, store *builds.Store, targetIDs []string) (map[string]time.Duration, error) {
+	if path == "" {
+		durations := make(map[string]time.Duration, len(targetIDs))
+		for _, id := range targetIDs {
+			if entry, ok := store.Get(id); ok && entry.DurationMs > 0 {
+				durations[id] = time.Duration(entry.DurationMs) * time.Millisecond
+			}
+		}
+		return durations, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shard durations: %w", err)
+	}
+	var trends []history.Trend
+	if err = json.Unmarshal(data, &trends); err != nil {
+		return nil, fmt.Errorf("failed to parse shard durations %s: %w", path, err)
+	}
+
+	durations := make(map[string]time.Duration, len(trends))
+	for _, t := range trends {
+		durations[t.Target] = time.Duration(t.AvgDurationMs) * time.Millisecond
+	}
+	return durations, nil
+}
+
+func printShards(shards []dag.Shard, format string) error {
+	switch format {
+	case "json":
+		output := make([]ShardJSON, 0, len(shards))
+		for _, s := range shards {
+			output = append(output, ShardJSON{
+				Shard:       fmt.Sprintf("%d/%d", s.Index, len(shards)),
+				Index:       s.Index,
+				Total:       len(shards),
+				EstimatedMs: s.Estimated.Milliseconds(),
+				Targets:     s.Targets,
+			})
+		}
+		data, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "text":
+		for _, s := range shards {
+			fmt.Printf("%d/%d  %s  %s\n", s.Index, len(shards), s.Estimated.Round(time.Millisecond), strings.Join(s.Targets, " "))
+		}
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+	return nil
+}
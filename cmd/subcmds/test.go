@@ -1,13 +1,15 @@
 package subcmds
 
 import (
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/vcnkl/rpm/actions"
-	"github.com/vcnkl/rpm/config"
 	"github.com/vcnkl/rpm/dag"
 	"github.com/vcnkl/rpm/git"
 	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/stores/builds"
 
 	"github.com/urfave/cli/v2"
 )
@@ -24,8 +26,45 @@ func TestCmd() *cli.Command {
 			},
 			&cli.BoolFlag{
 				Name:  "coverage",
-				Usage: "Pass coverage flags (target must handle)",
+				Usage: "Set RPM_COVERAGE and RPM_COVERAGE_DIR for each target and merge the coverage files it writes",
 			},
+			&cli.StringFlag{
+				Name:  "coverage-out",
+				Usage: "Write the merged LCOV coverage report to `FILE` (default .rpm/coverage/<run-id>/coverage.lcov)",
+			},
+			&cli.StringFlag{
+				Name:  "junit-out",
+				Usage: "Write a merged JUnit XML report of all test targets to `FILE`",
+			},
+			&cli.BoolFlag{
+				Name:    "force",
+				Aliases: []string{"f"},
+				Usage:   "Rerun targets with cached results and refresh the cache",
+			},
+			&cli.BoolFlag{
+				Name:  "no-cache",
+				Usage: "Neither reuse nor store cached test results",
+			},
+			&cli.StringFlag{
+				Name:  "shard",
+				Usage: "Only run shard `i/n` of the selected test targets (balanced by recorded durations)",
+			},
+			&cli.StringFlag{
+				Name:  "shard-durations",
+				Usage: "Balance shards by the durations in `FILE`, the JSON output of rpm history trends, instead of the builds store",
+			},
+			&cli.IntFlag{
+				Name:  "list-shards",
+				Usage: "Print how the selected test targets split into `N` shards and exit",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "text",
+				Usage: "Output format of --list-shards: text (default) or json",
+			},
+			outputFlag(),
+			rawFlag(),
+			eventsFlag(),
 		},
 		Action: func(ctx *cli.Context) error {
 			debug := ctx.Bool("debug")
@@ -36,9 +75,9 @@ func TestCmd() *cli.Command {
 			if debug {
 				level = logger.DebugLevel
 			}
-			log := logger.New(level)
+			log := logger.NewWithOutput(level, logOutput(ctx))
 
-			cfg := config.NewConfig()
+			cfg := loadConfig()
 
 			graph := dag.NewGraph()
 			for _, bundle := range cfg.Bundles() {
@@ -80,14 +119,104 @@ func TestCmd() *cli.Command {
 				return nil
 			}
 
-			action := actions.NewTestAction(cfg, graph, log, parallel)
-			result, err := action.Execute(ctx.Context, targetIDs)
+			store := builds.NewStore(cfg.BuildsPath())
+			if err := store.Load(); err != nil {
+				log.Warn("failed to load cache", logger.Err(err))
+			}
+
+			if n := ctx.Int("list-shards"); ctx.IsSet("list-shards") {
+				if n < 1 {
+					return cli.Exit("error: --list-shards must be at least 1", 1)
+				}
+				durations, err := shardDurations(ctx.String("shard-durations"), store, targetIDs)
+				if err != nil {
+					return cli.Exit("error: "+err.Error(), 1)
+				}
+				shards := dag.PlanShards(targetIDs, durations, n)
+				if err := printShards(shards, ctx.String("format")); err != nil {
+					return cli.Exit("error: "+err.Error(), 1)
+				}
+				return nil
+			}
+
+			if spec := ctx.String("shard"); spec != "" {
+				index, total, err := dag.ParseShard(spec)
+				if err != nil {
+					return cli.Exit("error: "+err.Error(), 1)
+				}
+				durations, err := shardDurations(ctx.String("shard-durations"), store, targetIDs)
+				if err != nil {
+					return cli.Exit("error: "+err.Error(), 1)
+				}
+				shard := dag.PlanShards(targetIDs, durations, total)[index-1]
+				log.Info("running shard",
+					logger.String("shard", spec),
+					logger.Int("targets", len(shard.Targets)),
+					logger.Duration("estimated", shard.Estimated))
+				targetIDs = shard.Targets
+				if len(targetIDs) == 0 {
+					log.Info("no test targets in shard")
+					return nil
+				}
+			}
+
+			output, err := newOutput(ctx, cfg, log)
+			if err != nil {
+				return cli.Exit("error: "+err.Error(), 1)
+			}
+			defer output.Close()
+
+			var coverageDir string
+			if ctx.Bool("coverage") || ctx.IsSet("coverage-out") {
+				coverageDir = coverageRunDir(cfg, log, output.RunID())
+			}
+
+			action := actions.NewTestAction(cfg, graph, store, log, output, actions.TestOptions{
+				Parallel:    parallel,
+				Force:       ctx.Bool("force"),
+				NoCache:     ctx.Bool("no-cache"),
+				CoverageDir: coverageDir,
+			})
+			started := time.Now()
+			runCtx, finish := startRun(ctx, cfg, log)
+			defer finish()
+
+			output.Events().RunStarted(ctx.Command.Name, ctx.Args().Slice(), targetIDs)
+			result, err := action.Execute(runCtx, targetIDs)
 			if err != nil {
+				output.Events().RunError(err, time.Since(started))
 				return cli.Exit("error: "+err.Error(), 1)
 			}
+			output.Events().RunFinished(result)
+			recordResult(runCtx, result)
+			recordHistory(ctx, cfg, log, output, started, targetIDs, result)
+
+			report := action.Report()
+			for _, f := range report.Failures() {
+				log.WithPrefix(f.Target).Error("test case failed",
+					logger.String("case", f.Case),
+					logger.String("reason", f.Message))
+			}
+
+			if path := ctx.String("junit-out"); path != "" {
+				if err = report.Suites().WriteFile(path); err != nil {
+					log.Error("failed to write junit report", logger.Err(err))
+				} else {
+					log.Info("junit report written", logger.String("path", path))
+				}
+			}
+
+			if coverageDir != "" {
+				path := ctx.String("coverage-out")
+				if path == "" {
+					path = filepath.Join(coverageDir, "coverage.lcov")
+				}
+				writeCoverage(cfg, log, action.Coverage(), path)
+			}
 
 			log.Info("tests completed",
 				logger.Int("passed", len(result.Executed)),
+				logger.Int("cached", len(result.Skipped)),
 				logger.Int("failed", len(result.Failed)),
 				logger.Duration("duration", result.Duration))
 
@@ -0,0 +1,53 @@
+package subcmds
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/models"
+	"github.com/vcnkl/rpm/tracing"
+
+	"github.com/urfave/cli/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const traceFlushTimeout = 5 * time.Second
+
+// startRun sets up trace export and starts the invocation's root span. The
+// returned function ends the span and flushes pending spans.
+func startRun(ctx *cli.Context, cfg *config.Config, log logger.Logger) (context.Context, func()) {
+	shutdown, err := tracing.Setup(ctx.Context, cfg.Repo().Tracing)
+	if err != nil {
+		log.Warn("failed to set up tracing", logger.Err(err))
+		return ctx.Context, func() {}
+	}
+
+	runCtx, span := tracing.StartRun(ctx.Context, ctx.Command.Name, ctx.Args().Slice())
+
+	return runCtx, func() {
+		span.End()
+
+		flushCtx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+		defer cancel()
+		if err := shutdown(flushCtx); err != nil {
+			log.Warn("failed to export traces", logger.Err(err))
+		}
+	}
+}
+
+func recordResult(ctx context.Context, result *models.Result) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		attribute.Int("rpm.executed", len(result.Executed)),
+		attribute.Int("rpm.skipped", len(result.Skipped)),
+		attribute.Int("rpm.failed", len(result.Failed)),
+	)
+	if len(result.Failed) > 0 {
+		span.SetStatus(codes.Error, fmt.Sprintf("%d targets failed", len(result.Failed)))
+	}
+}
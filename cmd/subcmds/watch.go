@@ -0,0 +1,111 @@
+package subcmds
+
+import (
+	"os/signal"
+	"syscall"
+
+	"github.com/vcnkl/rpm/actions"
+	"github.com/vcnkl/rpm/dag"
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/stores/builds"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+func WatchCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "watch",
+		Usage: "Rebuild or retest targets whenever their inputs change",
+		Subcommands: []*cli.Command{
+			watchModeCmd(actions.WatchBuild, "_build", "Build targets (or all *_build targets) and rebuild the affected ones on change"),
+			watchModeCmd(actions.WatchTest, "_test", "Run test targets (or all *_test targets) and rerun the affected ones on change"),
+		},
+	}
+}
+
+func watchModeCmd(mode actions.WatchMode, suffix, usage string) *cli.Command {
+	return &cli.Command{
+		Name:      string(mode),
+		Usage:     usage,
+		ArgsUsage: "[targets...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "no-clear",
+				Usage: "Don't clear the screen before each run",
+			},
+			outputFlag(),
+			rawFlag(),
+			eventsFlag(),
+		},
+		Action: func(ctx *cli.Context) error {
+			level := logger.InfoLevel
+			if ctx.Bool("debug") {
+				level = logger.DebugLevel
+			}
+			out := logOutput(ctx)
+			log := logger.NewWithOutput(level, out)
+
+			cfg := loadConfig()
+
+			graph := dag.NewGraph()
+			for _, bundle := range cfg.Bundles() {
+				for _, target := range bundle.Targets {
+					graph.AddTarget(target)
+				}
+			}
+
+			if err := graph.Resolve(cfg.Bundles()); err != nil {
+				return cli.Exit("error: "+err.Error(), 1)
+			}
+
+			var targetIDs []string
+
+			selector := dag.NewSelector(graph, cfg.RepoRoot())
+			if ctx.Args().Len() > 0 {
+				targetIDs = selector.ResolveTargetRefs(ctx.Args().Slice(), suffix)
+				for _, id := range targetIDs {
+					if _, ok := graph.Nodes[id]; !ok {
+						return cli.Exit("error: target not found: "+id, 1)
+					}
+				}
+			} else {
+				for _, t := range selector.SelectBySuffix(suffix) {
+					targetIDs = append(targetIDs, t.ID)
+				}
+			}
+
+			if len(targetIDs) == 0 {
+				log.Info("no targets to watch")
+				return nil
+			}
+
+			store := builds.NewStore(cfg.BuildsPath())
+			if err := store.Load(); err != nil {
+				log.Warn("failed to load cache", logger.Err(err))
+			}
+
+			output, err := newOutput(ctx, cfg, log)
+			if err != nil {
+				return cli.Exit("error: "+err.Error(), 1)
+			}
+			defer output.Close()
+
+			opts := actions.WatchOptions{Mode: mode, Parallel: ctx.Int("jobs")}
+			if !ctx.Bool("no-clear") && term.IsTerminal(int(out.Fd())) {
+				opts.Clear = func() {
+					out.WriteString("\x1b[H\x1b[2J")
+				}
+			}
+
+			watchCtx, cancel := signal.NotifyContext(ctx.Context, syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			action := actions.NewWatchAction(cfg, graph, store, log, output, opts)
+			if err = action.Execute(watchCtx, targetIDs); err != nil {
+				return cli.Exit("error: "+err.Error(), 1)
+			}
+			return nil
+		},
+	}
+}
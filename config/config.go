@@ -6,32 +6,53 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/vcnkl/rpm/cache/hashing"
 	"github.com/vcnkl/rpm/models"
 )
 
 type Config struct {
-	repoRoot   string
-	rpmDir     string
-	buildsPath string
-	dagPath    string
-	repo       *RepoConfig
-	bundles    map[string]*models.Bundle
+	repoRoot     string
+	rpmDir       string
+	buildsPath   string
+	dagPath      string
+	logsPath     string
+	historyPath  string
+	coveragePath string
+	resultsPath  string
+	controlPath  string
+	repo         *RepoConfig
+	bundles      map[string]*models.Bundle
+	hasher       hashing.InputHasher
 }
 
 func NewConfig() *Config {
-	repoRoot := findRepoRoot()
+	return Load(FindRepoRoot())
+}
+
+// Load reads repo.yml and every bundle of the repository at repoRoot.
+func Load(repoRoot string) *Config {
 	repo := loadRepoConfig(filepath.Join(repoRoot, "repo.yml"))
 	bundles := discoverBundles(repoRoot, repo.Ignore)
+	return NewConfigFromState(repoRoot, repo, bundles, nil)
+}
 
+// NewConfigFromState builds a config from an already loaded repo config and
+// bundles, such as those the rpm daemon holds. Inputs are hashed with
+// hasher, or in-process if it is nil.
+func NewConfigFromState(repoRoot string, repo *RepoConfig, bundles []*models.Bundle, hasher hashing.InputHasher) *Config {
 	bundleMap := make(map[string]*models.Bundle, len(bundles))
 	for _, b := range bundles {
 		bundleMap[b.Name] = b
 	}
+	if hasher == nil {
+		hasher = hashing.Local
+	}
 
 	cfg := &Config{
 		repoRoot: repoRoot,
 		repo:     repo,
 		bundles:  bundleMap,
+		hasher:   hasher,
 	}
 
 	cfg.initPaths()
@@ -39,10 +60,21 @@ func NewConfig() *Config {
 	return cfg
 }
 
+// DaemonPath returns the socket of the rpm daemon for the repository at
+// repoRoot.
+func DaemonPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".rpm", "daemon.sock")
+}
+
 func (c *Config) initPaths() {
 	c.rpmDir = c.initRpmDir()
 	c.buildsPath = c.initBuildsPath()
 	c.dagPath = c.initDagPath()
+	c.logsPath = filepath.Join(c.rpmDir, "logs")
+	c.historyPath = filepath.Join(c.rpmDir, "history.jsonl")
+	c.coveragePath = filepath.Join(c.rpmDir, "coverage")
+	c.resultsPath = filepath.Join(c.rpmDir, "results")
+	c.controlPath = filepath.Join(c.rpmDir, "dev.sock")
 }
 
 func (c *Config) initRpmDir() string {
@@ -79,6 +111,36 @@ func (c *Config) DagPath() string {
 	return c.dagPath
 }
 
+func (c *Config) LogsPath() string {
+	return c.logsPath
+}
+
+func (c *Config) HistoryPath() string {
+	return c.historyPath
+}
+
+func (c *Config) CoveragePath() string {
+	return c.coveragePath
+}
+
+func (c *Config) ResultsPath() string {
+	return c.resultsPath
+}
+
+// ControlPath is the Unix socket a running dev session listens on.
+func (c *Config) ControlPath() string {
+	return c.controlPath
+}
+
+// Hasher hashes target inputs, through the rpm daemon when the config came
+// from one.
+func (c *Config) Hasher() hashing.InputHasher {
+	if c.hasher == nil {
+		return hashing.Local
+	}
+	return c.hasher
+}
+
 func (c *Config) Repo() *RepoConfig {
 	return c.repo
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -79,6 +80,9 @@ func TestRepoConfig_SetDefaults(t *testing.T) {
 			assert.Equal(t, tt.expected.Env, cfg.Env)
 			assert.Equal(t, tt.expected.Docker.Backend, cfg.Docker.Backend)
 			assert.Equal(t, tt.expected.Docker.URL, cfg.Docker.URL)
+			assert.Equal(t, 100*time.Millisecond, cfg.Watch.Debounce)
+			assert.Equal(t, time.Second, cfg.Watch.PollInterval)
+			assert.Contains(t, cfg.Watch.SkipDirs, "node_modules")
 		})
 	}
 }
@@ -133,6 +137,14 @@ func TestTargetConfig_SetDefaults(t *testing.T) {
 				assert.True(t, *cfg.Config.Dotenv.Enabled)
 				assert.NotNil(t, cfg.Config.Reload)
 				assert.True(t, *cfg.Config.Reload)
+				assert.NotNil(t, cfg.Config.Cache)
+				assert.False(t, *cfg.Config.Cache)
+				assert.Equal(t, "never", cfg.Restart)
+				assert.Equal(t, 5, *cfg.MaxRestarts)
+				assert.Equal(t, "TERM", cfg.StopSignal)
+				assert.Equal(t, 5*time.Second, cfg.StopTimeout)
+				assert.Equal(t, 500*time.Millisecond, cfg.Ready.Interval)
+				assert.Equal(t, 60*time.Second, cfg.Ready.Timeout)
 				assert.NotNil(t, cfg.Config.Ignore)
 			},
 		},
@@ -448,6 +460,7 @@ func TestConfig_Accessors(t *testing.T) {
 		repoRoot:   "/repo",
 		buildsPath: "/repo/.rpm/builds.json",
 		dagPath:    "/repo/.rpm/dag.json",
+		logsPath:   "/repo/.rpm/logs",
 		repo:       &RepoConfig{Shell: "/bin/bash"},
 		bundles: map[string]*models.Bundle{
 			"core": {Name: "core"},
@@ -457,6 +470,7 @@ func TestConfig_Accessors(t *testing.T) {
 	assert.Equal(t, "/repo", cfg.RepoRoot())
 	assert.Equal(t, "/repo/.rpm/builds.json", cfg.BuildsPath())
 	assert.Equal(t, "/repo/.rpm/dag.json", cfg.DagPath())
+	assert.Equal(t, "/repo/.rpm/logs", cfg.LogsPath())
 	assert.Equal(t, "/bin/bash", cfg.Repo().Shell)
 	assert.Len(t, cfg.Bundles(), 1)
 	assert.Equal(t, "core", cfg.Bundles()["core"].Name)
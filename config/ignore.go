@@ -0,0 +1,172 @@
+package config
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// matchGlob reports whether the slash separated name matches pattern, where
+// a "**" segment matches any number of segments and the others follow
+// path.Match.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return len(parts) > 0
+			}
+			for i := range parts {
+				if matchSegments(pattern, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// repoIgnored reports whether the repo relative file rel is excluded by one
+// of the repo.yml ignore patterns. Patterns are anchored at the repo root
+// and one that matches a directory excludes everything below it.
+func repoIgnored(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+		pattern = strings.Trim(pattern, "/")
+		if pattern == "" {
+			continue
+		}
+		for name := rel; name != "."; name = path.Dir(name) {
+			if matchGlob(pattern, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ignoreRule is a single pattern of a .gitignore file.
+type ignoreRule struct {
+	// base is the directory of the .gitignore file relative to the repo
+	// root, empty for the root.
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+func parseGitignore(base string, data []byte) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		var ok bool
+		if rel, ok = strings.CutPrefix(rel, r.base+"/"); !ok {
+			return false
+		}
+	}
+	if r.anchored {
+		return matchGlob(r.pattern, rel)
+	}
+	ok, _ := path.Match(r.pattern, path.Base(rel))
+	return ok
+}
+
+// gitIgnored reports whether rel is ignored by rules, the last matching rule
+// deciding as in git.
+func gitIgnored(rules []ignoreRule, rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range rules {
+		if r.matches(rel, isDir) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// walkFiles returns the files under repoRoot named name, relative to
+// repoRoot, skipping those excluded by .gitignore files. It is used outside
+// git, where ls-files is not available.
+func walkFiles(repoRoot string, name string) ([]string, error) {
+	var files []string
+
+	var walk func(dir, rel string, rules []ignoreRule) error
+	walk = func(dir, rel string, rules []ignoreRule) error {
+		if data, err := os.ReadFile(filepath.Join(dir, ".gitignore")); err == nil {
+			rules = append(rules[:len(rules):len(rules)], parseGitignore(rel, data)...)
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.Name() == ".git" {
+				continue
+			}
+			entryRel := path.Join(rel, entry.Name())
+			if gitIgnored(rules, entryRel, entry.IsDir()) {
+				continue
+			}
+			if entry.IsDir() {
+				if err = walk(filepath.Join(dir, entry.Name()), entryRel, rules); err != nil {
+					return err
+				}
+			} else if entry.Name() == name {
+				files = append(files, entryRel)
+			}
+		}
+		return nil
+	}
+
+	if err := walk(repoRoot, "", nil); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{name: "exact", pattern: "a/b", path: "a/b", expected: true},
+		{name: "star in segment", pattern: "a/*", path: "a/b", expected: true},
+		{name: "star does not cross segments", pattern: "a/*", path: "a/b/c", expected: false},
+		{name: "leading double star at root", pattern: "**/testdata", path: "testdata", expected: true},
+		{name: "leading double star nested", pattern: "**/testdata", path: "x/y/testdata", expected: true},
+		{name: "middle double star", pattern: "a/**/c", path: "a/b/b/c", expected: true},
+		{name: "middle double star matches none", pattern: "a/**/c", path: "a/c", expected: true},
+		{name: "trailing double star", pattern: "a/**", path: "a/b/c", expected: true},
+		{name: "trailing double star excludes dir", pattern: "a/**", path: "a", expected: false},
+		{name: "no match", pattern: "a/b", path: "a/c", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, matchGlob(tt.pattern, tt.path))
+		})
+	}
+}
+
+func TestRepoIgnored(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		expected bool
+	}{
+		{name: "no patterns", path: "a/rpm.yml", expected: false},
+		{name: "directory excludes contents", patterns: []string{"legacy"}, path: "legacy/api/rpm.yml", expected: true},
+		{name: "anchored at root", patterns: []string{"legacy"}, path: "x/legacy/rpm.yml", expected: false},
+		{name: "dot slash and trailing slash", patterns: []string{"./legacy/"}, path: "legacy/rpm.yml", expected: true},
+		{name: "star in bundle dir", patterns: []string{"path/to/ignored/bundle/*"}, path: "path/to/ignored/bundle/rpm.yml", expected: true},
+		{name: "double star anywhere", patterns: []string{"**/testdata"}, path: "pkg/a/testdata/b/rpm.yml", expected: true},
+		{name: "prefix is not a match", patterns: []string{"legacy"}, path: "legacy2/rpm.yml", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, repoIgnored(tt.patterns, tt.path))
+		})
+	}
+}
+
+func TestGitIgnored(t *testing.T) {
+	root := parseGitignore("", []byte("# comment\n\nbuild/\n*.log\n!keep.log\n/vendor\ndocs/**/gen\n"))
+	nested := parseGitignore("svc", []byte("tmp\n/out\n"))
+	rules := append(root, nested...)
+
+	tests := []struct {
+		name     string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{name: "dir only pattern matches dir", path: "a/build", isDir: true, expected: true},
+		{name: "dir only pattern skips file", path: "a/build", isDir: false, expected: false},
+		{name: "basename pattern", path: "a/b/x.log", expected: true},
+		{name: "negation", path: "a/keep.log", expected: false},
+		{name: "anchored pattern at root", path: "vendor", isDir: true, expected: true},
+		{name: "anchored pattern not nested", path: "a/vendor", isDir: true, expected: false},
+		{name: "double star", path: "docs/a/b/gen", isDir: true, expected: true},
+		{name: "nested file applies below its dir", path: "svc/a/tmp", isDir: true, expected: true},
+		{name: "nested file anchored to its dir", path: "svc/out", isDir: true, expected: true},
+		{name: "nested file does not apply elsewhere", path: "other/tmp", isDir: true, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, gitIgnored(rules, tt.path, tt.isDir))
+		})
+	}
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestWalkFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":    "dist/\n",
+		"rpm.yml":       "",
+		"a/rpm.yml":     "",
+		"a/.gitignore":  "gen\n",
+		"a/gen/rpm.yml": "",
+		"dist/rpm.yml":  "",
+		"b/c/rpm.yml":   "",
+		"b/c/other.yml": "",
+		"d/rpm.yaml":    "",
+	})
+
+	files, err := walkFiles(root, "rpm.yml")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"rpm.yml", "a/rpm.yml", "b/c/rpm.yml"}, files)
+}
+
+func TestBundleFiles_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":         "ignored/\n",
+		"a/rpm.yml":          "",
+		"untracked/rpm.yml":  "",
+		"ignored/rpm.yml":    "",
+		"legacy/old/rpm.yml": "",
+	})
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	git("add", ".gitignore", "a/rpm.yml", "legacy/old/rpm.yml")
+
+	files, err := bundleFiles(root, []string{"legacy"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/rpm.yml", "untracked/rpm.yml"}, files)
+
+	// deleted files are dropped even before the deletion is staged
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "a")))
+	files, err = bundleFiles(root, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"legacy/old/rpm.yml", "untracked/rpm.yml"}, files)
+
+	// .gitignore changes apply right away
+	writeFiles(t, root, map[string]string{".gitignore": "untracked/\n"})
+	files, err = bundleFiles(root, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ignored/rpm.yml", "legacy/old/rpm.yml"}, files)
+}
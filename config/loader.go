@@ -1,10 +1,13 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/vcnkl/rpm/git"
@@ -15,7 +18,9 @@ import (
 	"github.com/knadh/koanf/v2"
 )
 
-func findRepoRoot() string {
+// FindRepoRoot returns the root of the git repository containing the
+// working directory, or the working directory outside git.
+func FindRepoRoot() string {
 	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
 	output, err := cmd.Output()
 	if err != nil {
@@ -44,47 +49,43 @@ func loadRepoConfig(path string) *RepoConfig {
 }
 
 func discoverBundles(repoRoot string, ignore []string) []*models.Bundle {
-	var bundles []*models.Bundle
+	paths, err := bundleFiles(repoRoot, ignore)
+	if err != nil {
+		panic(fmt.Sprintf("failed to discover bundles: %v", err))
+	}
 
-	err := filepath.Walk(repoRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	bundles := make([]*models.Bundle, 0, len(paths))
+	for _, rel := range paths {
+		bundles = append(bundles, loadBundleConfig(filepath.Join(repoRoot, rel), repoRoot))
+	}
+	return bundles
+}
 
-		relPath, err := filepath.Rel(repoRoot, path)
-		if err != nil {
-			panic(err)
-		}
-		for _, pattern := range ignore {
-			if skip, _ := filepath.Match(pattern, relPath); skip {
-				return nil
-			}
-		}
+// bundleFiles returns the rpm.yml files of the repository relative to
+// repoRoot: those git tracks or would track, or outside git those not
+// excluded by .gitignore, minus the ones matching the repo ignore patterns.
+func bundleFiles(repoRoot string, ignore []string) ([]string, error) {
+	found, err := git.ListFiles(repoRoot, "rpm.yml")
+	if errors.Is(err, git.ErrNotRepository) {
+		found, err = walkFiles(repoRoot, "rpm.yml")
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		if info.IsDir() {
-			tracked, err := git.IsTracked(path)
-			if err != nil {
-				return err
-			}
-			if !tracked {
-				return filepath.SkipDir
-			}
-			return nil
+	var files []string
+	for _, rel := range found {
+		if path.Base(rel) != "rpm.yml" || repoIgnored(ignore, rel) {
+			continue
 		}
-
-		if info.Name() == "rpm.yml" {
-			bundle := loadBundleConfig(path, repoRoot)
-			bundles = append(bundles, bundle)
+		// deleted files stay in the index until the deletion is staged
+		if _, err = os.Stat(filepath.Join(repoRoot, rel)); err != nil {
+			continue
 		}
-
-		return nil
-	})
-
-	if err != nil {
-		panic(fmt.Sprintf("failed to discover bundles: %v", err))
+		files = append(files, rel)
 	}
-
-	return bundles
+	sort.Strings(files)
+	return files, nil
 }
 
 func loadBundleConfig(path string, repoRoot string) *models.Bundle {
@@ -115,14 +116,30 @@ func loadBundleConfig(path string, repoRoot string) *models.Bundle {
 
 	for _, tc := range cfg.Targets {
 		target := &models.Target{
-			Name:       tc.Name,
-			BundleName: cfg.Name,
-			BundlePath: relPath,
-			In:         tc.In,
-			Out:        tc.Out,
-			Deps:       tc.Deps,
-			Env:        tc.Env,
-			Cmd:        tc.GetCmd(),
+			Name:        tc.Name,
+			BundleName:  cfg.Name,
+			BundlePath:  relPath,
+			In:          tc.In,
+			Out:         tc.Out,
+			Deps:        tc.Deps,
+			Env:         tc.Env,
+			Secrets:     tc.Secrets,
+			Reports:     tc.Reports,
+			Coverage:    tc.Coverage,
+			Restart:     models.RestartPolicy(tc.Restart),
+			MaxRestarts: *tc.MaxRestarts,
+			Critical:    tc.Critical,
+			StopSignal:  tc.StopSignal,
+			StopTimeout: tc.StopTimeout,
+			Ready: models.ReadyConfig{
+				TCP:      tc.Ready.TCP,
+				HTTP:     tc.Ready.HTTP,
+				Log:      tc.Ready.Log,
+				Cmd:      tc.Ready.Cmd,
+				Interval: tc.Ready.Interval,
+				Timeout:  tc.Ready.Timeout,
+			},
+			Cmd: tc.GetCmd(),
 			Config: models.TargetConfig{
 				WorkingDir: tc.Config.WorkingDir,
 				Dotenv: models.DotenvConfig{
@@ -130,9 +147,18 @@ func loadBundleConfig(path string, repoRoot string) *models.Bundle {
 					Files:   tc.Config.Dotenv.Files,
 				},
 				Reload: *tc.Config.Reload,
+				Cache:  *tc.Config.Cache,
 				Ignore: tc.Config.Ignore,
 			},
 		}
+		for _, rule := range tc.OnChange {
+			target.OnChange = append(target.OnChange, models.OnChangeRule{
+				Paths:  rule.Paths,
+				Action: models.ChangeAction(rule.Action),
+				Signal: rule.Signal,
+				Exec:   rule.Exec,
+			})
+		}
 		bundle.Targets = append(bundle.Targets, target)
 	}
 
@@ -1,11 +1,22 @@
 package config
 
+import "time"
+
 type RepoConfig struct {
-	Shell  string            `koanf:"shell"`
-	Env    map[string]string `koanf:"env"`
-	Docker DockerConfig      `koanf:"docker"`
-	Deps   []Dependency      `koanf:"deps"`
-	Ignore []string          `koanf:"ignore"`
+	Shell   string                  `koanf:"shell"`
+	Env     map[string]string       `koanf:"env"`
+	Docker  DockerConfig            `koanf:"docker"`
+	Deps    []Dependency            `koanf:"deps"`
+	Ignore  []string                `koanf:"ignore"`
+	Secrets map[string]SecretConfig `koanf:"secrets"`
+	Tracing TracingConfig           `koanf:"tracing"`
+	Watch   WatchConfig             `koanf:"watch"`
+}
+
+type SecretConfig struct {
+	Cmd  string `koanf:"cmd"`
+	File string `koanf:"file"`
+	Env  string `koanf:"env"`
 }
 
 type DockerConfig struct {
@@ -13,6 +24,19 @@ type DockerConfig struct {
 	URL     string `koanf:"url"`
 }
 
+type TracingConfig struct {
+	Endpoint    string            `koanf:"endpoint"`
+	Headers     map[string]string `koanf:"headers"`
+	ServiceName string            `koanf:"service_name"`
+}
+
+type WatchConfig struct {
+	Debounce     time.Duration `koanf:"debounce"`
+	Poll         bool          `koanf:"poll"`
+	PollInterval time.Duration `koanf:"poll_interval"`
+	SkipDirs     []string      `koanf:"skip_dirs"`
+}
+
 type Dependency struct {
 	Label      string `koanf:"label"`
 	CheckCmd   string `koanf:"check_cmd"`
@@ -32,4 +56,19 @@ func (r *RepoConfig) SetDefaults() {
 	if r.Ignore == nil {
 		r.Ignore = make([]string, 0)
 	}
+	if r.Secrets == nil {
+		r.Secrets = make(map[string]SecretConfig)
+	}
+	if r.Tracing.ServiceName == "" {
+		r.Tracing.ServiceName = "rpm"
+	}
+	if r.Watch.Debounce <= 0 {
+		r.Watch.Debounce = 100 * time.Millisecond
+	}
+	if r.Watch.PollInterval <= 0 {
+		r.Watch.PollInterval = time.Second
+	}
+	if r.Watch.SkipDirs == nil {
+		r.Watch.SkipDirs = []string{".git", ".rpm", "node_modules", ".venv", "__pycache__"}
+	}
 }
@@ -2,25 +2,53 @@ package config
 
 import (
 	"strings"
+	"time"
 )
 
 type TargetConfig struct {
-	Name   string            `koanf:"name"`
-	In     []string          `koanf:"in"`
-	Out    []string          `koanf:"out"`
-	Deps   []string          `koanf:"deps"`
-	Env    map[string]string `koanf:"env"`
-	Cmd    interface{}       `koanf:"cmd"`
-	Config TargetOptions     `koanf:"config"`
+	Name        string            `koanf:"name"`
+	In          []string          `koanf:"in"`
+	Out         []string          `koanf:"out"`
+	Deps        []string          `koanf:"deps"`
+	Env         map[string]string `koanf:"env"`
+	Secrets     []string          `koanf:"secrets"`
+	Reports     []string          `koanf:"reports"`
+	Coverage    []string          `koanf:"coverage"`
+	Ready       ReadyConfig       `koanf:"ready"`
+	Restart     string            `koanf:"restart"`
+	MaxRestarts *int              `koanf:"max_restarts"`
+	Critical    bool              `koanf:"critical"`
+	OnChange    []OnChangeConfig  `koanf:"on_change"`
+	StopSignal  string            `koanf:"stop_signal"`
+	StopTimeout time.Duration     `koanf:"stop_timeout"`
+	Cmd         interface{}       `koanf:"cmd"`
+	Config      TargetOptions     `koanf:"config"`
 }
 
 type TargetOptions struct {
 	WorkingDir string       `koanf:"working_dir"`
 	Dotenv     DotenvConfig `koanf:"dotenv"`
 	Reload     *bool        `koanf:"reload"`
+	Cache      *bool        `koanf:"cache"`
 	Ignore     []string     `koanf:"ignore"`
 }
 
+type ReadyConfig struct {
+	TCP      string        `koanf:"tcp"`
+	HTTP     string        `koanf:"http"`
+	Log      string        `koanf:"log"`
+	Cmd      string        `koanf:"cmd"`
+	Interval time.Duration `koanf:"interval"`
+	Timeout  time.Duration `koanf:"timeout"`
+}
+
+type OnChangeConfig struct {
+	Paths  []string `koanf:"paths"`
+	Action string   `koanf:"action"`
+	Signal string   `koanf:"signal"`
+	Exec   string   `koanf:"exec"`
+}
+
 type DotenvConfig struct {
 	Enabled *bool    `koanf:"enabled"`
 	Files   []string `koanf:"files"`
@@ -57,6 +85,34 @@ func (t *TargetConfig) SetDefaults() {
 	if t.Deps == nil {
 		t.Deps = []string{}
 	}
+	if t.Secrets == nil {
+		t.Secrets = []string{}
+	}
+	if t.Reports == nil {
+		t.Reports = []string{}
+	}
+	if t.Coverage == nil {
+		t.Coverage = []string{}
+	}
+	if t.StopSignal == "" {
+		t.StopSignal = "TERM"
+	}
+	if t.StopTimeout == 0 {
+		t.StopTimeout = 5 * time.Second
+	}
+	if t.Restart == "" {
+		t.Restart = "never"
+	}
+	if t.MaxRestarts == nil {
+		maxRestarts := 5
+		t.MaxRestarts = &maxRestarts
+	}
+	if t.Ready.Interval <= 0 {
+		t.Ready.Interval = 500 * time.Millisecond
+	}
+	if t.Ready.Timeout <= 0 {
+		t.Ready.Timeout = 60 * time.Second
+	}
 	if t.Config.WorkingDir == "" {
 		t.Config.WorkingDir = "local"
 	}
@@ -68,6 +124,10 @@ func (t *TargetConfig) SetDefaults() {
 		reload := true
 		t.Config.Reload = &reload
 	}
+	if t.Config.Cache == nil {
+		cache := false
+		t.Config.Cache = &cache
+	}
 	if t.Config.Ignore == nil {
 		t.Config.Ignore = []string{}
 	}
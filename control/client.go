@@ -0,0 +1,94 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/vcnkl/rpm/socket"
+)
+
+type Client struct {
+	socket *socket.Client
+}
+
+// NewClient returns a client of the control socket at path. Calls are not
+// limited in time, as stopping a target waits for its stop timeout.
+func NewClient(path string) *Client {
+	return &Client{socket: socket.NewClient(path, 0)}
+}
+
+func (c *Client) Services() ([]Service, error) {
+	resp, err := c.call(Request{Cmd: CmdList})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Services, nil
+}
+
+func (c *Client) Restart(targetID string) error {
+	_, err := c.call(Request{Cmd: CmdRestart, Target: targetID})
+	return err
+}
+
+func (c *Client) Stop(targetID string) error {
+	_, err := c.call(Request{Cmd: CmdStop, Target: targetID})
+	return err
+}
+
+func (c *Client) Rebuild(targetID string) error {
+	_, err := c.call(Request{Cmd: CmdRebuild, Target: targetID})
+	return err
+}
+
+// Logs calls fn with the last lines of the target's log, then with new
+// lines as they are written if follow is set, until ctx is done.
+func (c *Client) Logs(ctx context.Context, targetID string, lines int, follow bool, fn func(line string)) error {
+	conn, err := c.send(Request{Cmd: CmdLogs, Target: targetID, Lines: lines, Follow: follow})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	for {
+		var raw json.RawMessage
+		if err = conn.Receive(&raw); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		var resp Response
+		if err = socket.Decode(raw, &resp); err != nil {
+			return err
+		}
+		fn(resp.Line)
+	}
+}
+
+func (c *Client) call(req Request) (*Response, error) {
+	var resp Response
+	if err := c.socket.Call(req, &resp); err != nil {
+		return nil, noSession(err)
+	}
+	return &resp, nil
+}
+
+func (c *Client) send(req Request) (*socket.Conn, error) {
+	conn, err := c.socket.Open(req)
+	return conn, noSession(err)
+}
+
+func noSession(err error) error {
+	if errors.Is(err, socket.ErrUnavailable) {
+		return fmt.Errorf("no dev session running: %w", err)
+	}
+	return err
+}
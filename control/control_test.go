@@ -0,0 +1,185 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHandler struct {
+	mu      sync.Mutex
+	calls   []string
+	logPath string
+}
+
+func (h *fakeHandler) Services() []Service {
+	return []Service{{ID: "api:server_dev", Status: "ready", PID: 42}}
+}
+
+func (h *fakeHandler) Restart(targetID string) error { return h.record("restart", targetID) }
+func (h *fakeHandler) Stop(targetID string) error    { return h.record("stop", targetID) }
+func (h *fakeHandler) Rebuild(targetID string) error { return h.record("rebuild", targetID) }
+
+func (h *fakeHandler) LogPath(targetID string) (string, error) {
+	if targetID != "api:server_dev" {
+		return "", fmt.Errorf("unknown target: %s", targetID)
+	}
+	return h.logPath, nil
+}
+
+func (h *fakeHandler) record(cmd, targetID string) error {
+	if targetID != "api:server_dev" {
+		return fmt.Errorf("unknown target: %s", targetID)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = append(h.calls, cmd+" "+targetID)
+	return nil
+}
+
+func startServer(t *testing.T, h Handler) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dev.sock")
+	server, err := Listen(path, h)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.Serve(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return path
+}
+
+func TestServer_Commands(t *testing.T) {
+	h := &fakeHandler{}
+	client := NewClient(startServer(t, h))
+
+	services, err := client.Services()
+	require.NoError(t, err)
+	assert.Equal(t, []Service{{ID: "api:server_dev", Status: "ready", PID: 42}}, services)
+
+	require.NoError(t, client.Restart("api:server_dev"))
+	require.NoError(t, client.Stop("api:server_dev"))
+	require.NoError(t, client.Rebuild("api:server_dev"))
+	assert.Equal(t, []string{"restart api:server_dev", "stop api:server_dev", "rebuild api:server_dev"}, h.calls)
+
+	err = client.Restart("web:app_dev")
+	assert.EqualError(t, err, "unknown target: web:app_dev")
+}
+
+func TestServer_Logs(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "server.log")
+	require.NoError(t, os.WriteFile(logPath, []byte("one\ntwo\nthree\n"), 0644))
+	client := NewClient(startServer(t, &fakeHandler{logPath: logPath}))
+
+	var lines []string
+	err := client.Logs(context.Background(), "api:server_dev", 2, false, func(line string) {
+		lines = append(lines, line)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"two", "three"}, lines)
+
+	err = client.Logs(context.Background(), "web:app_dev", 0, false, func(string) {})
+	assert.EqualError(t, err, "unknown target: web:app_dev")
+}
+
+func TestListen_SessionRunning(t *testing.T) {
+	path := startServer(t, &fakeHandler{})
+
+	_, err := Listen(path, &fakeHandler{})
+	assert.ErrorIs(t, err, ErrSessionRunning)
+}
+
+func TestListen_StaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev.sock")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+
+	server, err := Listen(path, &fakeHandler{})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	server.Serve(ctx)
+	assert.NoFileExists(t, path)
+}
+
+func TestClient_NoSession(t *testing.T) {
+	_, err := NewClient(filepath.Join(t.TempDir(), "dev.sock")).Services()
+	assert.ErrorContains(t, err, "no dev session running")
+}
+
+func TestTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "target.log")
+	require.NoError(t, os.WriteFile(path, []byte("a\nb\nc\n"), 0644))
+
+	tests := []struct {
+		name     string
+		n        int
+		expected []string
+	}{
+		{name: "all lines", n: 0, expected: []string{"a", "b", "c"}},
+		{name: "last lines", n: 2, expected: []string{"b", "c"}},
+		{name: "more than available", n: 10, expected: []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			err := Tail(context.Background(), path, tt.n, false, func(line string) error {
+				lines = append(lines, line)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, lines)
+		})
+	}
+}
+
+func TestTail_Follow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "target.log")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	lines := make(chan string, 10)
+	done := make(chan error)
+	go func() {
+		done <- Tail(ctx, path, 0, true, func(line string) error {
+			lines <- line
+			return nil
+		})
+	}()
+
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0644))
+	assert.Equal(t, "first", <-lines)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("sec")
+	require.NoError(t, err)
+	time.Sleep(2 * tailInterval)
+	_, err = f.WriteString("ond\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, "second", <-lines)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestTail_Missing(t *testing.T) {
+	err := Tail(context.Background(), filepath.Join(t.TempDir(), "missing.log"), 0, false, func(string) error { return nil })
+	assert.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package control
+
+import "time"
+
+// Commands understood by the dev session's control socket.
+const (
+	CmdList    = "list"
+	CmdRestart = "restart"
+	CmdStop    = "stop"
+	CmdRebuild = "rebuild"
+	CmdLogs    = "logs"
+)
+
+// Request is sent as a single JSON line per connection.
+type Request struct {
+	Cmd    string `json:"cmd"`
+	Target string `json:"target,omitempty"`
+	Lines  int    `json:"lines,omitempty"`
+	Follow bool   `json:"follow,omitempty"`
+}
+
+// Response is a JSON line sent back for a request. A logs request gets one
+// response per log line until the log ends or the client disconnects.
+type Response struct {
+	Error    string    `json:"error,omitempty"`
+	Services []Service `json:"services,omitempty"`
+	Line     string    `json:"line,omitempty"`
+}
+
+type Service struct {
+	ID     string    `json:"id"`
+	Status string    `json:"status"`
+	PID    int       `json:"pid,omitempty"`
+	Since  time.Time `json:"since"`
+}
+
+// Handler carries out requests against a running dev session.
+type Handler interface {
+	Services() []Service
+	Restart(targetID string) error
+	Stop(targetID string) error
+	Rebuild(targetID string) error
+	LogPath(targetID string) (string, error)
+}
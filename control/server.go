@@ -0,0 +1,90 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/vcnkl/rpm/socket"
+)
+
+// ErrSessionRunning is returned by Listen when another session already
+// serves the socket.
+var ErrSessionRunning = errors.New("another dev session is already running")
+
+type Server struct {
+	socket  *socket.Server
+	handler Handler
+}
+
+// Listen creates the control socket at path, replacing a stale one left by
+// a session that did not shut down cleanly.
+func Listen(path string, handler Handler) (*Server, error) {
+	s, err := socket.Listen(path)
+	if errors.Is(err, socket.ErrInUse) {
+		return nil, ErrSessionRunning
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Server{socket: s, handler: handler}, nil
+}
+
+// Serve handles connections until ctx is done, then removes the socket.
+func (s *Server) Serve(ctx context.Context) {
+	s.socket.Serve(ctx, s.handle)
+}
+
+func (s *Server) handle(ctx context.Context, conn *socket.Conn) {
+	var req Request
+	if err := conn.Receive(&req); err != nil {
+		conn.Send(Response{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	switch req.Cmd {
+	case CmdList:
+		conn.Send(Response{Services: s.handler.Services()})
+	case CmdRestart:
+		conn.Send(result(s.handler.Restart(req.Target)))
+	case CmdStop:
+		conn.Send(result(s.handler.Stop(req.Target)))
+	case CmdRebuild:
+		conn.Send(result(s.handler.Rebuild(req.Target)))
+	case CmdLogs:
+		s.logs(ctx, conn, req)
+	default:
+		conn.Send(Response{Error: "unknown command: " + req.Cmd})
+	}
+}
+
+func (s *Server) logs(ctx context.Context, conn *socket.Conn, req Request) {
+	path, err := s.handler.LogPath(req.Target)
+	if err != nil {
+		conn.Send(Response{Error: err.Error()})
+		return
+	}
+
+	// The client closes the connection to stop following.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		var ignored json.RawMessage
+		conn.Receive(&ignored)
+		cancel()
+	}()
+
+	err = Tail(ctx, path, req.Lines, req.Follow, func(line string) error {
+		return conn.Send(Response{Line: line})
+	})
+	if err != nil && ctx.Err() == nil {
+		conn.Send(Response{Error: err.Error()})
+	}
+}
+
+func result(err error) Response {
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	return Response{}
+}
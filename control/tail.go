@@ -0,0 +1,107 @@
+package control
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// tailInterval is how often a followed file is checked for new data.
+const tailInterval = 200 * time.Millisecond
+
+// Tail calls fn with the last n lines of the file at path (all of them when
+// n is 0). With follow it keeps calling fn with lines appended later until
+// ctx is done, waiting for the file if it does not exist yet.
+func Tail(ctx context.Context, path string, n int, follow bool, fn func(line string) error) error {
+	f, err := open(ctx, path, follow)
+	if err != nil || f == nil {
+		return err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if n > 0 && len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if err = fn(line); err != nil {
+			return err
+		}
+	}
+	if !follow {
+		return nil
+	}
+
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	var partial []byte
+	buf := make([]byte, 32*1024)
+	ticker := time.NewTicker(tailInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if info, err := f.Stat(); err == nil && info.Size() < offset {
+			offset, partial = 0, nil
+			if _, err = f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+
+		for {
+			read, err := f.Read(buf)
+			offset += int64(read)
+			partial = append(partial, buf[:read]...)
+			for {
+				i := bytes.IndexByte(partial, '\n')
+				if i < 0 {
+					break
+				}
+				if err := fn(string(partial[:i])); err != nil {
+					return err
+				}
+				partial = partial[i+1:]
+			}
+			if err == io.EOF || read == 0 {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// open opens path, waiting for it to be created when follow is set. It
+// returns a nil file when ctx ends first.
+func open(ctx context.Context, path string, follow bool) (*os.File, error) {
+	for {
+		f, err := os.Open(path)
+		if err == nil || !follow || !os.IsNotExist(err) {
+			return f, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-time.After(tailInterval):
+		}
+	}
+}
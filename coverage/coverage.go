@@ -0,0 +1,208 @@
+// Package coverage reads Go coverprofile, LCOV and Cobertura coverage files,
+// merges them at line granularity and writes the result as LCOV.
+package coverage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatGo        Format = "go"
+	FormatLCOV      Format = "lcov"
+	FormatCobertura Format = "cobertura"
+)
+
+// Profile maps file paths to per-line hit counts. A line with a zero count
+// is instrumented but not covered.
+type Profile struct {
+	Files map[string]map[int]int
+}
+
+func NewProfile() *Profile {
+	return &Profile{
+		Files: make(map[string]map[int]int),
+	}
+}
+
+func (p *Profile) add(file string, line, hits int) {
+	lines, ok := p.Files[file]
+	if !ok {
+		lines = make(map[int]int)
+		p.Files[file] = lines
+	}
+	lines[line] += hits
+}
+
+// Merge adds the hit counts of other into p.
+func (p *Profile) Merge(other *Profile) {
+	for file, lines := range other.Files {
+		for line, hits := range lines {
+			p.add(file, line, hits)
+		}
+	}
+}
+
+// Rewrite returns a copy of p with every file path passed through fn. Files
+// for which fn returns "" are dropped.
+func (p *Profile) Rewrite(fn func(string) string) *Profile {
+	out := NewProfile()
+	for file, lines := range p.Files {
+		name := fn(file)
+		if name == "" {
+			continue
+		}
+		for line, hits := range lines {
+			out.add(name, line, hits)
+		}
+	}
+	return out
+}
+
+// Lines returns the number of instrumented and covered lines.
+func (p *Profile) Lines() (total, covered int) {
+	for _, lines := range p.Files {
+		for _, hits := range lines {
+			total++
+			if hits > 0 {
+				covered++
+			}
+		}
+	}
+	return total, covered
+}
+
+func Detect(data []byte) (Format, error) {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("mode:")):
+		return FormatGo, nil
+	case bytes.HasPrefix(trimmed, []byte("<")) && bytes.Contains(trimmed, []byte("<coverage")):
+		return FormatCobertura, nil
+	case bytes.Contains(trimmed, []byte("SF:")):
+		return FormatLCOV, nil
+	}
+	return "", fmt.Errorf("unrecognized coverage format")
+}
+
+func Parse(data []byte) (*Profile, error) {
+	format, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatGo:
+		return parseGo(data)
+	case FormatCobertura:
+		return parseCobertura(data)
+	default:
+		return parseLCOV(data)
+	}
+}
+
+func ParseFile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read coverage file %s: %w", path, err)
+	}
+	profile, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse coverage file %s: %w", path, err)
+	}
+	return profile, nil
+}
+
+// WriteLCOV writes p in LCOV tracefile format with files and lines sorted.
+func (p *Profile) WriteLCOV(w io.Writer) error {
+	files := make([]string, 0, len(p.Files))
+	for file := range p.Files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var b strings.Builder
+	for _, file := range files {
+		lines := p.Files[file]
+		numbers := make([]int, 0, len(lines))
+		for n := range lines {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+
+		covered := 0
+		fmt.Fprintf(&b, "SF:%s\n", file)
+		for _, n := range numbers {
+			fmt.Fprintf(&b, "DA:%d,%d\n", n, lines[n])
+			if lines[n] > 0 {
+				covered++
+			}
+		}
+		fmt.Fprintf(&b, "LF:%d\nLH:%d\nend_of_record\n", len(numbers), covered)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (p *Profile) WriteFile(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	var buf bytes.Buffer
+	if err := p.WriteLCOV(&buf); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write coverage report %s: %w", path, err)
+	}
+	return nil
+}
+
+// Percent returns covered/total as a percentage, or 0 when nothing was
+// instrumented.
+func Percent(total, covered int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(covered) * 100 / float64(total)
+}
+
+// ResolvePath turns a path found in a coverage file into a slash-separated
+// path relative to repoRoot. Relative paths (including Go import paths) are
+// tried against each of dirs, dropping leading elements until an existing
+// file is found. Paths that cannot be resolved are returned unchanged.
+func ResolvePath(file, repoRoot string, dirs ...string) string {
+	if filepath.IsAbs(file) {
+		return relative(repoRoot, file)
+	}
+
+	parts := strings.Split(filepath.ToSlash(file), "/")
+	for _, dir := range dirs {
+		for i := range parts {
+			candidate := filepath.Join(dir, filepath.Join(parts[i:]...))
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return relative(repoRoot, candidate)
+			}
+		}
+	}
+	return file
+}
+
+func relative(repoRoot, path string) string {
+	rel, err := filepath.Rel(repoRoot, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,220 @@
+package coverage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected Format
+		hasError bool
+	}{
+		{
+			name:     "go coverprofile",
+			data:     "mode: set\npkg/a.go:1.1,2.2 1 1\n",
+			expected: FormatGo,
+		},
+		{
+			name:     "lcov",
+			data:     "TN:\nSF:src/a.js\nDA:1,1\nend_of_record\n",
+			expected: FormatLCOV,
+		},
+		{
+			name:     "cobertura",
+			data:     "<?xml version=\"1.0\"?>\n<coverage line-rate=\"1\"></coverage>",
+			expected: FormatCobertura,
+		},
+		{
+			name:     "unknown",
+			data:     "hello",
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := Detect([]byte(tt.data))
+			if tt.hasError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected map[string]map[int]int
+	}{
+		{
+			name: "go coverprofile takes the highest count per line",
+			data: `mode: count
+example.com/m/pkg/a.go:3.10,5.2 2 4
+example.com/m/pkg/a.go:5.2,6.3 1 0
+example.com/m/pkg/b.go:1.1,1.20 1 0
+`,
+			expected: map[string]map[int]int{
+				"example.com/m/pkg/a.go": {3: 4, 4: 4, 5: 4, 6: 0},
+				"example.com/m/pkg/b.go": {1: 0},
+			},
+		},
+		{
+			name: "lcov",
+			data: `TN:
+SF:src/a.js
+DA:1,2
+DA:2,0
+end_of_record
+SF:src/b.js
+DA:7,1
+end_of_record
+`,
+			expected: map[string]map[int]int{
+				"src/a.js": {1: 2, 2: 0},
+				"src/b.js": {7: 1},
+			},
+		},
+		{
+			name: "cobertura joins the source directory",
+			data: `<?xml version="1.0" ?>
+<coverage line-rate="0.5">
+  <sources><source>lib</source></sources>
+  <packages>
+    <package name="app">
+      <classes>
+        <class name="a" filename="app/a.py">
+          <lines>
+            <line number="1" hits="3"/>
+            <line number="2" hits="0"/>
+          </lines>
+        </class>
+      </classes>
+    </package>
+  </packages>
+</coverage>`,
+			expected: map[string]map[int]int{
+				"lib/app/a.py": {1: 3, 2: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := Parse([]byte(tt.data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, profile.Files)
+		})
+	}
+}
+
+func TestParse_InvalidGoBlock(t *testing.T) {
+	_, err := Parse([]byte("mode: set\npkg/a.go 1 1\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestProfile_MergeAndWriteLCOV(t *testing.T) {
+	a := NewProfile()
+	a.add("b.go", 2, 0)
+	a.add("a.go", 1, 1)
+
+	b := NewProfile()
+	b.add("b.go", 2, 3)
+	b.add("b.go", 1, 0)
+
+	a.Merge(b)
+
+	total, covered := a.Lines()
+	assert.Equal(t, 3, total)
+	assert.Equal(t, 2, covered)
+
+	var buf bytes.Buffer
+	require.NoError(t, a.WriteLCOV(&buf))
+	assert.Equal(t, `SF:a.go
+DA:1,1
+LF:1
+LH:1
+end_of_record
+SF:b.go
+DA:1,0
+DA:2,3
+LF:2
+LH:1
+end_of_record
+`, buf.String())
+}
+
+func TestSummarize(t *testing.T) {
+	profile := NewProfile()
+	profile.add("services/api/main.go", 1, 1)
+	profile.add("services/api/main.go", 2, 0)
+	profile.add("services/api/client/client.go", 1, 1)
+	profile.add("libs/core/core.go", 1, 0)
+	profile.add("/usr/lib/go/src/fmt/print.go", 1, 1)
+
+	summaries := Summarize(profile, map[string]string{
+		"api":    "services/api",
+		"client": "services/api/client",
+		"core":   "libs/core",
+	})
+
+	assert.Equal(t, []Summary{
+		{Name: "api", Total: 2, Covered: 1},
+		{Name: "client", Total: 1, Covered: 1},
+		{Name: "core", Total: 1, Covered: 0},
+	}, summaries)
+	assert.Equal(t, 50.0, summaries[0].Percent())
+	assert.Equal(t, 0.0, Summary{}.Percent())
+}
+
+func TestResolvePath(t *testing.T) {
+	root := t.TempDir()
+	bundle := filepath.Join(root, "services", "api")
+	require.NoError(t, os.MkdirAll(filepath.Join(bundle, "pkg"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(bundle, "pkg", "a.go"), nil, 0644))
+
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+	}{
+		{
+			name:     "go import path",
+			file:     "example.com/api/pkg/a.go",
+			expected: "services/api/pkg/a.go",
+		},
+		{
+			name:     "bundle relative",
+			file:     "pkg/a.go",
+			expected: "services/api/pkg/a.go",
+		},
+		{
+			name:     "absolute inside repo",
+			file:     filepath.Join(bundle, "pkg", "a.go"),
+			expected: "services/api/pkg/a.go",
+		},
+		{
+			name:     "unresolvable",
+			file:     "example.com/other/b.go",
+			expected: "example.com/other/b.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ResolvePath(tt.file, root, bundle, root))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package coverage
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/vcnkl/rpm/stores/logs"
+)
+
+// extensions are those of the coverage files rpm reads from a target's
+// coverage directory.
+var extensions = []string{".out", ".cov", ".coverprofile", ".txt", ".info", ".lcov", ".xml"}
+
+// IsCoverageFile reports whether path has the extension of a Go
+// coverprofile, LCOV or Cobertura file.
+func IsCoverageFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// RunDir returns the directory under root for the coverage of run runID,
+// first removing all but the newest keep directories of earlier runs.
+func RunDir(root, runID string, keep int) (string, error) {
+	return filepath.Join(root, runID), logs.PruneRuns(root, keep)
+}
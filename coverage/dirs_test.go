@@ -0,0 +1,58 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCoverageFile(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{path: "coverage.out", expected: true},
+		{path: "lcov.info", expected: true},
+		{path: "report.LCOV", expected: true},
+		{path: "cobertura.xml", expected: true},
+		{path: "pkg.coverprofile", expected: true},
+		{path: "test.log", expected: false},
+		{path: ".DS_Store", expected: false},
+		{path: "coverage", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsCoverageFile(tt.path))
+		})
+	}
+}
+
+func TestRunDir(t *testing.T) {
+	root := t.TempDir()
+	for _, run := range []string{"20240101-000000-1", "20240102-000000-1", "20240103-000000-1"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, run), 0755))
+	}
+
+	dir, err := RunDir(root, "20240104-000000-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "20240104-000000-1"), dir)
+
+	entries, err := os.ReadDir(root)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"20240103-000000-1"}, names)
+}
+
+func TestRunDir_MissingRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "coverage")
+	dir, err := RunDir(root, "run", 20)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "run"), dir)
+}
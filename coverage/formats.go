@@ -0,0 +1,151 @@
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// parseGo reads a `go test -coverprofile` file. Each block covers a range of
+// lines; a line is counted as hit if any block on it was executed.
+func parseGo(data []byte) (*Profile, error) {
+	profile := NewProfile()
+	lines := make(map[string]map[int]int)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "mode:") {
+			continue
+		}
+
+		// file.go:12.34,15.2 3 1
+		colon := strings.LastIndex(text, ":")
+		if colon == -1 {
+			return nil, fmt.Errorf("line %d: invalid coverprofile block %q", lineNo, text)
+		}
+		file := text[:colon]
+		fields := strings.Fields(text[colon+1:])
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: invalid coverprofile block %q", lineNo, text)
+		}
+
+		start, end, ok := strings.Cut(fields[0], ",")
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid coverprofile range %q", lineNo, fields[0])
+		}
+		startLine, err1 := strconv.Atoi(strings.SplitN(start, ".", 2)[0])
+		endLine, err2 := strconv.Atoi(strings.SplitN(end, ".", 2)[0])
+		count, err3 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("line %d: invalid coverprofile block %q", lineNo, text)
+		}
+
+		fileLines, ok := lines[file]
+		if !ok {
+			fileLines = make(map[int]int)
+			lines[file] = fileLines
+		}
+		for l := startLine; l <= endLine; l++ {
+			if count > fileLines[l] {
+				fileLines[l] = count
+			} else if _, seen := fileLines[l]; !seen {
+				fileLines[l] = 0
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for file, fileLines := range lines {
+		for l, hits := range fileLines {
+			profile.add(file, l, hits)
+		}
+	}
+	return profile, nil
+}
+
+func parseLCOV(data []byte) (*Profile, error) {
+	profile := NewProfile()
+
+	var file string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(text, "SF:"):
+			file = strings.TrimPrefix(text, "SF:")
+		case strings.HasPrefix(text, "DA:"):
+			if file == "" {
+				return nil, fmt.Errorf("line %d: DA record outside of a source file", lineNo)
+			}
+			parts := strings.Split(strings.TrimPrefix(text, "DA:"), ",")
+			if len(parts) < 2 {
+				return nil, fmt.Errorf("line %d: invalid DA record %q", lineNo, text)
+			}
+			line, err1 := strconv.Atoi(parts[0])
+			hits, err2 := strconv.Atoi(parts[1])
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("line %d: invalid DA record %q", lineNo, text)
+			}
+			profile.add(file, line, hits)
+		case text == "end_of_record":
+			file = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+type coberturaReport struct {
+	Sources  []string `xml:"sources>source"`
+	Packages []struct {
+		Classes []struct {
+			Filename string `xml:"filename,attr"`
+			Lines    []struct {
+				Number int `xml:"number,attr"`
+				Hits   int `xml:"hits,attr"`
+			} `xml:"lines>line"`
+		} `xml:"classes>class"`
+	} `xml:"packages>package"`
+}
+
+// parseCobertura reads a Cobertura XML report. Class file names are joined
+// with the first <source> entry, if any.
+func parseCobertura(data []byte) (*Profile, error) {
+	var report coberturaReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	source := ""
+	if len(report.Sources) > 0 {
+		source = strings.TrimSpace(report.Sources[0])
+	}
+
+	profile := NewProfile()
+	for _, pkg := range report.Packages {
+		for _, class := range pkg.Classes {
+			file := class.Filename
+			if source != "" && !path.IsAbs(file) {
+				file = path.Join(source, file)
+			}
+			for _, line := range class.Lines {
+				profile.add(file, line.Number, line.Hits)
+			}
+		}
+	}
+	return profile, nil
+}
@@ -0,0 +1,68 @@
+package coverage
+
+import (
+	"sort"
+	"strings"
+)
+
+type Summary struct {
+	Name    string
+	Total   int
+	Covered int
+}
+
+func (s Summary) Percent() float64 {
+	return Percent(s.Total, s.Covered)
+}
+
+// Summarize attributes every file of p to the bundle with the longest
+// matching directory in bundles (name to repo-relative path) and returns
+// one summary per bundle that has instrumented lines, sorted by name.
+// Files outside every bundle are left out.
+func Summarize(p *Profile, bundles map[string]string) []Summary {
+	totals := make(map[string]*Summary)
+
+	for file, lines := range p.Files {
+		name := owner(file, bundles)
+		if name == "" {
+			continue
+		}
+		s, ok := totals[name]
+		if !ok {
+			s = &Summary{Name: name}
+			totals[name] = s
+		}
+		for _, hits := range lines {
+			s.Total++
+			if hits > 0 {
+				s.Covered++
+			}
+		}
+	}
+
+	summaries := make([]Summary, 0, len(totals))
+	for _, s := range totals {
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
+
+func owner(file string, bundles map[string]string) string {
+	best, bestLen := "", -1
+	for name, dir := range bundles {
+		dir = strings.Trim(dir, "/")
+		if dir == "." {
+			dir = ""
+		}
+		if dir != "" && file != dir && !strings.HasPrefix(file, dir+"/") {
+			continue
+		}
+		if len(dir) > bestLen {
+			best, bestLen = name, len(dir)
+		}
+	}
+	return best
+}
@@ -0,0 +1,20 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+)
+
+// binaryID identifies the running rpm executable by path and modification
+// time.
+func binaryID() string {
+	path, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return path
+	}
+	return fmt.Sprintf("%s@%d", path, info.ModTime().UnixNano())
+}
@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vcnkl/rpm/cache/hashing"
+	"github.com/vcnkl/rpm/socket"
+)
+
+// ErrNotRunning is returned when no daemon serves the socket.
+var ErrNotRunning = errors.New("the rpm daemon is not running")
+
+// ErrOtherBinary is returned by Load when the daemon runs a different rpm
+// executable, whose state may not match this one's.
+var ErrOtherBinary = errors.New("the rpm daemon runs a different rpm binary")
+
+// callTimeout bounds every call, so a wedged daemon or one stuck reloading
+// slows commands down instead of hanging them; they then work in-process.
+const callTimeout = 5 * time.Second
+
+type Client struct {
+	socket *socket.Client
+}
+
+func NewClient(path string) *Client {
+	return &Client{socket: socket.NewClient(path, callTimeout)}
+}
+
+// Load returns the daemon's loaded config.
+func (c *Client) Load() (*State, error) {
+	resp, err := c.call(Request{Cmd: CmdLoad})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Binary != binaryID() {
+		return nil, ErrOtherBinary
+	}
+	return resp.State, nil
+}
+
+// HashInputs hashes inputs through the daemon's file hashes, or in-process
+// if the daemon went away or failed to answer.
+func (c *Client) HashInputs(bundleRoot string, patterns []string) (string, error) {
+	resp, err := c.call(Request{Cmd: CmdHash, BundleRoot: bundleRoot, Patterns: patterns})
+	if err != nil {
+		return hashing.HashInputs(bundleRoot, patterns)
+	}
+	return resp.Hash, nil
+}
+
+func (c *Client) Status() (*Status, error) {
+	resp, err := c.call(Request{Cmd: CmdStatus})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Status, nil
+}
+
+func (c *Client) Stop() error {
+	_, err := c.call(Request{Cmd: CmdStop})
+	return err
+}
+
+func (c *Client) call(req Request) (*Response, error) {
+	var resp Response
+	err := c.socket.Call(req, &resp)
+	if errors.Is(err, socket.ErrUnavailable) {
+		return nil, fmt.Errorf("%w: %v", ErrNotRunning, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
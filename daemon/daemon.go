@@ -0,0 +1,251 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vcnkl/rpm/cache/hashing"
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/dag"
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/watcher"
+)
+
+// Daemon keeps a repository's config, resolved graph and file hashes in
+// memory, reloading the config when repo.yml, an rpm.yml or a .gitignore
+// changes.
+type Daemon struct {
+	repoRoot string
+	log      logger.Logger
+	files    *hashing.FileCache
+	binary   string
+	started  time.Time
+
+	mu      sync.Mutex
+	cfg     *config.Config
+	graph   *dag.Graph
+	loaded  time.Time
+	loadErr error
+	// configFiles holds the modification times of the files the config was
+	// loaded from.
+	configFiles map[string]time.Time
+}
+
+func New(repoRoot string, log logger.Logger) *Daemon {
+	return &Daemon{
+		repoRoot: repoRoot,
+		log:      log,
+		files:    hashing.NewFileCache(),
+		binary:   binaryID(),
+		started:  time.Now(),
+	}
+}
+
+// Run loads the config and serves the socket at path until ctx is done or
+// a client asks the daemon to stop.
+func (d *Daemon) Run(ctx context.Context, path string) error {
+	d.reload()
+
+	server, err := Listen(path, d)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	repo := d.repoConfig()
+	w, err := watcher.NewWatcher([]string{d.repoRoot}, nil, watcher.Options{
+		Debounce:     repo.Watch.Debounce,
+		Poll:         repo.Watch.Poll,
+		PollInterval: repo.Watch.PollInterval,
+		SkipDirs:     repo.Watch.SkipDirs,
+	})
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+	w.OnChange(d.changed)
+	w.OnError(func(err error) {
+		d.log.Warn("file watcher error", logger.Err(err), logger.Bool("polling", w.Polling()))
+	})
+	go w.Start(ctx)
+
+	d.log.Info("daemon started", logger.String("socket", path), logger.Int("pid", os.Getpid()))
+	server.Serve(ctx, cancel)
+	d.log.Info("daemon stopped")
+	return nil
+}
+
+// reload loads the config and resolves the graph. A config that fails to
+// load is kept as an error, so clients load in-process and report it.
+func (d *Daemon) reload() {
+	start := time.Now()
+	cfg, graph, files, err := load(d.repoRoot)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loaded = time.Now()
+	d.loadErr = err
+	d.configFiles = files
+	if err != nil {
+		d.cfg, d.graph = nil, nil
+		d.log.Error("failed to load config", logger.Err(err))
+		return
+	}
+	d.cfg, d.graph = cfg, graph
+	d.log.Info("config loaded",
+		logger.Int("bundles", len(cfg.Bundles())),
+		logger.Int("targets", len(graph.Nodes)),
+		logger.Duration("duration", time.Since(start)))
+}
+
+func load(repoRoot string) (cfg *config.Config, graph *dag.Graph, files map[string]time.Time, err error) {
+	files = make(map[string]time.Time)
+	files[filepath.Join(repoRoot, "repo.yml")] = modTime(filepath.Join(repoRoot, "repo.yml"))
+
+	// The config loaders panic on invalid files.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	cfg = config.Load(repoRoot)
+	graph = dag.NewGraph()
+	for _, bundle := range cfg.Bundles() {
+		path := filepath.Join(repoRoot, bundle.Path, "rpm.yml")
+		files[path] = modTime(path)
+		for _, target := range bundle.Targets {
+			graph.AddTarget(target)
+		}
+	}
+	if err = graph.Resolve(cfg.Bundles()); err != nil {
+		return nil, nil, files, err
+	}
+	return cfg, graph, files, nil
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// stale reports whether a file the config was loaded from changed, which
+// may not have been reported by the watcher yet.
+func (d *Daemon) stale() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for path, loaded := range d.configFiles {
+		if !modTime(path).Equal(loaded) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Daemon) changed(batch watcher.Batch) {
+	if batch.Rescan {
+		d.log.Warn("file changes lost, reloading")
+		d.files.Clear()
+		d.reload()
+		return
+	}
+
+	reload := false
+	for _, path := range batch.Paths {
+		// Hashes are only checked against size, modification time and
+		// inode, which an edit can keep.
+		d.files.Forget(path)
+		switch filepath.Base(path) {
+		case "rpm.yml", "repo.yml", ".gitignore":
+			reload = true
+		}
+		if _, err := os.Stat(path); err != nil && d.containsConfig(path) {
+			reload = true
+		}
+	}
+	if reload {
+		d.reload()
+	}
+}
+
+// containsConfig reports whether dir holds a file the config was loaded
+// from.
+func (d *Daemon) containsConfig(dir string) bool {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for path := range d.configFiles {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Daemon) repoConfig() *config.RepoConfig {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cfg != nil {
+		return d.cfg.Repo()
+	}
+	repo := &config.RepoConfig{}
+	repo.SetDefaults()
+	return repo
+}
+
+// State returns the loaded config, reloading it first if it is stale.
+func (d *Daemon) State() (*State, error) {
+	if d.stale() {
+		d.reload()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.loadErr != nil {
+		return nil, d.loadErr
+	}
+
+	state := &State{RepoRoot: d.cfg.RepoRoot(), Repo: d.cfg.Repo()}
+	for _, bundle := range d.cfg.Bundles() {
+		state.Bundles = append(state.Bundles, bundle)
+	}
+	sort.Slice(state.Bundles, func(i, j int) bool {
+		return state.Bundles[i].Name < state.Bundles[j].Name
+	})
+	return state, nil
+}
+
+func (d *Daemon) HashInputs(bundleRoot string, patterns []string) (string, error) {
+	return d.files.HashInputs(bundleRoot, patterns)
+}
+
+func (d *Daemon) Status() *Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := &Status{
+		PID:     os.Getpid(),
+		Started: d.started,
+		Loaded:  d.loaded,
+		Files:   d.files.Len(),
+	}
+	if d.loadErr != nil {
+		status.Error = d.loadErr.Error()
+		return status
+	}
+	status.Bundles = len(d.cfg.Bundles())
+	status.Targets = len(d.graph.Nodes)
+	return status
+}
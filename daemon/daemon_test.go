@@ -0,0 +1,210 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vcnkl/rpm/cache/hashing"
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/socket"
+	"github.com/vcnkl/rpm/watcher"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func startDaemon(t *testing.T, repoRoot string) *Client {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "daemon.sock")
+	d := New(repoRoot, logger.NewWithWriter(logger.ErrorLevel, io.Discard))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- d.Run(ctx, path)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	client := NewClient(path)
+	require.Eventually(t, func() bool {
+		_, err := client.Status()
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return client
+}
+
+func TestDaemon_Load(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeFile(t, filepath.Join(repoRoot, "repo.yml"), "shell: /bin/sh\n")
+	bundlePath := filepath.Join(repoRoot, "lib", "rpm.yml")
+	writeFile(t, bundlePath, "name: lib\ntargets:\n  - name: lib_build\n    cmd: make\n")
+
+	client := startDaemon(t, repoRoot)
+
+	state, err := client.Load()
+	require.NoError(t, err)
+	assert.Equal(t, repoRoot, state.RepoRoot)
+	assert.Equal(t, "/bin/sh", state.Repo.Shell)
+	require.Len(t, state.Bundles, 1)
+	assert.Equal(t, "lib", state.Bundles[0].Name)
+	require.Len(t, state.Bundles[0].Targets, 1)
+	assert.Equal(t, "lib_build", state.Bundles[0].Targets[0].Name)
+
+	// Edits are picked up on the next load even before the watcher reports
+	// them.
+	writeFile(t, bundlePath, "name: lib\ntargets:\n  - name: lib_build\n    cmd: make\n  - name: lib_test\n    cmd: make test\n")
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(bundlePath, later, later))
+	state, err = client.Load()
+	require.NoError(t, err)
+	assert.Len(t, state.Bundles[0].Targets, 2)
+
+	writeFile(t, bundlePath, "name: lib\ntargets: [\n")
+	later = later.Add(time.Second)
+	require.NoError(t, os.Chtimes(bundlePath, later, later))
+	_, err = client.Load()
+	assert.ErrorContains(t, err, "failed to read rpm.yml")
+}
+
+func TestDaemon_HashInputs(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeFile(t, filepath.Join(repoRoot, "repo.yml"), "shell: /bin/sh\n")
+	bundleRoot := filepath.Join(repoRoot, "lib")
+	writeFile(t, filepath.Join(bundleRoot, "a.go"), "package a")
+
+	client := startDaemon(t, repoRoot)
+
+	expected, err := hashing.HashInputs(bundleRoot, []string{"*.go"})
+	require.NoError(t, err)
+	hash, err := client.HashInputs(bundleRoot, []string{"*.go"})
+	require.NoError(t, err)
+	assert.Equal(t, expected, hash)
+
+	status, err := client.Status()
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Files)
+}
+
+func TestClient_HashInputsFallback(t *testing.T) {
+	bundleRoot := t.TempDir()
+	writeFile(t, filepath.Join(bundleRoot, "a.go"), "package a")
+	expected, err := hashing.HashInputs(bundleRoot, []string{"*.go"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		handle func(conn *socket.Conn)
+	}{
+		{
+			name:   "connection closed without a response",
+			handle: func(conn *socket.Conn) {},
+		},
+		{
+			name: "error response",
+			handle: func(conn *socket.Conn) {
+				conn.Send(Response{Error: "failed to hash inputs"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "daemon.sock")
+			server, err := socket.Listen(path)
+			require.NoError(t, err)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				server.Serve(ctx, func(ctx context.Context, conn *socket.Conn) {
+					var req Request
+					conn.Receive(&req)
+					tt.handle(conn)
+				})
+				close(done)
+			}()
+			t.Cleanup(func() {
+				cancel()
+				<-done
+			})
+
+			hash, err := NewClient(path).HashInputs(bundleRoot, []string{"*.go"})
+			require.NoError(t, err)
+			assert.Equal(t, expected, hash)
+		})
+	}
+}
+
+func TestDaemon_Changed(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeFile(t, filepath.Join(repoRoot, "repo.yml"), "shell: /bin/sh\n")
+	bundleRoot := filepath.Join(repoRoot, "lib")
+	writeFile(t, filepath.Join(bundleRoot, "a.go"), "package a")
+	writeFile(t, filepath.Join(bundleRoot, "b.go"), "package b")
+
+	d := New(repoRoot, logger.NewWithWriter(logger.ErrorLevel, io.Discard))
+	_, err := d.HashInputs(bundleRoot, []string{"*.go"})
+	require.NoError(t, err)
+	require.Equal(t, 2, d.files.Len())
+
+	// Files that still exist are forgotten too, as an edit may keep their
+	// size and modification time.
+	d.changed(watcher.Batch{Paths: []string{filepath.Join(bundleRoot, "a.go")}})
+	assert.Equal(t, 1, d.files.Len())
+
+	d.changed(watcher.Batch{Rescan: true})
+	assert.Equal(t, 0, d.files.Len())
+}
+
+func TestDaemon_Stop(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeFile(t, filepath.Join(repoRoot, "repo.yml"), "shell: /bin/sh\n")
+
+	path := filepath.Join(t.TempDir(), "daemon.sock")
+	d := New(repoRoot, logger.NewWithWriter(logger.ErrorLevel, io.Discard))
+	done := make(chan error)
+	go func() {
+		done <- d.Run(context.Background(), path)
+	}()
+
+	client := NewClient(path)
+	require.Eventually(t, func() bool {
+		_, err := client.Status()
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err := Listen(path, d)
+	assert.ErrorIs(t, err, ErrRunning)
+
+	require.NoError(t, client.Stop())
+	assert.NoError(t, <-done)
+	_, err = client.Status()
+	assert.ErrorIs(t, err, ErrNotRunning)
+}
+
+func TestClient_NotRunning(t *testing.T) {
+	dir := t.TempDir()
+	client := NewClient(filepath.Join(dir, "daemon.sock"))
+
+	_, err := client.Load()
+	assert.ErrorIs(t, err, ErrNotRunning)
+
+	writeFile(t, filepath.Join(dir, "a.txt"), "a")
+	expected, err := hashing.HashInputs(dir, []string{"*.txt"})
+	require.NoError(t, err)
+	hash, err := client.HashInputs(dir, []string{"*.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, expected, hash)
+}
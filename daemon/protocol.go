@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/models"
+)
+
+// Commands understood by the daemon socket.
+const (
+	CmdLoad   = "load"
+	CmdHash   = "hash"
+	CmdStatus = "status"
+	CmdStop   = "stop"
+)
+
+// Request is sent as a single JSON line per connection.
+type Request struct {
+	Cmd        string   `json:"cmd"`
+	BundleRoot string   `json:"bundle_root,omitempty"`
+	Patterns   []string `json:"patterns,omitempty"`
+}
+
+// Response is the JSON line sent back for a request. Binary identifies the
+// rpm executable the daemon runs, so clients built differently do not use
+// its state.
+type Response struct {
+	Error  string  `json:"error,omitempty"`
+	Binary string  `json:"binary,omitempty"`
+	State  *State  `json:"state,omitempty"`
+	Hash   string  `json:"hash,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// State is the loaded configuration of the repository.
+type State struct {
+	RepoRoot string             `json:"repo_root"`
+	Repo     *config.RepoConfig `json:"repo"`
+	Bundles  []*models.Bundle   `json:"bundles"`
+}
+
+type Status struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Loaded  time.Time `json:"loaded"`
+	Bundles int       `json:"bundles"`
+	Targets int       `json:"targets"`
+	Files   int       `json:"files"`
+	Error   string    `json:"error,omitempty"`
+}
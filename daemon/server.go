@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vcnkl/rpm/socket"
+)
+
+// ErrRunning is returned by Listen when another daemon already serves the
+// socket.
+var ErrRunning = errors.New("the rpm daemon is already running")
+
+type Server struct {
+	socket *socket.Server
+	daemon *Daemon
+}
+
+// Listen creates the daemon socket at path, replacing a stale one left by a
+// daemon that did not shut down cleanly.
+func Listen(path string, d *Daemon) (*Server, error) {
+	s, err := socket.Listen(path)
+	if errors.Is(err, socket.ErrInUse) {
+		return nil, ErrRunning
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Server{socket: s, daemon: d}, nil
+}
+
+// Serve handles connections until ctx is done or a client sends stop, which
+// calls stop, then removes the socket.
+func (s *Server) Serve(ctx context.Context, stop func()) {
+	s.socket.Serve(ctx, func(ctx context.Context, conn *socket.Conn) {
+		if s.handle(conn) {
+			stop()
+		}
+	})
+}
+
+// handle answers one request and reports whether it asked the daemon to
+// stop.
+func (s *Server) handle(conn *socket.Conn) bool {
+	var req Request
+	if err := conn.Receive(&req); err != nil {
+		conn.Send(Response{Error: "invalid request: " + err.Error()})
+		return false
+	}
+
+	resp := Response{Binary: s.daemon.binary}
+	switch req.Cmd {
+	case CmdLoad:
+		state, err := s.daemon.State()
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		resp.State = state
+	case CmdHash:
+		hash, err := s.daemon.HashInputs(req.BundleRoot, req.Patterns)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		resp.Hash = hash
+	case CmdStatus:
+		resp.Status = s.daemon.Status()
+	case CmdStop:
+		conn.Send(resp)
+		return true
+	default:
+		resp.Error = "unknown command: " + req.Cmd
+	}
+	conn.Send(resp)
+	return false
+}
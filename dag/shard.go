@@ -0,0 +1,97 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultShardWeight is the estimated duration of a target when no target
+// has a recorded duration.
+const defaultShardWeight = time.Second
+
+type Shard struct {
+	Index     int
+	Targets   []string
+	Estimated time.Duration
+}
+
+// ParseShard parses an "i/n" shard spec, where 1 <= i <= n.
+func ParseShard(spec string) (index, total int, err error) {
+	i, n, ok := strings.Cut(spec, "/")
+	if ok {
+		index, err = strconv.Atoi(i)
+		if err == nil {
+			total, err = strconv.Atoi(n)
+		}
+	}
+	if !ok || err != nil || total < 1 || index < 1 || index > total {
+		return 0, 0, fmt.Errorf("invalid shard %q (expected i/n with 1 <= i <= n)", spec)
+	}
+	return index, total, nil
+}
+
+// PlanShards splits targetIDs into n shards of roughly equal estimated
+// duration. Targets are placed longest first onto the shard with the least
+// work so far; targets without a duration are estimated at the average of
+// those with one. Without durations the targets are dealt out round-robin in
+// sorted order. The result depends only on the inputs, so every machine
+// given the same targets and durations computes the same plan.
+func PlanShards(targetIDs []string, durations map[string]time.Duration, n int) []Shard {
+	weights := make(map[string]time.Duration, len(targetIDs))
+
+	var known time.Duration
+	var count int
+	for _, id := range targetIDs {
+		if d, ok := durations[id]; ok && d > 0 {
+			known += d
+			count++
+		}
+	}
+	fallback := defaultShardWeight
+	if count > 0 {
+		fallback = known / time.Duration(count)
+	}
+
+	ids := make([]string, 0, len(targetIDs))
+	for _, id := range targetIDs {
+		if _, ok := weights[id]; ok {
+			continue
+		}
+		weights[id] = fallback
+		if d, ok := durations[id]; ok && d > 0 {
+			weights[id] = d
+		}
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		if weights[ids[i]] != weights[ids[j]] {
+			return weights[ids[i]] > weights[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	shards := make([]Shard, n)
+	for i := range shards {
+		shards[i] = Shard{Index: i + 1, Targets: []string{}}
+	}
+
+	for _, id := range ids {
+		lightest := 0
+		for i := 1; i < n; i++ {
+			if shards[i].Estimated < shards[lightest].Estimated {
+				lightest = i
+			}
+		}
+		shards[lightest].Targets = append(shards[lightest].Targets, id)
+		shards[lightest].Estimated += weights[id]
+	}
+
+	for i := range shards {
+		sort.Strings(shards[i].Targets)
+	}
+	return shards
+}
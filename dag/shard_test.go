@@ -0,0 +1,118 @@
+package dag
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseShard(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		index    int
+		total    int
+		hasError bool
+	}{
+		{name: "first of one", spec: "1/1", index: 1, total: 1},
+		{name: "middle", spec: "2/5", index: 2, total: 5},
+		{name: "zero index", spec: "0/5", hasError: true},
+		{name: "index past total", spec: "6/5", hasError: true},
+		{name: "zero total", spec: "1/0", hasError: true},
+		{name: "missing slash", spec: "2", hasError: true},
+		{name: "not a number", spec: "a/b", hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, total, err := ParseShard(tt.spec)
+			if tt.hasError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.index, index)
+			assert.Equal(t, tt.total, total)
+		})
+	}
+}
+
+func TestPlanShards(t *testing.T) {
+	tests := []struct {
+		name      string
+		targetIDs []string
+		durations map[string]time.Duration
+		n         int
+		expected  [][]string
+		estimated []time.Duration
+	}{
+		{
+			name:      "balances by duration",
+			targetIDs: []string{"a:t", "b:t", "c:t", "d:t"},
+			durations: map[string]time.Duration{
+				"a:t": 8 * time.Second,
+				"b:t": 5 * time.Second,
+				"c:t": 4 * time.Second,
+				"d:t": 3 * time.Second,
+			},
+			n:         2,
+			expected:  [][]string{{"a:t", "d:t"}, {"b:t", "c:t"}},
+			estimated: []time.Duration{11 * time.Second, 9 * time.Second},
+		},
+		{
+			name:      "unknown durations use the average",
+			targetIDs: []string{"a:t", "b:t", "c:t"},
+			durations: map[string]time.Duration{
+				"a:t": 4 * time.Second,
+				"b:t": 2 * time.Second,
+			},
+			n:         2,
+			expected:  [][]string{{"a:t"}, {"b:t", "c:t"}},
+			estimated: []time.Duration{4 * time.Second, 5 * time.Second},
+		},
+		{
+			name:      "no history splits by name",
+			targetIDs: []string{"c:t", "a:t", "b:t"},
+			n:         2,
+			expected:  [][]string{{"a:t", "c:t"}, {"b:t"}},
+			estimated: []time.Duration{2 * time.Second, time.Second},
+		},
+		{
+			name:      "more shards than targets",
+			targetIDs: []string{"a:t"},
+			n:         3,
+			expected:  [][]string{{"a:t"}, {}, {}},
+			estimated: []time.Duration{time.Second, 0, 0},
+		},
+		{
+			name:      "duplicates are planned once",
+			targetIDs: []string{"a:t", "a:t"},
+			n:         1,
+			expected:  [][]string{{"a:t"}},
+			estimated: []time.Duration{time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shards := PlanShards(tt.targetIDs, tt.durations, tt.n)
+			require.Len(t, shards, tt.n)
+			for i, s := range shards {
+				assert.Equal(t, i+1, s.Index)
+				assert.Equal(t, tt.expected[i], s.Targets)
+				assert.Equal(t, tt.estimated[i], s.Estimated)
+			}
+		})
+	}
+}
+
+func TestPlanShards_OrderIndependent(t *testing.T) {
+	durations := map[string]time.Duration{"a:t": time.Second, "b:t": time.Second, "c:t": 2 * time.Second}
+
+	first := PlanShards([]string{"a:t", "b:t", "c:t"}, durations, 2)
+	second := PlanShards([]string{"c:t", "b:t", "a:t"}, durations, 2)
+
+	assert.Equal(t, first, second)
+}
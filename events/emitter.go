@@ -0,0 +1,202 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vcnkl/rpm/models"
+)
+
+// Emitter writes events as JSON lines. A nil *Emitter is valid and discards
+// everything, so callers do not need to check whether --events was given.
+type Emitter struct {
+	w     io.Writer
+	c     io.Closer
+	runID string
+	mu    sync.Mutex
+}
+
+func NewEmitter(w io.Writer, runID string) *Emitter {
+	return &Emitter{
+		w:     w,
+		runID: runID,
+	}
+}
+
+// Open creates an emitter for dest, which is either a file path or "-" for
+// stdout.
+func Open(dest string, runID string) (*Emitter, error) {
+	if dest == "-" {
+		return NewEmitter(os.Stdout, runID), nil
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create events file %s: %w", dest, err)
+	}
+
+	e := NewEmitter(f, runID)
+	e.c = f
+	return e, nil
+}
+
+func (e *Emitter) Close() error {
+	if e == nil || e.c == nil {
+		return nil
+	}
+	return e.c.Close()
+}
+
+func (e *Emitter) RunStarted(command string, args []string, targets []string) {
+	if e == nil {
+		return
+	}
+	e.emit(&RunStarted{
+		Header:  e.header(TypeRunStarted),
+		Command: command,
+		Args:    nonNil(args),
+		Targets: nonNil(targets),
+	})
+}
+
+func (e *Emitter) TargetQueued(target string, deps []string) {
+	if e == nil {
+		return
+	}
+	e.emit(&TargetQueued{
+		Header: e.header(TypeTargetQueued),
+		Target: target,
+		Deps:   nonNil(deps),
+	})
+}
+
+func (e *Emitter) Cache(target string, hit bool, inputHash string) {
+	if e == nil {
+		return
+	}
+	typ := TypeCacheMiss
+	if hit {
+		typ = TypeCacheHit
+	}
+	e.emit(&Cache{
+		Header:    e.header(typ),
+		Target:    target,
+		InputHash: inputHash,
+	})
+}
+
+func (e *Emitter) CommandStarted(target, command, workDir string, pid int) {
+	if e == nil {
+		return
+	}
+	e.emit(&CommandStarted{
+		Header:  e.header(TypeCommandStarted),
+		Target:  target,
+		Command: command,
+		WorkDir: workDir,
+		PID:     pid,
+	})
+}
+
+func (e *Emitter) Output(target, stream, line string) {
+	if e == nil {
+		return
+	}
+	e.emit(&Output{
+		Header: e.header(TypeOutput),
+		Target: target,
+		Stream: stream,
+		Line:   line,
+	})
+}
+
+func (e *Emitter) TargetFinished(target string, status Status, exitCode int, duration time.Duration, err error) {
+	if e == nil {
+		return
+	}
+	ev := &TargetFinished{
+		Header:     e.header(TypeTargetFinished),
+		Target:     target,
+		Status:     status,
+		ExitCode:   exitCode,
+		DurationMs: duration.Milliseconds(),
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	e.emit(ev)
+}
+
+func (e *Emitter) RunFinished(result *models.Result) {
+	if e == nil {
+		return
+	}
+	ev := &RunFinished{
+		Header:     e.header(TypeRunFinished),
+		Status:     StatusSuccess,
+		Executed:   nonNil(result.Executed),
+		Skipped:    nonNil(result.Skipped),
+		Failed:     make([]FailedTarget, 0, len(result.Failed)),
+		DurationMs: result.Duration.Milliseconds(),
+	}
+	for _, f := range result.Failed {
+		ft := FailedTarget{ID: f.ID, ExitCode: f.ExitCode}
+		if f.Error != nil {
+			ft.Error = f.Error.Error()
+		}
+		ev.Failed = append(ev.Failed, ft)
+	}
+	if len(ev.Failed) > 0 {
+		ev.Status = StatusFailed
+	}
+	e.emit(ev)
+}
+
+// RunError ends the stream of a run that failed with err before producing a
+// result.
+func (e *Emitter) RunError(err error, duration time.Duration) {
+	if e == nil {
+		return
+	}
+	e.emit(&RunFinished{
+		Header:     e.header(TypeRunFinished),
+		Status:     StatusError,
+		Executed:   []string{},
+		Skipped:    []string{},
+		Failed:     []FailedTarget{},
+		DurationMs: duration.Milliseconds(),
+		Error:      err.Error(),
+	})
+}
+
+func (e *Emitter) header(typ Type) Header {
+	return Header{
+		Version: SchemaVersion,
+		Type:    typ,
+		Time:    time.Now().UTC(),
+		RunID:   e.runID,
+	}
+}
+
+func (e *Emitter) emit(ev any) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, _ = e.w.Write(append(data, '\n'))
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,203 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vcnkl/rpm/models"
+)
+
+func decodeLines(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var m map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &m))
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestEmitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		emit     func(e *Emitter)
+		expected map[string]any
+	}{
+		{
+			name: "run started",
+			emit: func(e *Emitter) { e.RunStarted("build", nil, []string{"a:lib_build"}) },
+			expected: map[string]any{
+				"type":    "run_started",
+				"command": "build",
+				"args":    []any{},
+				"targets": []any{"a:lib_build"},
+			},
+		},
+		{
+			name: "target queued",
+			emit: func(e *Emitter) { e.TargetQueued("b:lib_build", []string{"a:lib_build"}) },
+			expected: map[string]any{
+				"type":   "target_queued",
+				"target": "b:lib_build",
+				"deps":   []any{"a:lib_build"},
+			},
+		},
+		{
+			name: "cache hit",
+			emit: func(e *Emitter) { e.Cache("a:lib_build", true, "sha256:abc") },
+			expected: map[string]any{
+				"type":       "cache_hit",
+				"target":     "a:lib_build",
+				"input_hash": "sha256:abc",
+			},
+		},
+		{
+			name: "cache miss",
+			emit: func(e *Emitter) { e.Cache("a:lib_build", false, "sha256:abc") },
+			expected: map[string]any{
+				"type":       "cache_miss",
+				"target":     "a:lib_build",
+				"input_hash": "sha256:abc",
+			},
+		},
+		{
+			name: "command started",
+			emit: func(e *Emitter) { e.CommandStarted("a:lib_build", "make", "/repo/a", 42) },
+			expected: map[string]any{
+				"type":     "command_started",
+				"target":   "a:lib_build",
+				"command":  "make",
+				"work_dir": "/repo/a",
+				"pid":      float64(42),
+			},
+		},
+		{
+			name: "output",
+			emit: func(e *Emitter) { e.Output("a:lib_build", "stderr", "warning: x") },
+			expected: map[string]any{
+				"type":   "output",
+				"target": "a:lib_build",
+				"stream": "stderr",
+				"line":   "warning: x",
+			},
+		},
+		{
+			name: "target finished with error",
+			emit: func(e *Emitter) {
+				e.TargetFinished("a:app_test", StatusFailed, 2, 1500*time.Millisecond, errors.New("boom"))
+			},
+			expected: map[string]any{
+				"type":        "target_finished",
+				"target":      "a:app_test",
+				"status":      "failed",
+				"exit_code":   float64(2),
+				"duration_ms": float64(1500),
+				"error":       "boom",
+			},
+		},
+		{
+			name: "target finished successfully omits error",
+			emit: func(e *Emitter) {
+				e.TargetFinished("a:lib_build", StatusSuccess, 0, time.Second, nil)
+			},
+			expected: map[string]any{
+				"type":        "target_finished",
+				"target":      "a:lib_build",
+				"status":      "success",
+				"exit_code":   float64(0),
+				"duration_ms": float64(1000),
+			},
+		},
+		{
+			name: "run finished",
+			emit: func(e *Emitter) {
+				e.RunFinished(&models.Result{
+					Executed: []string{"a:lib_build"},
+					Failed:   []models.FailedTarget{{ID: "a:app_test", Error: errors.New("boom"), ExitCode: 1}},
+					Duration: 2 * time.Second,
+				})
+			},
+			expected: map[string]any{
+				"type":        "run_finished",
+				"status":      "failed",
+				"executed":    []any{"a:lib_build"},
+				"skipped":     []any{},
+				"failed":      []any{map[string]any{"id": "a:app_test", "error": "boom", "exit_code": float64(1)}},
+				"duration_ms": float64(2000),
+			},
+		},
+		{
+			name: "run error",
+			emit: func(e *Emitter) { e.RunError(errors.New("dependency cycle"), time.Second) },
+			expected: map[string]any{
+				"type":        "run_finished",
+				"status":      "error",
+				"executed":    []any{},
+				"skipped":     []any{},
+				"failed":      []any{},
+				"duration_ms": float64(1000),
+				"error":       "dependency cycle",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.emit(NewEmitter(&buf, "run-1"))
+
+			lines := decodeLines(t, buf.Bytes())
+			require.Len(t, lines, 1)
+
+			event := lines[0]
+			assert.Equal(t, float64(SchemaVersion), event["version"])
+			assert.Equal(t, "run-1", event["run_id"])
+			assert.NotEmpty(t, event["time"])
+			for key, value := range tt.expected {
+				assert.Equal(t, value, event[key], key)
+			}
+			_, hasError := event["error"]
+			_, expectError := tt.expected["error"]
+			assert.Equal(t, expectError, hasError)
+		})
+	}
+}
+
+func TestEmitter_Nil(t *testing.T) {
+	var e *Emitter
+	assert.NotPanics(t, func() {
+		e.RunStarted("build", nil, nil)
+		e.Output("a", "stdout", "x")
+		e.RunFinished(&models.Result{})
+		assert.NoError(t, e.Close())
+	})
+}
+
+func TestOpen_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	e, err := Open(path, "run-1")
+	require.NoError(t, err)
+	e.RunStarted("test", nil, nil)
+	e.RunFinished(&models.Result{})
+	require.NoError(t, e.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := decodeLines(t, data)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "run_started", lines[0]["type"])
+	assert.Equal(t, "run_finished", lines[1]["type"])
+}
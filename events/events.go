@@ -0,0 +1,116 @@
+// Package events defines the newline-delimited JSON event stream written by
+// --events. Every line is one JSON object starting with the Header fields; the
+// "type" field selects which of the event structs below describes the rest.
+//
+// SchemaVersion is bumped whenever a field is removed or changes meaning.
+// Adding fields or event types does not change the version, so consumers
+// should ignore what they do not recognize.
+package events
+
+import (
+	"time"
+)
+
+const SchemaVersion = 1
+
+type Type string
+
+const (
+	TypeRunStarted     Type = "run_started"
+	TypeTargetQueued   Type = "target_queued"
+	TypeCacheHit       Type = "cache_hit"
+	TypeCacheMiss      Type = "cache_miss"
+	TypeCommandStarted Type = "command_started"
+	TypeOutput         Type = "output"
+	TypeTargetFinished Type = "target_finished"
+	TypeRunFinished    Type = "run_finished"
+)
+
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+	// StatusError is the status of a run that stopped before running its
+	// targets, such as on a dependency cycle.
+	StatusError Status = "error"
+)
+
+type Header struct {
+	Version int       `json:"version"`
+	Type    Type      `json:"type"`
+	Time    time.Time `json:"time"`
+	RunID   string    `json:"run_id"`
+}
+
+// RunStarted is the first event of every stream.
+type RunStarted struct {
+	Header
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Targets []string `json:"targets"`
+}
+
+// TargetQueued is emitted once per target, in dependency order, before any
+// command runs.
+type TargetQueued struct {
+	Header
+	Target string   `json:"target"`
+	Deps   []string `json:"deps"`
+}
+
+// Cache is emitted with type cache_hit or cache_miss after a build target's
+// inputs have been hashed.
+type Cache struct {
+	Header
+	Target    string `json:"target"`
+	InputHash string `json:"input_hash"`
+}
+
+type CommandStarted struct {
+	Header
+	Target  string `json:"target"`
+	Command string `json:"command"`
+	WorkDir string `json:"work_dir"`
+	PID     int    `json:"pid"`
+}
+
+// Output carries one line of command output with secrets already masked.
+type Output struct {
+	Header
+	Target string `json:"target"`
+	Stream string `json:"stream"`
+	Line   string `json:"line"`
+}
+
+// TargetFinished reports the outcome of one target. ExitCode is -1 when the
+// command did not run to completion (cancelled, failed to start, dependency
+// failed).
+type TargetFinished struct {
+	Header
+	Target     string `json:"target"`
+	Status     Status `json:"status"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+type FailedTarget struct {
+	ID       string `json:"id"`
+	Error    string `json:"error"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// RunFinished is the last event of every stream and mirrors models.Result.
+// Status is failed when any target failed, and error with Error set when the
+// run could not be executed at all.
+type RunFinished struct {
+	Header
+	Status     Status         `json:"status"`
+	Executed   []string       `json:"executed"`
+	Skipped    []string       `json:"skipped"`
+	Failed     []FailedTarget `json:"failed"`
+	DurationMs int64          `json:"duration_ms"`
+	Error      string         `json:"error,omitempty"`
+}
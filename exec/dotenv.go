@@ -0,0 +1,325 @@
+package exec
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var dotenvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+type LookupFunc func(key string) (string, bool)
+
+type DotenvError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *DotenvError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+func LoadDotenv(path string) (map[string]string, error) {
+	return LoadDotenvWithLookup(path, os.LookupEnv)
+}
+
+// LoadDotenvWithLookup parses the file at path, resolving ${VAR} references
+// against earlier keys in the same file first and lookup second.
+func LoadDotenvWithLookup(path string, lookup LookupFunc) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDotenv(path, string(data), lookup)
+}
+
+func ParseDotenv(name, content string, lookup LookupFunc) (map[string]string, error) {
+	p := &dotenvParser{
+		name:   name,
+		src:    content,
+		line:   1,
+		lookup: lookup,
+		vars:   make(map[string]string),
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.vars, nil
+}
+
+type dotenvParser struct {
+	name   string
+	src    string
+	pos    int
+	line   int
+	lookup LookupFunc
+	vars   map[string]string
+}
+
+func (p *dotenvParser) errorf(line int, format string, args ...any) error {
+	return &DotenvError{File: p.name, Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *dotenvParser) parse() error {
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.src) {
+			return nil
+		}
+
+		if p.src[p.pos] == '#' {
+			p.restOfLine()
+			continue
+		}
+
+		if err := p.parseAssignment(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *dotenvParser) parseAssignment() error {
+	startLine := p.line
+
+	end := p.pos
+	for end < len(p.src) && p.src[end] != '=' && p.src[end] != '\n' {
+		end++
+	}
+	if end >= len(p.src) || p.src[end] != '=' {
+		return p.errorf(startLine, "expected KEY=VALUE, got %q", strings.TrimSpace(p.src[p.pos:end]))
+	}
+
+	key := strings.TrimSpace(p.src[p.pos:end])
+	if rest, ok := strings.CutPrefix(key, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+		key = strings.TrimSpace(rest)
+	}
+	if !dotenvKeyPattern.MatchString(key) {
+		return p.errorf(startLine, "invalid variable name %q", key)
+	}
+
+	p.pos = end + 1
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+
+	var value string
+	var err error
+	if p.pos < len(p.src) && (p.src[p.pos] == '"' || p.src[p.pos] == '\'') {
+		value, err = p.parseQuoted(startLine)
+	} else {
+		value, err = p.parseUnquoted(startLine)
+	}
+	if err != nil {
+		return err
+	}
+
+	p.vars[key] = value
+	return nil
+}
+
+func (p *dotenvParser) parseQuoted(startLine int) (string, error) {
+	quote := p.src[p.pos]
+	p.pos++
+
+	start := p.pos
+	for {
+		if p.pos >= len(p.src) {
+			if quote == '"' {
+				return "", p.errorf(startLine, "unterminated double-quoted value")
+			}
+			return "", p.errorf(startLine, "unterminated single-quoted value")
+		}
+		c := p.src[p.pos]
+		if c == '\n' {
+			p.line++
+		}
+		if c == '\\' && quote == '"' && p.pos+1 < len(p.src) {
+			if p.src[p.pos+1] == '\n' {
+				p.line++
+			}
+			p.pos += 2
+			continue
+		}
+		if c == quote {
+			break
+		}
+		p.pos++
+	}
+	raw := p.src[start:p.pos]
+	p.pos++
+
+	closeLine := p.line
+	rest := p.restOfLine()
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", p.errorf(closeLine, "unexpected %q after closing quote", rest)
+	}
+
+	if quote == '\'' {
+		return raw, nil
+	}
+	return p.expand(raw, startLine, true)
+}
+
+func (p *dotenvParser) parseUnquoted(startLine int) (string, error) {
+	raw := p.restOfLine()
+
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && (i == 0 || raw[i-1] == ' ' || raw[i-1] == '\t') {
+			raw = raw[:i]
+			break
+		}
+	}
+
+	return p.expand(strings.TrimSpace(raw), startLine, false)
+}
+
+// expand resolves escapes and variable references. Double-quoted values
+// accept \n, \r, \t, \", \\ and \$; unquoted values only \$.
+func (p *dotenvParser) expand(s string, line int, doubleQuoted bool) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if c == '\\' && i+1 < len(s) {
+			next := s[i+1]
+			if next == '$' {
+				b.WriteByte('$')
+				i++
+				continue
+			}
+			if doubleQuoted {
+				switch next {
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				case 't':
+					b.WriteByte('\t')
+				case '"', '\\':
+					b.WriteByte(next)
+				case '\n':
+				default:
+					b.WriteByte(c)
+					b.WriteByte(next)
+				}
+				i++
+				continue
+			}
+		}
+
+		if c != '$' || i+1 >= len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := closingBrace(s[i+2:])
+			if end == -1 {
+				return "", p.errorf(line, "unterminated variable reference %q", s[i:])
+			}
+			value, err := p.resolveBraced(s[i+2:i+2+end], line)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i += end + 2
+			continue
+		}
+
+		j := i + 1
+		for j < len(s) && isNameChar(s[j], j == i+1) {
+			j++
+		}
+		if j == i+1 {
+			b.WriteByte(c)
+			continue
+		}
+		value, _ := p.resolve(s[i+1 : j])
+		b.WriteString(value)
+		i = j - 1
+	}
+
+	return b.String(), nil
+}
+
+// resolveBraced handles the body of ${...}: NAME, NAME:-default (used when
+// unset or empty) and NAME-default (used when unset).
+func (p *dotenvParser) resolveBraced(ref string, line int) (string, error) {
+	name := ref
+	fallback := ""
+	useOnEmpty := false
+	hasFallback := false
+
+	if idx := strings.Index(ref, ":-"); idx != -1 {
+		name, fallback, useOnEmpty, hasFallback = ref[:idx], ref[idx+2:], true, true
+	} else if idx = strings.IndexByte(ref, '-'); idx != -1 {
+		name, fallback, hasFallback = ref[:idx], ref[idx+1:], true
+	}
+
+	if !dotenvKeyPattern.MatchString(name) {
+		return "", p.errorf(line, "invalid variable reference ${%s}", ref)
+	}
+
+	value, ok := p.resolve(name)
+	if hasFallback && (!ok || (useOnEmpty && value == "")) {
+		return p.expand(fallback, line, false)
+	}
+	return value, nil
+}
+
+func (p *dotenvParser) resolve(name string) (string, bool) {
+	if v, ok := p.vars[name]; ok {
+		return v, true
+	}
+	if p.lookup != nil {
+		return p.lookup(name)
+	}
+	return "", false
+}
+
+func (p *dotenvParser) restOfLine() string {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *dotenvParser) skipBlank() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\n':
+			p.line++
+		case ' ', '\t', '\r':
+		default:
+			return
+		}
+		p.pos++
+	}
+}
+
+func closingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func isNameChar(c byte, first bool) bool {
+	if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		return true
+	}
+	return !first && c >= '0' && c <= '9'
+}
@@ -0,0 +1,186 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		env      map[string]string
+		expected map[string]string
+	}{
+		{
+			name:     "export prefix",
+			content:  "export FOO=bar\nexport\tBAZ=qux",
+			expected: map[string]string{"FOO": "bar", "BAZ": "qux"},
+		},
+		{
+			name:     "inline comments on unquoted values",
+			content:  "FOO=bar # trailing comment\nBAZ=qux#not-a-comment\nEMPTY= # nothing",
+			expected: map[string]string{"FOO": "bar", "BAZ": "qux#not-a-comment", "EMPTY": ""},
+		},
+		{
+			name:     "inline comment after quoted value",
+			content:  `FOO="bar # baz" # comment`,
+			expected: map[string]string{"FOO": "bar # baz"},
+		},
+		{
+			name:     "double quoted escapes",
+			content:  `FOO="line1\nline2\ttab \"quoted\" back\\slash \$HOME"`,
+			expected: map[string]string{"FOO": "line1\nline2\ttab \"quoted\" back\\slash $HOME"},
+		},
+		{
+			name:     "single quoted values are literal",
+			content:  `FOO='${BAR}\n'`,
+			env:      map[string]string{"BAR": "x"},
+			expected: map[string]string{"FOO": `${BAR}\n`},
+		},
+		{
+			name:     "multi-line double quoted value",
+			content:  "KEY=\"-----BEGIN-----\nabc\n-----END-----\"\nNEXT=1",
+			expected: map[string]string{"KEY": "-----BEGIN-----\nabc\n-----END-----", "NEXT": "1"},
+		},
+		{
+			name:     "multi-line single quoted value",
+			content:  "KEY='a\nb'",
+			expected: map[string]string{"KEY": "a\nb"},
+		},
+		{
+			name:     "references to earlier keys",
+			content:  "HOST=localhost\nPORT=8080\nURL=http://${HOST}:$PORT/api",
+			expected: map[string]string{"HOST": "localhost", "PORT": "8080", "URL": "http://localhost:8080/api"},
+		},
+		{
+			name:     "references to lookup env",
+			content:  `DATA="${BUNDLE_ROOT}/data"`,
+			env:      map[string]string{"BUNDLE_ROOT": "/repo/core"},
+			expected: map[string]string{"DATA": "/repo/core/data"},
+		},
+		{
+			name:     "file keys shadow lookup env",
+			content:  "NAME=local\nGREETING=hello-$NAME",
+			env:      map[string]string{"NAME": "global"},
+			expected: map[string]string{"NAME": "local", "GREETING": "hello-local"},
+		},
+		{
+			name:     "undefined reference expands to empty",
+			content:  "FOO=a${MISSING}b",
+			expected: map[string]string{"FOO": "ab"},
+		},
+		{
+			name:     "default values",
+			content:  "A=${UNSET:-fallback}\nB=${EMPTY:-fallback}\nC=${EMPTY-fallback}\nD=${UNSET-${OTHER}}",
+			env:      map[string]string{"EMPTY": "", "OTHER": "other"},
+			expected: map[string]string{"A": "fallback", "B": "fallback", "C": "", "D": "other"},
+		},
+		{
+			name:     "escaped dollar in unquoted value",
+			content:  `PRICE=\$5`,
+			expected: map[string]string{"PRICE": "$5"},
+		},
+		{
+			name:     "lone dollar kept",
+			content:  "FOO=cost $ 5",
+			expected: map[string]string{"FOO": "cost $ 5"},
+		},
+		{
+			name:     "crlf line endings",
+			content:  "FOO=bar\r\nBAZ=\"qux\"\r\n",
+			expected: map[string]string{"FOO": "bar", "BAZ": "qux"},
+		},
+		{
+			name:     "dotted keys",
+			content:  "spring.profile=dev",
+			expected: map[string]string{"spring.profile": "dev"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			}
+
+			result, err := ParseDotenv(".env", tt.content, lookup)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParseDotenv_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		expectedLine int
+		errorMsg     string
+	}{
+		{
+			name:         "missing equals",
+			content:      "FOO=bar\n\nBROKEN\n",
+			expectedLine: 3,
+			errorMsg:     "expected KEY=VALUE",
+		},
+		{
+			name:         "invalid key",
+			content:      "MY KEY=value",
+			expectedLine: 1,
+			errorMsg:     "invalid variable name",
+		},
+		{
+			name:         "empty key",
+			content:      "=value",
+			expectedLine: 1,
+			errorMsg:     "invalid variable name",
+		},
+		{
+			name:         "unterminated double quote",
+			content:      "A=1\nFOO=\"bar\nBAZ=qux",
+			expectedLine: 2,
+			errorMsg:     "unterminated double-quoted value",
+		},
+		{
+			name:         "unterminated single quote",
+			content:      "FOO='bar",
+			expectedLine: 1,
+			errorMsg:     "unterminated single-quoted value",
+		},
+		{
+			name:         "trailing characters after quote",
+			content:      `FOO="bar"baz`,
+			expectedLine: 1,
+			errorMsg:     "after closing quote",
+		},
+		{
+			name:         "unterminated reference",
+			content:      "FOO=${BAR",
+			expectedLine: 1,
+			errorMsg:     "unterminated variable reference",
+		},
+		{
+			name:         "line counted after multi-line value",
+			content:      "KEY=\"a\nb\"\nBROKEN",
+			expectedLine: 3,
+			errorMsg:     "expected KEY=VALUE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDotenv("apps/api/.env", tt.content, nil)
+			require.Error(t, err)
+
+			var dotenvErr *DotenvError
+			require.ErrorAs(t, err, &dotenvErr)
+			assert.Equal(t, "apps/api/.env", dotenvErr.File)
+			assert.Equal(t, tt.expectedLine, dotenvErr.Line)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
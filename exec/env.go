@@ -1,7 +1,7 @@
 package exec
 
 import (
-	"bufio"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,33 +10,66 @@ import (
 	"github.com/vcnkl/rpm/models"
 )
 
-func ComposeEnv(repoRoot string, repo *config.RepoConfig, bundle *models.Bundle, target *models.Target) []string {
+// SecretResolver returns the values of the secrets defined in repo.yml.
+type SecretResolver interface {
+	Resolve(name string) (string, error)
+}
+
+func ComposeEnv(repoRoot string, repo *config.RepoConfig, bundle *models.Bundle, target *models.Target, resolver SecretResolver) ([]string, error) {
 	env := os.Environ()
 
-	for k, v := range repo.Env {
+	vars := make(map[string]string, len(env))
+	for _, e := range env {
+		if idx := strings.Index(e, "="); idx != -1 {
+			vars[e[:idx]] = e[idx+1:]
+		}
+	}
+
+	set := func(k, v string) {
 		env = append(env, k+"="+v)
+		vars[k] = v
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+
+	for k, v := range repo.Env {
+		set(k, v)
 	}
 
-	env = append(env, "REPO_ROOT="+repoRoot)
+	set("REPO_ROOT", repoRoot)
 
 	bundleRoot := filepath.Join(repoRoot, bundle.Path)
-	env = append(env, "BUNDLE_ROOT="+bundleRoot)
+	set("BUNDLE_ROOT", bundleRoot)
 
 	for k, v := range bundle.Env {
-		env = append(env, k+"="+v)
+		set(k, v)
 	}
 
 	for k, v := range target.Env {
-		env = append(env, k+"="+v)
+		set(k, v)
+	}
+
+	for _, name := range target.Secrets {
+		if resolver == nil {
+			return nil, fmt.Errorf("secret %s requested without a secrets resolver", name)
+		}
+		value, err := resolver.Resolve(name)
+		if err != nil {
+			return nil, err
+		}
+		set(name, value)
 	}
 
 	if target.Config.Dotenv.Enabled {
 		dotenvPath := filepath.Join(bundleRoot, ".env")
-		dotenvVars, err := LoadDotenv(dotenvPath)
-		if err == nil {
-			for k, v := range dotenvVars {
-				env = append(env, k+"="+v)
-			}
+		dotenvVars, err := LoadDotenvWithLookup(dotenvPath, lookup)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for k, v := range dotenvVars {
+			set(k, v)
 		}
 
 		for _, file := range target.Config.Dotenv.Files {
@@ -46,55 +79,18 @@ func ComposeEnv(repoRoot string, repo *config.RepoConfig, bundle *models.Bundle,
 				matches = []string{pattern}
 			}
 			for _, filePath := range matches {
-				fileVars, err := LoadDotenv(filePath)
-				if err == nil {
-					for k, v := range fileVars {
-						env = append(env, k+"="+v)
-					}
+				fileVars, err := LoadDotenvWithLookup(filePath, lookup)
+				if err != nil && !os.IsNotExist(err) {
+					return nil, err
+				}
+				for k, v := range fileVars {
+					set(k, v)
 				}
 			}
 		}
 	}
 
-	return env
-}
-
-func LoadDotenv(path string) (map[string]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	result := make(map[string]string)
-	scanner := bufio.NewScanner(file)
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		idx := strings.Index(line, "=")
-		if idx == -1 {
-			continue
-		}
-
-		key := strings.TrimSpace(line[:idx])
-		value := strings.TrimSpace(line[idx+1:])
-
-		if len(value) >= 2 {
-			if (value[0] == '"' && value[len(value)-1] == '"') ||
-				(value[0] == '\'' && value[len(value)-1] == '\'') {
-				value = value[1 : len(value)-1]
-			}
-		}
-
-		result[key] = value
-	}
-
-	return result, scanner.Err()
+	return env, nil
 }
 
 func MergeEnv(base, override []string) []string {
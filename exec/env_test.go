@@ -77,13 +77,9 @@ func TestLoadDotenv(t *testing.T) {
 			expectError: false,
 		},
 		{
-			name:    "line without equals ignored",
-			content: "FOO=bar\nINVALID_LINE\nBAZ=qux",
-			expected: map[string]string{
-				"FOO": "bar",
-				"BAZ": "qux",
-			},
-			expectError: false,
+			name:        "line without equals rejected",
+			content:     "FOO=bar\nINVALID_LINE\nBAZ=qux",
+			expectError: true,
 		},
 		{
 			name:    "empty value",
@@ -276,7 +272,8 @@ func TestComposeEnv(t *testing.T) {
 				tt.expectedEnvs["BUNDLE_ROOT"] = bundlePath
 			}
 
-			result := ComposeEnv(repoRoot, tt.repo, tt.bundle, tt.target)
+			result, err := ComposeEnv(repoRoot, tt.repo, tt.bundle, tt.target, nil)
+			require.NoError(t, err)
 
 			resultMap := make(map[string]string)
 			for _, e := range result {
@@ -296,3 +293,61 @@ func TestComposeEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestComposeEnv_DotenvInterpolation(t *testing.T) {
+	tmpDir := t.TempDir()
+	bundlePath := filepath.Join(tmpDir, "apps/api")
+	require.NoError(t, os.MkdirAll(bundlePath, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(bundlePath, ".env"),
+		[]byte("DATABASE_URL=postgres://${DB_HOST}:${DB_PORT:-5432}/${PROJECT}\nDATA_DIR=$BUNDLE_ROOT/data"), 0644))
+
+	repo := &config.RepoConfig{Env: map[string]string{"PROJECT": "sample"}}
+	bundle := &models.Bundle{
+		Name: "api",
+		Path: "apps/api",
+		Env:  map[string]string{"DB_HOST": "localhost"},
+	}
+	target := &models.Target{
+		Name:       "server_dev",
+		BundleName: "api",
+		BundlePath: "apps/api",
+		Config:     models.TargetConfig{Dotenv: models.DotenvConfig{Enabled: true}},
+	}
+
+	result, err := ComposeEnv(tmpDir, repo, bundle, target, nil)
+	require.NoError(t, err)
+
+	resultMap := make(map[string]string)
+	for _, e := range result {
+		idx := indexOf(e, "=")
+		if idx != -1 {
+			resultMap[e[:idx]] = e[idx+1:]
+		}
+	}
+
+	assert.Equal(t, "postgres://localhost:5432/sample", resultMap["DATABASE_URL"])
+	assert.Equal(t, bundlePath+"/data", resultMap["DATA_DIR"])
+}
+
+func TestComposeEnv_MalformedDotenv(t *testing.T) {
+	tmpDir := t.TempDir()
+	bundlePath := filepath.Join(tmpDir, "core")
+	require.NoError(t, os.MkdirAll(bundlePath, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(bundlePath, ".env"), []byte("FOO=bar\nnot a pair\n"), 0644))
+
+	bundle := &models.Bundle{Name: "core", Path: "core"}
+	target := &models.Target{
+		Name:       "app_build",
+		BundleName: "core",
+		BundlePath: "core",
+		Config:     models.TargetConfig{Dotenv: models.DotenvConfig{Enabled: true}},
+	}
+
+	_, err := ComposeEnv(tmpDir, &config.RepoConfig{}, bundle, target, nil)
+	require.Error(t, err)
+
+	var dotenvErr *DotenvError
+	require.ErrorAs(t, err, &dotenvErr)
+	assert.Equal(t, filepath.Join(bundlePath, ".env"), dotenvErr.File)
+	assert.Equal(t, 2, dotenvErr.Line)
+}
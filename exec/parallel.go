@@ -3,6 +3,7 @@ package exec
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/vcnkl/rpm/dag"
 )
@@ -20,6 +21,25 @@ func NewParallelExecutor(maxWorkers int) *ParallelExecutor {
 
 type TaskFunc func(ctx context.Context, node *dag.Node) error
 
+// Task describes how a node was scheduled. Queued is when the executor picked
+// the node up, Ready when its dependencies had all completed and Started when
+// it was given worker Slot (0-based, below maxWorkers).
+type Task struct {
+	Slot    int
+	Queued  time.Time
+	Ready   time.Time
+	Started time.Time
+}
+
+type taskKey struct{}
+
+// TaskFromContext returns the scheduling information of the task running with
+// ctx, as passed to a TaskFunc.
+func TaskFromContext(ctx context.Context) (Task, bool) {
+	task, ok := ctx.Value(taskKey{}).(Task)
+	return task, ok
+}
+
 func (p *ParallelExecutor) Execute(ctx context.Context, nodes []*dag.Node, fn TaskFunc) map[string]error {
 	results := make(map[string]error)
 	var mu sync.Mutex
@@ -27,7 +47,10 @@ func (p *ParallelExecutor) Execute(ctx context.Context, nodes []*dag.Node, fn Ta
 	completed := make(map[string]bool)
 	var completedMu sync.Mutex
 
-	sem := make(chan struct{}, p.maxWorkers)
+	slots := make(chan int, p.maxWorkers)
+	for i := 0; i < p.maxWorkers; i++ {
+		slots <- i
+	}
 	var wg sync.WaitGroup
 
 	for _, node := range nodes {
@@ -35,6 +58,8 @@ func (p *ParallelExecutor) Execute(ctx context.Context, nodes []*dag.Node, fn Ta
 		go func(n *dag.Node) {
 			defer wg.Done()
 
+			task := Task{Queued: time.Now()}
+
 			for {
 				select {
 				case <-ctx.Done():
@@ -77,8 +102,10 @@ func (p *ParallelExecutor) Execute(ctx context.Context, nodes []*dag.Node, fn Ta
 				}
 			}
 
+			task.Ready = time.Now()
+
 			select {
-			case sem <- struct{}{}:
+			case task.Slot = <-slots:
 			case <-ctx.Done():
 				mu.Lock()
 				results[n.ID] = ctx.Err()
@@ -86,9 +113,10 @@ func (p *ParallelExecutor) Execute(ctx context.Context, nodes []*dag.Node, fn Ta
 				return
 			}
 
-			err := fn(ctx, n)
+			task.Started = time.Now()
+			err := fn(context.WithValue(ctx, taskKey{}, task), n)
 
-			<-sem
+			slots <- task.Slot
 
 			mu.Lock()
 			results[n.ID] = err
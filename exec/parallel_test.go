@@ -0,0 +1,45 @@
+package exec
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vcnkl/rpm/dag"
+	"github.com/vcnkl/rpm/models"
+)
+
+func TestParallelExecutor_TaskFromContext(t *testing.T) {
+	a := &dag.Node{ID: "a", Target: &models.Target{Name: "a"}}
+	b := &dag.Node{ID: "b", Target: &models.Target{Name: "b"}, Deps: []*dag.Node{a}}
+	c := &dag.Node{ID: "c", Target: &models.Target{Name: "c"}}
+
+	tasks := make(map[string]Task)
+	var mu sync.Mutex
+
+	results := NewParallelExecutor(2).Execute(context.Background(), []*dag.Node{a, b, c}, func(ctx context.Context, node *dag.Node) error {
+		task, ok := TaskFromContext(ctx)
+		require.True(t, ok)
+		mu.Lock()
+		tasks[node.ID] = task
+		mu.Unlock()
+		return nil
+	})
+
+	require.Len(t, results, 3)
+	require.Len(t, tasks, 3)
+	for id, task := range tasks {
+		assert.NoError(t, results[id])
+		assert.GreaterOrEqual(t, task.Slot, 0, id)
+		assert.Less(t, task.Slot, 2, id)
+		assert.False(t, task.Ready.Before(task.Queued), id)
+		assert.False(t, task.Started.Before(task.Ready), id)
+	}
+	assert.False(t, tasks["b"].Ready.Before(tasks["a"].Started), "b is ready only after a ran")
+
+	_, ok := TaskFromContext(context.Background())
+	assert.False(t, ok)
+}
@@ -2,14 +2,16 @@ package exec
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	osexec "os/exec"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/bitfield/script"
 	"github.com/vcnkl/rpm/models"
 )
 
@@ -20,13 +22,66 @@ type ShellOptions struct {
 	Stdout  io.Writer
 	Stderr  io.Writer
 	Timeout time.Duration
+	OnStart func(pid int)
+	// Stop says how the command is stopped when ctx is cancelled; the zero
+	// value means DefaultStopOptions.
+	Stop StopOptions
+	// Foreground keeps the command in the terminal's foreground process
+	// group with stdin attached, so it can prompt, instead of starting it in
+	// its own process group.
+	Foreground bool
 }
 
-func RunCommand(ctx context.Context, cmdStr string, opts *ShellOptions) error {
-	if opts.Shell == "" {
-		opts.Shell = "/usr/bin/env bash"
+// killGracePeriod is how long a cancelled command's process group has to exit
+// after SIGTERM before it is killed.
+const killGracePeriod = 5 * time.Second
+
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.Code)
+}
+
+// ExitCode returns the exit status carried by err, 0 for nil and -1 when the
+// command did not exit normally.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
 	}
+	return -1
+}
 
+// ShellCommand returns a command that runs cmdStr with opts.Shell -c in its
+// own process group, so the whole group can be signalled, unless
+// opts.Foreground is set.
+func ShellCommand(cmdStr string, opts *ShellOptions) *osexec.Cmd {
+	shell := opts.Shell
+	if shell == "" {
+		shell = "/usr/bin/env bash"
+	}
+	shellParts := strings.Fields(shell)
+	args := append(shellParts[1:], "-c", cmdStr)
+
+	cmd := osexec.Command(shellParts[0], args...)
+	cmd.Dir = opts.WorkDir
+	cmd.Env = opts.Env
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+	if opts.Foreground {
+		cmd.Stdin = os.Stdin
+	} else {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+	return cmd
+}
+
+func RunCommand(ctx context.Context, cmdStr string, opts *ShellOptions) error {
 	if opts.Stdout == nil {
 		opts.Stdout = os.Stdout
 	}
@@ -40,44 +95,75 @@ func RunCommand(ctx context.Context, cmdStr string, opts *ShellOptions) error {
 		defer cancel()
 	}
 
-	shellParts := strings.Fields(opts.Shell)
-	shellCmd := shellParts[0]
-	shellArgs := shellParts[1:]
-
-	wrappedCmd := cmdStr
-	if opts.WorkDir != "" {
-		wrappedCmd = fmt.Sprintf("cd %q && (\n%s\n)", opts.WorkDir, cmdStr)
+	cmd := ShellCommand(cmdStr, opts)
+	if err := cmd.Start(); err != nil {
+		return err
 	}
 
-	fullCmd := shellCmd
-	for _, arg := range shellArgs {
-		fullCmd += " " + arg
+	if opts.OnStart != nil {
+		opts.OnStart(cmd.Process.Pid)
 	}
-	fullCmd += " -c " + shellQuote(wrappedCmd)
 
-	done := make(chan error, 1)
+	var waitErr error
+	done := make(chan struct{})
 	go func() {
-		pipe := script.NewPipe().WithEnv(opts.Env)
-		pipe = pipe.Exec(fullCmd)
-		pipe = pipe.WithStdout(opts.Stdout).WithStderr(opts.Stderr)
-		_, err := pipe.Stdout()
-		exitStatus := pipe.ExitStatus()
-		if err == nil && exitStatus != 0 {
-			err = fmt.Errorf("command exited with status %d", exitStatus)
-		}
-		done <- err
+		waitErr = cmd.Wait()
+		close(done)
 	}()
 
 	select {
 	case <-ctx.Done():
+		stop := opts.Stop
+		if stop.Timeout == 0 {
+			stop = DefaultStopOptions
+		}
+		StopGroup(cmd.Process.Pid, done, stop)
 		return ctx.Err()
-	case err := <-done:
-		return err
+	case <-done:
+		return exitError(waitErr)
+	}
+}
+
+// ExitErr converts the result of waiting for a command into an *ExitError
+// when the command exited with a non-zero status.
+func ExitErr(err error) error {
+	return exitError(err)
+}
+
+func exitError(err error) error {
+	var exitErr *osexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return &ExitError{Code: exitErr.ExitCode()}
 	}
+	return err
 }
 
-func shellQuote(s string) string {
-	return "'" + strings.ReplaceAll(s, "'", "'\"'\"'") + "'"
+// RunInteractive runs cmdStr with the terminal attached directly to the child
+// so prompts and REPLs work. Interrupts reach the child through the terminal's
+// process group, so the call waits for the child to exit instead of returning
+// when ctx is cancelled.
+func RunInteractive(ctx context.Context, cmdStr string, opts *ShellOptions) error {
+	opts.Foreground = true
+	cmd := ShellCommand(cmdStr, opts)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	if opts.OnStart != nil {
+		opts.OnStart(cmd.Process.Pid)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return exitError(err)
+	}
+
+	return nil
 }
 
 func ResolveWorkDir(repoRoot string, target *models.Target) string {
@@ -1,9 +1,14 @@
 package exec
 
 import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/vcnkl/rpm/models"
 )
@@ -79,79 +84,96 @@ func TestResolveWorkDir(t *testing.T) {
 	}
 }
 
-func TestShellQuote(t *testing.T) {
+func TestDependencyFailedError(t *testing.T) {
 	tests := []struct {
 		name     string
-		input    string
+		targetID string
 		expected string
 	}{
 		{
-			name:     "simple string",
-			input:    "hello world",
-			expected: "'hello world'",
-		},
-		{
-			name:     "string with single quote",
-			input:    "it's working",
-			expected: "'it'\"'\"'s working'",
-		},
-		{
-			name:     "string with multiple single quotes",
-			input:    "it's Bob's code",
-			expected: "'it'\"'\"'s Bob'\"'\"'s code'",
-		},
-		{
-			name:     "empty string",
-			input:    "",
-			expected: "''",
-		},
-		{
-			name:     "string with special chars",
-			input:    "echo $HOME && rm -rf /",
-			expected: "'echo $HOME && rm -rf /'",
-		},
-		{
-			name:     "string with newlines",
-			input:    "line1\nline2",
-			expected: "'line1\nline2'",
+			name:     "simple target ID",
+			targetID: "core:app_build",
+			expected: "dependency failed for target: core:app_build",
 		},
 		{
-			name:     "string with double quotes",
-			input:    `say "hello"`,
-			expected: `'say "hello"'`,
+			name:     "empty target ID",
+			targetID: "",
+			expected: "dependency failed for target: ",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := shellQuote(tt.input)
-			assert.Equal(t, tt.expected, result)
+			err := &DependencyFailedError{TargetID: tt.targetID}
+			assert.Equal(t, tt.expected, err.Error())
 		})
 	}
 }
 
-func TestDependencyFailedError(t *testing.T) {
+func TestShellCommand(t *testing.T) {
+	cmd := ShellCommand("echo hi", &ShellOptions{Shell: "/bin/sh -e", WorkDir: "/tmp", Env: []string{"A=1"}})
+	assert.Equal(t, []string{"/bin/sh", "-e", "-c", "echo hi"}, cmd.Args)
+	assert.Equal(t, "/tmp", cmd.Dir)
+	assert.Equal(t, []string{"A=1"}, cmd.Env)
+	require.NotNil(t, cmd.SysProcAttr)
+	assert.True(t, cmd.SysProcAttr.Setpgid)
+
+	cmd = ShellCommand("true", &ShellOptions{})
+	assert.Equal(t, []string{"/usr/bin/env", "bash", "-c", "true"}, cmd.Args)
+
+	cmd = ShellCommand("read x", &ShellOptions{Foreground: true})
+	assert.Nil(t, cmd.SysProcAttr)
+	assert.Equal(t, os.Stdin, cmd.Stdin)
+}
+
+func TestRunCommand_SeparatesStreams(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	err := RunCommand(context.Background(), "echo out; echo err >&2", &ShellOptions{
+		Shell:  "/bin/sh",
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "out\n", stdout.String())
+	assert.Equal(t, "err\n", stderr.String())
+}
+
+func TestRunInteractive(t *testing.T) {
 	tests := []struct {
 		name     string
-		targetID string
-		expected string
+		cmd      string
+		errorMsg string
 	}{
 		{
-			name:     "simple target ID",
-			targetID: "core:app_build",
-			expected: "dependency failed for target: core:app_build",
+			name: "success",
+			cmd:  "test -n \"$RPM_TEST\" && test \"$(pwd)\" = \"$EXPECTED_DIR\"",
 		},
 		{
-			name:     "empty target ID",
-			targetID: "",
-			expected: "dependency failed for target: ",
+			name:     "non-zero exit",
+			cmd:      "exit 3",
+			errorMsg: "command exited with status 3",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := &DependencyFailedError{TargetID: tt.targetID}
-			assert.Equal(t, tt.expected, err.Error())
+			dir, err := filepath.EvalSymlinks(t.TempDir())
+			require.NoError(t, err)
+
+			err = RunInteractive(context.Background(), tt.cmd, &ShellOptions{
+				WorkDir: dir,
+				Env:     []string{"RPM_TEST=1", "EXPECTED_DIR=" + dir, "PATH=" + os.Getenv("PATH")},
+				Shell:   "/bin/sh",
+			})
+
+			if tt.errorMsg != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.errorMsg, err.Error())
+				return
+			}
+			require.NoError(t, err)
 		})
 	}
 }
@@ -0,0 +1,36 @@
+package exec
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected syscall.Signal
+		hasError bool
+	}{
+		{input: "HUP", expected: syscall.SIGHUP},
+		{input: "SIGHUP", expected: syscall.SIGHUP},
+		{input: "usr2", expected: syscall.SIGUSR2},
+		{input: "TERM", expected: syscall.SIGTERM},
+		{input: "15", expected: syscall.SIGTERM},
+		{input: "BOGUS", hasError: true},
+		{input: "", hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			sig, err := ParseSignal(tt.input)
+			if tt.hasError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, sig)
+		})
+	}
+}
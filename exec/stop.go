@@ -0,0 +1,81 @@
+package exec
+
+import (
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/vcnkl/rpm/models"
+)
+
+// groupPollInterval is how often a stopping process group is checked for
+// remaining processes.
+const groupPollInterval = 50 * time.Millisecond
+
+// StopOptions says how to stop a process group: Signal first, then SIGKILL
+// when the group has not exited after Timeout.
+type StopOptions struct {
+	Signal  syscall.Signal
+	Timeout time.Duration
+}
+
+// DefaultStopOptions sends SIGTERM and waits killGracePeriod.
+var DefaultStopOptions = StopOptions{Signal: syscall.SIGTERM, Timeout: killGracePeriod}
+
+// TargetStopOptions returns the target's stop_signal and stop_timeout, with
+// the defaults for unset ones.
+func TargetStopOptions(target *models.Target) (StopOptions, error) {
+	opts := DefaultStopOptions
+	if target.StopSignal != "" {
+		sig, err := ParseSignal(target.StopSignal)
+		if err != nil {
+			return opts, err
+		}
+		opts.Signal = sig
+	}
+	if target.StopTimeout > 0 {
+		opts.Timeout = target.StopTimeout
+	}
+	return opts, nil
+}
+
+// StopGroup stops the process group led by pid. done must be closed once
+// the leader has been waited for. It returns after the leader and every
+// other process in the group exited, and reports whether they had to be
+// killed.
+func StopGroup(pid int, done <-chan struct{}, opts StopOptions) bool {
+	if opts.Signal == 0 {
+		opts.Signal = DefaultStopOptions.Signal
+	}
+	pgid := -pid
+	_ = syscall.Kill(pgid, opts.Signal)
+
+	deadline := time.NewTimer(opts.Timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(groupPollInterval)
+	defer ticker.Stop()
+
+	leaderDone := false
+	for {
+		select {
+		case <-done:
+			leaderDone = true
+			done = nil
+		case <-ticker.C:
+		case <-deadline.C:
+			_ = syscall.Kill(pgid, syscall.SIGKILL)
+			if !leaderDone {
+				<-done
+			}
+			return true
+		}
+		if leaderDone && !groupAlive(pgid) {
+			return false
+		}
+	}
+}
+
+func groupAlive(pgid int) bool {
+	err := syscall.Kill(pgid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
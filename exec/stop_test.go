@@ -0,0 +1,84 @@
+package exec
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vcnkl/rpm/models"
+)
+
+func startGroup(t *testing.T, script string) (int, <-chan struct{}) {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	require.NoError(t, cmd.Start())
+	done := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(done)
+	}()
+	// Give the shell time to install its traps.
+	time.Sleep(100 * time.Millisecond)
+	return cmd.Process.Pid, done
+}
+
+func TestStopGroup(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		opts   StopOptions
+		killed bool
+	}{
+		{
+			name:   "exits on signal",
+			script: "sleep 10",
+			opts:   StopOptions{Signal: syscall.SIGTERM, Timeout: 5 * time.Second},
+		},
+		{
+			name:   "custom signal",
+			script: `trap "exit 0" INT; trap "" TERM; sleep 10`,
+			opts:   StopOptions{Signal: syscall.SIGINT, Timeout: 5 * time.Second},
+		},
+		{
+			name:   "waits for children after the leader exits",
+			script: `trap "exit 0" TERM; sh -c 'trap "" TERM; sleep 0.3' & wait`,
+			opts:   StopOptions{Signal: syscall.SIGTERM, Timeout: 5 * time.Second},
+		},
+		{
+			name:   "killed after timeout",
+			script: `trap "" TERM; sleep 10`,
+			opts:   StopOptions{Signal: syscall.SIGTERM, Timeout: 200 * time.Millisecond},
+			killed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pid, done := startGroup(t, tt.script)
+
+			killed := StopGroup(pid, done, tt.opts)
+			assert.Equal(t, tt.killed, killed)
+			if !tt.killed {
+				assert.False(t, groupAlive(-pid))
+			}
+		})
+	}
+}
+
+func TestTargetStopOptions(t *testing.T) {
+	opts, err := TargetStopOptions(&models.Target{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultStopOptions, opts)
+
+	opts, err = TargetStopOptions(&models.Target{StopSignal: "INT", StopTimeout: time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, StopOptions{Signal: syscall.SIGINT, Timeout: time.Second}, opts)
+
+	_, err = TargetStopOptions(&models.Target{StopSignal: "BOGUS"})
+	assert.Error(t, err)
+}
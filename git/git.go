@@ -3,26 +3,52 @@ package git
 import (
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
 )
 
-func IsTracked(path string) (bool, error) {
-	cmd := exec.Command("git", "ls-files", "--error-unmatch", path)
-	err := cmd.Run()
+// ErrNotRepository is returned when a directory is not inside a git work tree
+// or git is not installed.
+var ErrNotRepository = errors.New("not a git repository")
+
+// ListFiles returns the tracked and untracked, not ignored files under
+// repoRoot named name, relative to repoRoot, with a single git call.
+func ListFiles(repoRoot string, name string) ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "-z", "--cached", "--others", "--exclude-standard",
+		"--", ":(glob)**/"+name)
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
 	if err != nil {
-		var exitError *exec.ExitError
-		if errors.As(err, &exitError) {
-			// assume tracked when not in a git repo
-			if exitError.ExitCode() == 128 {
-				return true, nil
-			}
-			return false, nil
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() != 128 {
+			return nil, errors.Wrap(err, "failed to list files")
 		}
-		return false, err
+		return nil, ErrNotRepository
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file == "" || seen[file] {
+			continue
+		}
+		seen[file] = true
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func HeadCommit(repoRoot string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to resolve HEAD")
 	}
-	return true, nil
+	return strings.TrimSpace(string(output)), nil
 }
 
 func GetChangedFiles(repoRoot string) ([]string, error) {
@@ -0,0 +1,164 @@
+// Package junit reads, merges and writes JUnit XML test reports.
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type Suites struct {
+	XMLName  xml.Name `xml:"testsuites"`
+	Name     string   `xml:"name,attr,omitempty"`
+	Tests    int      `xml:"tests,attr"`
+	Failures int      `xml:"failures,attr"`
+	Errors   int      `xml:"errors,attr"`
+	Skipped  int      `xml:"skipped,attr"`
+	Time     float64  `xml:"time,attr"`
+	Suites   []Suite  `xml:"testsuite"`
+}
+
+type Suite struct {
+	Name       string     `xml:"name,attr"`
+	Tests      int        `xml:"tests,attr"`
+	Failures   int        `xml:"failures,attr"`
+	Errors     int        `xml:"errors,attr"`
+	Skipped    int        `xml:"skipped,attr"`
+	Time       float64    `xml:"time,attr"`
+	Timestamp  string     `xml:"timestamp,attr,omitempty"`
+	Properties []Property `xml:"properties>property,omitempty"`
+	Cases      []Case     `xml:"testcase"`
+	SystemOut  string     `xml:"system-out,omitempty"`
+	SystemErr  string     `xml:"system-err,omitempty"`
+}
+
+type Property struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type Case struct {
+	Name      string   `xml:"name,attr"`
+	Classname string   `xml:"classname,attr,omitempty"`
+	Time      float64  `xml:"time,attr"`
+	Failure   *Outcome `xml:"failure,omitempty"`
+	Error     *Outcome `xml:"error,omitempty"`
+	Skipped   *Outcome `xml:"skipped,omitempty"`
+	SystemOut string   `xml:"system-out,omitempty"`
+	SystemErr string   `xml:"system-err,omitempty"`
+}
+
+type Outcome struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func (c *Case) Failed() bool {
+	return c.Failure != nil || c.Error != nil
+}
+
+// Message returns the failure or error message of a failed case, falling back
+// to the first line of its text.
+func (c *Case) Message() string {
+	o := c.Failure
+	if o == nil {
+		o = c.Error
+	}
+	if o == nil {
+		return ""
+	}
+	if o.Message != "" {
+		return o.Message
+	}
+	return firstLine(o.Text)
+}
+
+// Parse accepts both a <testsuites> document and a bare <testsuite> root, as
+// produced by different tools.
+func Parse(data []byte) ([]Suite, error) {
+	var suites Suites
+	if err := xml.Unmarshal(data, &suites); err == nil {
+		return suites.Suites, nil
+	}
+
+	var suite Suite
+	if err := xml.Unmarshal(data, &suite); err != nil {
+		return nil, err
+	}
+	return []Suite{suite}, nil
+}
+
+func ParseFile(path string) ([]Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report %s: %w", path, err)
+	}
+	suites, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	return suites, nil
+}
+
+// Recount recomputes the counters of every suite and of the document from
+// its test cases, since reports from some tools omit or miscount them.
+func (s *Suites) Recount() {
+	s.Tests, s.Failures, s.Errors, s.Skipped, s.Time = 0, 0, 0, 0, 0
+	for i := range s.Suites {
+		suite := &s.Suites[i]
+		suite.Tests, suite.Failures, suite.Errors, suite.Skipped = len(suite.Cases), 0, 0, 0
+		caseTime := 0.0
+		for _, c := range suite.Cases {
+			switch {
+			case c.Failure != nil:
+				suite.Failures++
+			case c.Error != nil:
+				suite.Errors++
+			case c.Skipped != nil:
+				suite.Skipped++
+			}
+			caseTime += c.Time
+		}
+		if suite.Time == 0 {
+			suite.Time = caseTime
+		}
+
+		s.Tests += suite.Tests
+		s.Failures += suite.Failures
+		s.Errors += suite.Errors
+		s.Skipped += suite.Skipped
+		s.Time += suite.Time
+	}
+}
+
+func (s *Suites) WriteFile(path string) error {
+	s.Recount()
+
+	data, err := xml.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize junit report: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	data = append([]byte(xml.Header), data...)
+	if err = os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write junit report %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func firstLine(s string) string {
+	for i, r := range s {
+		if r == '\n' {
+			return s[:i]
+		}
+	}
+	return s
+}
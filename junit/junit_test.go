@@ -0,0 +1,139 @@
+package junit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		suites      int
+		cases       int
+		failed      int
+		expectError bool
+	}{
+		{
+			name: "testsuites root",
+			input: `<?xml version="1.0"?>
+<testsuites>
+  <testsuite name="a"><testcase name="t1"/><testcase name="t2"><failure message="x"/></testcase></testsuite>
+  <testsuite name="b"><testcase name="t3"><error message="panic"/></testcase></testsuite>
+</testsuites>`,
+			suites: 2,
+			cases:  3,
+			failed: 2,
+		},
+		{
+			name:   "bare testsuite root",
+			input:  `<testsuite name="a"><testcase name="t1"/><testcase name="t2"><skipped/></testcase></testsuite>`,
+			suites: 1,
+			cases:  2,
+			failed: 0,
+		},
+		{
+			name:        "not xml",
+			input:       "ok  \tgithub.com/x\t0.1s",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suites, err := Parse([]byte(tt.input))
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, suites, tt.suites)
+
+			cases, failed := 0, 0
+			for _, s := range suites {
+				for _, c := range s.Cases {
+					cases++
+					if c.Failed() {
+						failed++
+					}
+				}
+			}
+			assert.Equal(t, tt.cases, cases)
+			assert.Equal(t, tt.failed, failed)
+		})
+	}
+}
+
+func TestCase_Message(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        Case
+		expected string
+	}{
+		{name: "passed", c: Case{}, expected: ""},
+		{name: "failure message", c: Case{Failure: &Outcome{Message: "boom", Text: "trace"}}, expected: "boom"},
+		{name: "failure text first line", c: Case{Failure: &Outcome{Text: "line1\nline2"}}, expected: "line1"},
+		{name: "error message", c: Case{Error: &Outcome{Message: "panic"}}, expected: "panic"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.c.Message())
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	r := NewReport()
+	r.Add("core:unit_test", []Suite{{
+		Name: "pkg",
+		Cases: []Case{
+			{Name: "TestA", Classname: "pkg", Time: 0.5},
+			{Name: "TestB", Classname: "pkg", Time: 0.25, Failure: &Outcome{Message: "expected 1"}},
+		},
+	}})
+	r.AddResult("api:e2e_test", 2*time.Second, errors.New("command exited with status 1"), "/logs/api__e2e_test.log")
+	r.AddResult("api:lint_test", time.Second, nil, "")
+	r.AddNotRun("web:unit_test", errors.New("dependency failed"), true)
+	r.AddNotRun("web:e2e_test", errors.New("context canceled"), false)
+
+	suites := r.Suites()
+	require.Len(t, suites.Suites, 5)
+	assert.Equal(t, "api:e2e_test", suites.Suites[0].Name, "suites are ordered by target")
+	assert.Equal(t, 6, suites.Tests)
+	assert.Equal(t, 2, suites.Failures)
+	assert.Equal(t, 1, suites.Errors)
+	assert.Equal(t, 1, suites.Skipped)
+	assert.Equal(t, []Property{{Name: "rpm.target", Value: "core:unit_test"}}, suites.Suites[2].Properties)
+	assert.InDelta(t, 0.75, suites.Suites[2].Time, 0.001)
+
+	assert.Equal(t, []Failure{
+		{Target: "api:e2e_test", Suite: "api:e2e_test", Case: "api:e2e_test", Message: "command exited with status 1"},
+		{Target: "core:unit_test", Suite: "pkg", Case: "pkg.TestB", Message: "expected 1"},
+		{Target: "web:e2e_test", Suite: "web:e2e_test", Case: "web:e2e_test", Message: "context canceled"},
+	}, r.Failures())
+}
+
+func TestSuites_WriteFile(t *testing.T) {
+	r := NewReport()
+	r.Add("core:unit_test", []Suite{{Name: "pkg", Cases: []Case{{Name: "TestA"}, {Name: "TestB", Skipped: &Outcome{}}}}})
+
+	path := filepath.Join(t.TempDir(), "reports", "junit.xml")
+	require.NoError(t, r.Suites().WriteFile(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `<?xml version="1.0" encoding="UTF-8"?>`)
+
+	suites, err := Parse(data)
+	require.NoError(t, err)
+	require.Len(t, suites, 1)
+	assert.Equal(t, 2, suites[0].Tests)
+	assert.Equal(t, 1, suites[0].Skipped)
+}
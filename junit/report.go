@@ -0,0 +1,128 @@
+package junit
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Report merges the test reports of several targets. Each suite is tagged
+// with an "rpm.target" property naming the target that produced it.
+type Report struct {
+	suites map[string][]Suite
+	mu     sync.Mutex
+}
+
+type Failure struct {
+	Target  string
+	Suite   string
+	Case    string
+	Message string
+}
+
+func NewReport() *Report {
+	return &Report{
+		suites: make(map[string][]Suite),
+	}
+}
+
+func (r *Report) Add(targetID string, suites []Suite) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, suite := range suites {
+		suite.Properties = append(suite.Properties, Property{Name: "rpm.target", Value: targetID})
+		r.suites[targetID] = append(r.suites[targetID], suite)
+	}
+}
+
+// AddResult records a target that produced no report as a suite holding a
+// single test case named after the target.
+func (r *Report) AddResult(targetID string, duration time.Duration, err error, logPath string) {
+	c := Case{
+		Name:      targetID,
+		Classname: targetID,
+		Time:      duration.Seconds(),
+	}
+	if err != nil {
+		c.Failure = &Outcome{
+			Message: err.Error(),
+			Type:    "exit",
+		}
+		if logPath != "" {
+			c.Failure.Text = "log: " + logPath
+		}
+	}
+
+	r.addCase(targetID, c)
+}
+
+// AddNotRun records a target that never ran as a single test case, skipped
+// when skipped is set, such as after a dependency failed, and an error
+// otherwise.
+func (r *Report) AddNotRun(targetID string, err error, skipped bool) {
+	c := Case{Name: targetID, Classname: targetID}
+	outcome := &Outcome{Message: err.Error(), Type: "not_run"}
+	if skipped {
+		c.Skipped = outcome
+	} else {
+		c.Error = outcome
+	}
+	r.addCase(targetID, c)
+}
+
+func (r *Report) addCase(targetID string, c Case) {
+	r.Add(targetID, []Suite{{
+		Name:  targetID,
+		Time:  c.Time,
+		Cases: []Case{c},
+	}})
+}
+
+// Suites returns all suites ordered by target.
+func (r *Report) Suites() *Suites {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	targets := make([]string, 0, len(r.suites))
+	for id := range r.suites {
+		targets = append(targets, id)
+	}
+	sort.Strings(targets)
+
+	out := &Suites{Name: "rpm", Suites: make([]Suite, 0)}
+	for _, id := range targets {
+		out.Suites = append(out.Suites, r.suites[id]...)
+	}
+	out.Recount()
+	return out
+}
+
+// Failures returns every failed or errored test case, ordered by target.
+func (r *Report) Failures() []Failure {
+	var failures []Failure
+	for _, suite := range r.Suites().Suites {
+		target := ""
+		for _, p := range suite.Properties {
+			if p.Name == "rpm.target" {
+				target = p.Value
+			}
+		}
+		for _, c := range suite.Cases {
+			if !c.Failed() {
+				continue
+			}
+			name := c.Name
+			if c.Classname != "" && c.Classname != c.Name {
+				name = c.Classname + "." + c.Name
+			}
+			failures = append(failures, Failure{
+				Target:  target,
+				Suite:   suite.Name,
+				Case:    name,
+				Message: c.Message(),
+			})
+		}
+	}
+	return failures
+}
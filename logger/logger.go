@@ -1,9 +1,9 @@
 package logger
 
 import (
+	"bytes"
 	"io"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/mattn/go-isatty"
@@ -25,7 +25,8 @@ type Logger interface {
 	Warn(msg string, fields ...Field)
 	Error(msg string, fields ...Field)
 	WithPrefix(prefix string) Logger
-	Writer() io.Writer
+	Writer() io.WriteCloser
+	Redactor() *Redactor
 }
 
 type Field struct {
@@ -54,23 +55,41 @@ func Bool(key string, value bool) Field {
 }
 
 type logger struct {
-	zlog   zerolog.Logger
-	prefix string
+	zlog     zerolog.Logger
+	prefix   string
+	redactor *Redactor
+	out      io.Writer
+	format   func(io.Writer) io.Writer
 }
 
 func New(level Level) Logger {
-	out := os.Stdout
-	var zl zerolog.Logger
+	return NewWithOutput(level, os.Stdout)
+}
 
+// NewWithOutput writes log lines to out instead of stdout, e.g. when stdout is
+// reserved for the JSON event stream.
+func NewWithOutput(level Level, out *os.File) Logger {
+	format := func(w io.Writer) io.Writer { return w }
 	if isatty.IsTerminal(out.Fd()) {
-		zl = zerolog.New(zerolog.ConsoleWriter{
-			Out:        out,
-			TimeFormat: time.RFC3339,
-		}).With().Timestamp().Logger()
-	} else {
-		zl = zerolog.New(out).With().Timestamp().Logger()
+		format = func(w io.Writer) io.Writer {
+			return zerolog.ConsoleWriter{
+				Out:        w,
+				TimeFormat: time.RFC3339,
+			}
+		}
 	}
 
+	return newLogger(out, format, level)
+}
+
+// NewWithWriter writes JSON log lines to w, e.g. for a UI to display.
+func NewWithWriter(level Level, w io.Writer) Logger {
+	return newLogger(w, func(w io.Writer) io.Writer { return w }, level)
+}
+
+func newLogger(out io.Writer, format func(io.Writer) io.Writer, level Level) *logger {
+	zl := zerolog.New(format(out)).With().Timestamp().Logger()
+
 	switch level {
 	case DebugLevel:
 		zl = zl.Level(zerolog.DebugLevel)
@@ -82,25 +101,39 @@ func New(level Level) Logger {
 		zl = zl.Level(zerolog.ErrorLevel)
 	}
 
-	return &logger{zlog: zl}
+	return &logger{
+		zlog:     zl,
+		redactor: NewRedactor(),
+		out:      out,
+		format:   format,
+	}
 }
 
 func (l *logger) WithPrefix(prefix string) Logger {
 	return &logger{
-		zlog:   l.zlog.With().Str("target", prefix).Logger(),
-		prefix: prefix,
+		zlog:     l.zlog.With().Str("target", prefix).Logger(),
+		prefix:   prefix,
+		redactor: l.redactor,
+		out:      l.out,
+		format:   l.format,
 	}
 }
 
-func (l *logger) Writer() io.Writer {
-	return &writer{logger: l}
+func (l *logger) Writer() io.WriteCloser {
+	return newLineWriter(func(line string) {
+		l.Info(line)
+	})
+}
+
+func (l *logger) Redactor() *Redactor {
+	return l.redactor
 }
 
 func (l *logger) applyFields(event *zerolog.Event, fields []Field) *zerolog.Event {
 	for _, f := range fields {
 		switch v := f.Value.(type) {
 		case string:
-			event = event.Str(f.Key, v)
+			event = event.Str(f.Key, l.redactor.Redact(v))
 		case int:
 			event = event.Int(f.Key, v)
 		case int64:
@@ -111,7 +144,7 @@ func (l *logger) applyFields(event *zerolog.Event, fields []Field) *zerolog.Even
 			event = event.Dur(f.Key, v)
 		case error:
 			if v != nil {
-				event = event.Err(v)
+				event = event.Str(zerolog.ErrorFieldName, l.redactor.Redact(v.Error()))
 			}
 		default:
 			event = event.Interface(f.Key, v)
@@ -121,26 +154,32 @@ func (l *logger) applyFields(event *zerolog.Event, fields []Field) *zerolog.Even
 }
 
 func (l *logger) Debug(msg string, fields ...Field) {
-	l.applyFields(l.zlog.Debug(), fields).Msg(msg)
+	l.applyFields(l.zlog.Debug(), fields).Msg(l.redactor.Redact(msg))
 }
 
 func (l *logger) Info(msg string, fields ...Field) {
-	l.applyFields(l.zlog.Info(), fields).Msg(msg)
+	l.applyFields(l.zlog.Info(), fields).Msg(l.redactor.Redact(msg))
 }
 
 func (l *logger) Warn(msg string, fields ...Field) {
-	l.applyFields(l.zlog.Warn(), fields).Msg(msg)
+	l.applyFields(l.zlog.Warn(), fields).Msg(l.redactor.Redact(msg))
 }
 
 func (l *logger) Error(msg string, fields ...Field) {
-	l.applyFields(l.zlog.Error(), fields).Msg(msg)
-}
-
-type writer struct {
-	logger *logger
-}
-
-func (w *writer) Write(p []byte) (n int, err error) {
-	w.logger.Info(strings.TrimSuffix(string(p), "\n"))
-	return len(p), nil
+	l.applyFields(l.zlog.Error(), fields).Msg(l.redactor.Redact(msg))
+}
+
+// writeGroup renders lines as info events into a buffer and writes them to
+// the underlying output in one call, so concurrent targets cannot interleave.
+func (l *logger) writeGroup(lines []outputLine) {
+	var buf bytes.Buffer
+	zl := l.zlog.Output(l.format(&buf))
+	for _, line := range lines {
+		event := zl.Info()
+		if line.stream == Stderr {
+			event = zl.Warn()
+		}
+		event.Msg(l.redactor.Redact(line.text))
+	}
+	_, _ = l.out.Write(buf.Bytes())
 }
@@ -0,0 +1,200 @@
+package logger
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/mattn/go-isatty"
+)
+
+type OutputMode string
+
+const (
+	OutputStream  OutputMode = "stream"
+	OutputGrouped OutputMode = "grouped"
+	OutputRaw     OutputMode = "raw"
+)
+
+func ParseOutputMode(s string) (OutputMode, error) {
+	switch OutputMode(s) {
+	case "", OutputStream:
+		return OutputStream, nil
+	case OutputGrouped:
+		return OutputGrouped, nil
+	case OutputRaw:
+		return OutputRaw, nil
+	}
+	return "", fmt.Errorf("invalid output mode: %s (expected stream, grouped or raw)", s)
+}
+
+var prefixColors = []string{"36", "33", "32", "35", "34", "31", "96", "93", "92", "95", "94", "91"}
+
+type Stream int
+
+const (
+	Stdout Stream = iota
+	Stderr
+)
+
+func (s Stream) String() string {
+	if s == Stderr {
+		return "stderr"
+	}
+	return "stdout"
+}
+
+type outputLine struct {
+	stream Stream
+	text   string
+}
+
+type grouper interface {
+	writeGroup(lines []outputLine)
+}
+
+// TargetOutput receives the stdout and stderr of a single target command. Each
+// complete line is redacted and appended to the target's log file, then either
+// logged immediately (stream) or held until Close (grouped). Stderr lines are
+// logged at warn level. In raw mode output is instead copied to the terminal
+// as it arrives, unbuffered, behind a short target prefix at each line start.
+type TargetOutput struct {
+	id        string
+	log       Logger
+	mode      OutputMode
+	file      io.WriteCloser
+	stdout    *lineWriter
+	stderr    *lineWriter
+	rawStdout *rawWriter
+	rawStderr *rawWriter
+	pending   []outputLine
+	rawOut    io.Writer
+	rawErr    io.Writer
+	rawPrefix string
+	onLine    []func(stream Stream, line string)
+	mu        sync.Mutex
+}
+
+func NewTargetOutput(id string, log Logger, mode OutputMode, file io.WriteCloser) *TargetOutput {
+	o := &TargetOutput{
+		id:     id,
+		log:    log,
+		mode:   mode,
+		file:   file,
+		rawOut: os.Stdout,
+		rawErr: os.Stderr,
+	}
+	if l, ok := log.(*logger); ok {
+		o.rawOut = l.out
+	}
+	color := false
+	if f, ok := o.rawOut.(*os.File); ok {
+		color = isatty.IsTerminal(f.Fd())
+	}
+	o.rawPrefix = rawPrefix(id, color)
+	o.stdout = newLineWriter(func(line string) { o.emit(Stdout, line) })
+	o.stderr = newLineWriter(func(line string) { o.emit(Stderr, line) })
+	if mode == OutputRaw {
+		o.rawStdout = newRawWriter(log.Redactor(), func() (io.Writer, string) { return o.rawOut, o.rawPrefix })
+		o.rawStderr = newRawWriter(log.Redactor(), func() (io.Writer, string) { return o.rawErr, o.rawPrefix })
+	}
+	return o
+}
+
+func (o *TargetOutput) Stdout() io.Writer {
+	if o.rawStdout != nil {
+		return io.MultiWriter(o.rawStdout, o.stdout)
+	}
+	return o.stdout
+}
+
+func (o *TargetOutput) Stderr() io.Writer {
+	if o.rawStderr != nil {
+		return io.MultiWriter(o.rawStderr, o.stderr)
+	}
+	return o.stderr
+}
+
+// OnLine registers fn to be called with every redacted output line as it is
+// read, regardless of the output mode. Functions are called in the order
+// they were registered.
+func (o *TargetOutput) OnLine(fn func(stream Stream, line string)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.onLine = append(o.onLine, fn)
+}
+
+func (o *TargetOutput) emit(stream Stream, line string) {
+	line = o.log.Redactor().Redact(line)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.file != nil {
+		fmt.Fprintln(o.file, line)
+	}
+	for _, fn := range o.onLine {
+		fn(stream, line)
+	}
+
+	switch o.mode {
+	case OutputGrouped:
+		o.pending = append(o.pending, outputLine{stream: stream, text: line})
+	case OutputRaw:
+		// copied to the terminal by the raw writers
+	default:
+		logLine(o.log, stream, line)
+	}
+}
+
+// Close flushes any trailing partial lines, prints held output in grouped mode
+// and closes the log file. It must be called whether or not the command failed.
+func (o *TargetOutput) Close() error {
+	o.stdout.Close()
+	o.stderr.Close()
+	if o.rawStdout != nil {
+		o.rawStdout.Close()
+		o.rawStderr.Close()
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.pending) > 0 {
+		if g, ok := o.log.(grouper); ok {
+			g.writeGroup(o.pending)
+		} else {
+			for _, line := range o.pending {
+				logLine(o.log, line.stream, line.text)
+			}
+		}
+		o.pending = nil
+	}
+
+	if o.file != nil {
+		err := o.file.Close()
+		o.file = nil
+		return err
+	}
+	return nil
+}
+
+func logLine(log Logger, stream Stream, line string) {
+	if stream == Stderr {
+		log.Warn(line)
+		return
+	}
+	log.Info(line)
+}
+
+func rawPrefix(id string, color bool) string {
+	if !color {
+		return id + " | "
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+	code := prefixColors[h.Sum32()%uint32(len(prefixColors))]
+	return "\x1b[" + code + "m" + id + " |\x1b[0m "
+}
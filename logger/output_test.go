@@ -0,0 +1,243 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (n *nopCloser) Close() error {
+	n.closed = true
+	return nil
+}
+
+func messages(t *testing.T, out string) []string {
+	t.Helper()
+
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		var event map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		msgs = append(msgs, event["message"].(string))
+	}
+	return msgs
+}
+
+func TestParseOutputMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    OutputMode
+		expectError bool
+	}{
+		{name: "empty defaults to stream", input: "", expected: OutputStream},
+		{name: "stream", input: "stream", expected: OutputStream},
+		{name: "grouped", input: "grouped", expected: OutputGrouped},
+		{name: "raw", input: "raw", expected: OutputRaw},
+		{name: "invalid", input: "fancy", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := ParseOutputMode(tt.input)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, mode)
+		})
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		writes   []string
+		expected []string
+	}{
+		{
+			name:     "single complete line",
+			writes:   []string{"hello\n"},
+			expected: []string{"hello"},
+		},
+		{
+			name:     "line split across writes",
+			writes:   []string{"hel", "lo wor", "ld\n"},
+			expected: []string{"hello world"},
+		},
+		{
+			name:     "several lines in one write",
+			writes:   []string{"a\nb\nc\n"},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "trailing partial line flushed on close",
+			writes:   []string{"a\nb"},
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "crlf stripped",
+			writes:   []string{"a\r\n"},
+			expected: []string{"a"},
+		},
+		{
+			name:     "empty lines preserved",
+			writes:   []string{"a\n\nb\n"},
+			expected: []string{"a", "", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			w := newLineWriter(func(line string) {
+				lines = append(lines, line)
+			})
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				require.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+			require.NoError(t, w.Close())
+			assert.Equal(t, tt.expected, lines)
+		})
+	}
+}
+
+func TestTargetOutput_Stream(t *testing.T) {
+	var buf bytes.Buffer
+	log := newLogger(&buf, func(w io.Writer) io.Writer { return w }, InfoLevel).WithPrefix("core:app_build")
+	file := &nopCloser{}
+
+	out := NewTargetOutput("core:app_build", log, OutputStream, file)
+	_, _ = out.Stdout().Write([]byte("first\nsec"))
+	assert.Equal(t, []string{"first"}, messages(t, buf.String()))
+
+	_, _ = out.Stdout().Write([]byte("ond\n"))
+	require.NoError(t, out.Close())
+
+	assert.Equal(t, []string{"first", "second"}, messages(t, buf.String()))
+	assert.Equal(t, "first\nsecond\n", file.String())
+	assert.True(t, file.closed)
+}
+
+func TestTargetOutput_Grouped(t *testing.T) {
+	var buf bytes.Buffer
+	base := newLogger(&buf, func(w io.Writer) io.Writer { return w }, InfoLevel)
+
+	a := NewTargetOutput("a:build", base.WithPrefix("a:build"), OutputGrouped, nil)
+	b := NewTargetOutput("b:build", base.WithPrefix("b:build"), OutputGrouped, nil)
+
+	_, _ = a.Stdout().Write([]byte("a1\n"))
+	_, _ = b.Stdout().Write([]byte("b1\n"))
+	_, _ = a.Stdout().Write([]byte("a2\n"))
+	assert.Empty(t, buf.String())
+
+	require.NoError(t, b.Close())
+	require.NoError(t, a.Close())
+
+	assert.Equal(t, []string{"b1", "a1", "a2"}, messages(t, buf.String()))
+}
+
+func TestTargetOutput_RedactsFileAndLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := newLogger(&buf, func(w io.Writer) io.Writer { return w }, InfoLevel)
+	log.Redactor().Add("hunter2")
+	file := &nopCloser{}
+
+	out := NewTargetOutput("core:app_build", log, OutputGrouped, file)
+	_, _ = out.Stdout().Write([]byte("password is hunter2\n"))
+	require.NoError(t, out.Close())
+
+	assert.Equal(t, "password is ***\n", file.String())
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestTargetOutput_StderrLoggedAsWarn(t *testing.T) {
+	var buf bytes.Buffer
+	log := newLogger(&buf, func(w io.Writer) io.Writer { return w }, InfoLevel)
+
+	for _, mode := range []OutputMode{OutputStream, OutputGrouped} {
+		buf.Reset()
+		out := NewTargetOutput("core:app_build", log, mode, nil)
+		_, _ = out.Stdout().Write([]byte("to stdout\n"))
+		_, _ = out.Stderr().Write([]byte("to stderr\n"))
+		require.NoError(t, out.Close())
+
+		var levels []string
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var event map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &event))
+			levels = append(levels, event["level"].(string)+":"+event["message"].(string))
+		}
+		assert.Equal(t, []string{"info:to stdout", "warn:to stderr"}, levels, "mode %s", mode)
+	}
+}
+
+func TestTargetOutput_Raw(t *testing.T) {
+	var logBuf, stdout, stderr bytes.Buffer
+	log := newLogger(&logBuf, func(w io.Writer) io.Writer { return w }, InfoLevel)
+	log.Redactor().Add("hunter2")
+	file := &nopCloser{}
+
+	out := NewTargetOutput("core:app_build", log, OutputRaw, file)
+	out.rawOut = &stdout
+	out.rawErr = &stderr
+	out.rawPrefix = rawPrefix("core:app_build", false)
+
+	_, _ = out.Stdout().Write([]byte("\x1b[32mgreen\x1b[0m\n"))
+	_, _ = out.Stderr().Write([]byte("token hunter2\n"))
+	require.NoError(t, out.Close())
+
+	assert.Equal(t, "core:app_build | \x1b[32mgreen\x1b[0m\n", stdout.String())
+	assert.Equal(t, "core:app_build | token ***\n", stderr.String())
+	assert.Empty(t, logBuf.String())
+	assert.Equal(t, "\x1b[32mgreen\x1b[0m\ntoken ***\n", file.String())
+}
+
+func TestTargetOutput_RawStreams(t *testing.T) {
+	var logBuf, stdout bytes.Buffer
+	log := newLogger(&logBuf, func(w io.Writer) io.Writer { return w }, InfoLevel)
+	log.Redactor().Add("hunter2")
+	file := &nopCloser{}
+
+	out := NewTargetOutput("core:app_build", log, OutputRaw, file)
+	out.rawOut = &stdout
+	out.rawPrefix = "p | "
+
+	_, _ = out.Stdout().Write([]byte("progress 10%"))
+	assert.Equal(t, "p | progress 10%", stdout.String(), "partial lines are not held")
+
+	_, _ = out.Stdout().Write([]byte("\rprogress 100%\ndone\nsecret hun"))
+	assert.Equal(t, "p | progress 10%\rprogress 100%\np | done\np | secret ", stdout.String(),
+		"possible start of a secret is held")
+
+	_, _ = out.Stdout().Write([]byte("ter2 used\nlast"))
+	require.NoError(t, out.Close())
+
+	assert.Equal(t, "p | progress 10%\rprogress 100%\np | done\np | secret *** used\np | last\n", stdout.String())
+	assert.Equal(t, "progress 10%\rprogress 100%\ndone\nsecret *** used\nlast\n", file.String())
+}
+
+func TestRawPrefix(t *testing.T) {
+	assert.Equal(t, "api:server | ", rawPrefix("api:server", false))
+
+	colored := rawPrefix("api:server", true)
+	assert.True(t, strings.HasPrefix(colored, "\x1b["))
+	assert.Contains(t, colored, "api:server |")
+	assert.Equal(t, colored, rawPrefix("api:server", true))
+}
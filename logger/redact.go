@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const redactedValue = "***"
+
+// minRedactLength avoids masking every occurrence of trivially short values
+// such as "1" or "on", which would make output unreadable without hiding
+// anything meaningful.
+const minRedactLength = 4
+
+// CanRedact reports whether value is long enough to be masked. Shorter
+// values are left visible in output.
+func CanRedact(value string) bool {
+	return len(strings.TrimSpace(value)) >= minRedactLength
+}
+
+type Redactor struct {
+	values []string
+	mu     sync.RWMutex
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{}
+}
+
+// Add registers a secret value. Multi-line values are also registered line by
+// line so that output split at newlines is still masked.
+func (r *Redactor) Add(value string) {
+	if r == nil {
+		return
+	}
+
+	candidates := []string{value}
+	if strings.Contains(value, "\n") {
+		candidates = append(candidates, strings.Split(value, "\n")...)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range candidates {
+		c = strings.TrimSpace(c)
+		if len(c) < minRedactLength || r.contains(c) {
+			continue
+		}
+		r.values = append(r.values, c)
+	}
+
+	sort.Slice(r.values, func(i, j int) bool {
+		return len(r.values[i]) > len(r.values[j])
+	})
+}
+
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, v := range r.values {
+		if strings.Contains(s, v) {
+			s = strings.ReplaceAll(s, v, redactedValue)
+		}
+	}
+	return s
+}
+
+// PartialSuffix returns the length of the longest suffix of s that is the
+// start of a secret value, which a later write could complete.
+func (r *Redactor) PartialSuffix(s string) int {
+	if r == nil {
+		return 0
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	longest := 0
+	for _, v := range r.values {
+		for n := min(len(v)-1, len(s)); n > longest; n-- {
+			if strings.HasSuffix(s, v[:n]) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
+
+func (r *Redactor) contains(value string) bool {
+	for _, v := range r.values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
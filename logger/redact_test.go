@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	tests := []struct {
+		name     string
+		secrets  []string
+		input    string
+		expected string
+	}{
+		{
+			name:     "no secrets registered",
+			input:    "password=hunter2",
+			expected: "password=hunter2",
+		},
+		{
+			name:     "single secret",
+			secrets:  []string{"hunter2"},
+			input:    "password=hunter2 again hunter2",
+			expected: "password=*** again ***",
+		},
+		{
+			name:     "longer secret masked before its prefix",
+			secrets:  []string{"abc", "abcdef"},
+			input:    "token abcdef",
+			expected: "token ***",
+		},
+		{
+			name:     "multi-line secret masked per line",
+			secrets:  []string{"line-one\nline-two"},
+			input:    "got line-two",
+			expected: "got ***",
+		},
+		{
+			name:     "empty secret ignored",
+			secrets:  []string{""},
+			input:    "nothing to hide",
+			expected: "nothing to hide",
+		},
+		{
+			name:     "very short secret ignored",
+			secrets:  []string{"x"},
+			input:    "exit status 1",
+			expected: "exit status 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRedactor()
+			for _, s := range tt.secrets {
+				r.Add(s)
+			}
+			assert.Equal(t, tt.expected, r.Redact(tt.input))
+		})
+	}
+}
+
+func TestCanRedact(t *testing.T) {
+	assert.True(t, CanRedact("hunter2"))
+	assert.True(t, CanRedact("abcd"))
+	assert.False(t, CanRedact("123"))
+	assert.False(t, CanRedact(" 12 \n"))
+	assert.False(t, CanRedact(""))
+}
+
+func TestRedactor_PartialSuffix(t *testing.T) {
+	r := NewRedactor()
+	r.Add("hunter2")
+	r.Add("s3cr3t")
+
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{input: "password: hun", expected: 3},
+		{input: "password: hunter", expected: 6},
+		{input: "password: hunter2", expected: 0},
+		{input: "key s3", expected: 2},
+		{input: "nothing here", expected: 0},
+		{input: "", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, r.PartialSuffix(tt.input))
+		})
+	}
+
+	var nilRedactor *Redactor
+	assert.Equal(t, 0, nilRedactor.PartialSuffix("hun"))
+}
+
+func TestRedactor_Nil(t *testing.T) {
+	var r *Redactor
+	r.Add("secret")
+	assert.Equal(t, "secret", r.Redact("secret"))
+}
+
+func TestLogger_RedactsOutput(t *testing.T) {
+	var buf bytes.Buffer
+	log := newLogger(&buf, func(w io.Writer) io.Writer { return w }, InfoLevel)
+	log.Redactor().Add("s3cr3t")
+
+	prefixed := log.WithPrefix("core:app_build")
+	w := prefixed.Writer()
+	_, _ = w.Write([]byte("connecting with s3cr3t\n"))
+	_ = w.Close()
+	prefixed.Info("env", String("var", "TOKEN=s3cr3t"))
+	prefixed.Error("failed", Err(errors.New("bad token s3cr3t")))
+
+	assert.NotContains(t, buf.String(), "s3cr3t")
+	assert.Contains(t, buf.String(), "connecting with ***")
+	assert.Contains(t, buf.String(), "TOKEN=***")
+	assert.Contains(t, buf.String(), "bad token ***")
+}
@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// lineWriter buffers partial writes and calls emit once per complete line, so
+// output is never split or merged at arbitrary buffer boundaries.
+type lineWriter struct {
+	buf  []byte
+	emit func(line string)
+	mu   sync.Mutex
+}
+
+func newLineWriter(emit func(line string)) *lineWriter {
+	return &lineWriter{emit: emit}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx == -1 {
+			break
+		}
+		w.emit(strings.TrimSuffix(string(w.buf[:idx]), "\r"))
+		w.buf = w.buf[idx+1:]
+	}
+
+	return len(p), nil
+}
+
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.emit(strings.TrimSuffix(string(w.buf), "\r"))
+		w.buf = nil
+	}
+	return nil
+}
+
+// rawWriter copies command output to w as it arrives, adding prefix at the
+// start of every line and leaving everything else, such as carriage return
+// redraws, untouched. A trailing fragment that could be the start of a
+// secret is held back until the next write so that it is still masked.
+type rawWriter struct {
+	w         func() (io.Writer, string)
+	redactor  *Redactor
+	held      string
+	lineStart bool
+	mu        sync.Mutex
+}
+
+func newRawWriter(redactor *Redactor, w func() (io.Writer, string)) *rawWriter {
+	return &rawWriter{w: w, redactor: redactor, lineStart: true}
+}
+
+func (r *rawWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.held + string(p)
+	keep := r.redactor.PartialSuffix(s)
+	r.held = s[len(s)-keep:]
+	r.write(r.redactor.Redact(s[:len(s)-keep]))
+	return len(p), nil
+}
+
+func (r *rawWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	w, prefix := r.w()
+
+	var b strings.Builder
+	for s != "" {
+		if r.lineStart {
+			b.WriteString(prefix)
+			r.lineStart = false
+		}
+		idx := strings.IndexByte(s, '\n')
+		if idx == -1 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:idx+1])
+		s = s[idx+1:]
+		r.lineStart = true
+	}
+	_, _ = io.WriteString(w, b.String())
+}
+
+// Close writes any held output and ends an unterminated last line.
+func (r *rawWriter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.write(r.redactor.Redact(r.held))
+	r.held = ""
+	if !r.lineStart {
+		r.write("\n")
+	}
+	return nil
+}
@@ -3,10 +3,11 @@ package models
 import "time"
 
 type Result struct {
-	Executed []string
-	Skipped  []string
-	Failed   []FailedTarget
-	Duration time.Duration
+	Executed  []string
+	Skipped   []string
+	Failed    []FailedTarget
+	Duration  time.Duration
+	Durations map[string]time.Duration
 }
 
 type FailedTarget struct {
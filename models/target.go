@@ -1,5 +1,10 @@
 package models
 
+import (
+	"fmt"
+	"time"
+)
+
 type Target struct {
 	Name       string
 	BundleName string
@@ -8,17 +13,141 @@ type Target struct {
 	Out        []string
 	Deps       []string
 	Env        map[string]string
-	Cmd        string
-	Config     TargetConfig
+	Secrets    []string
+	Reports    []string
+	Coverage   []string
+	Ready      ReadyConfig
+	Restart    RestartPolicy
+	// MaxRestarts limits consecutive restarts of a dev target; 0 means no
+	// limit.
+	MaxRestarts int
+	// Critical dev targets end the whole dev session when they fail.
+	Critical bool
+	// OnChange decides how a reloading dev target reacts to a change; the
+	// first rule matching a changed path applies.
+	OnChange []OnChangeRule
+	// StopSignal and StopTimeout say how the target's process group is
+	// stopped: StopSignal first, then SIGKILL after StopTimeout.
+	StopSignal  string
+	StopTimeout time.Duration
+	Cmd         string
+	Config      TargetConfig
 }
 
 type TargetConfig struct {
 	WorkingDir string
 	Dotenv     DotenvConfig
 	Reload     bool
+	Cache      bool
 	Ignore     []string
 }
 
+type ReadyConfig struct {
+	TCP      string
+	HTTP     string
+	Log      string
+	Cmd      string
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// Enabled reports whether any readiness check is configured.
+func (r ReadyConfig) Enabled() bool {
+	return r.TCP != "" || r.HTTP != "" || r.Log != "" || r.Cmd != ""
+}
+
+type RestartPolicy string
+
+const (
+	RestartNever     RestartPolicy = "never"
+	RestartOnFailure RestartPolicy = "on-failure"
+	RestartAlways    RestartPolicy = "always"
+)
+
+func ParseRestartPolicy(s string) (RestartPolicy, error) {
+	switch RestartPolicy(s) {
+	case "", RestartNever:
+		return RestartNever, nil
+	case RestartOnFailure:
+		return RestartOnFailure, nil
+	case RestartAlways:
+		return RestartAlways, nil
+	}
+	return "", fmt.Errorf("invalid restart policy: %s (expected never, on-failure or always)", s)
+}
+
+// ShouldRestart reports whether a process that exited, successfully or
+// not, is restarted under the policy.
+func (p RestartPolicy) ShouldRestart(failed bool) bool {
+	switch p {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return failed
+	}
+	return false
+}
+
+type ChangeAction string
+
+const (
+	ChangeRestart     ChangeAction = "restart"
+	ChangeSignal      ChangeAction = "signal"
+	ChangeExec        ChangeAction = "exec"
+	ChangeRebuildOnly ChangeAction = "rebuild-only"
+)
+
+// OnChangeRule applies Action to changes of files matching Paths, or to all
+// changes when Paths is empty.
+type OnChangeRule struct {
+	Paths  []string
+	Action ChangeAction
+	Signal string
+	Exec   string
+}
+
+// ResolveAction returns the rule's action. It defaults to signal or exec
+// when only those are set, and to restart otherwise.
+func (r OnChangeRule) ResolveAction() (ChangeAction, error) {
+	switch r.Action {
+	case "":
+		if r.Signal != "" {
+			return ChangeSignal, nil
+		}
+		if r.Exec != "" {
+			return ChangeExec, nil
+		}
+		return ChangeRestart, nil
+	case ChangeRestart, ChangeRebuildOnly:
+		return r.Action, nil
+	case ChangeSignal:
+		if r.Signal == "" {
+			return "", fmt.Errorf("on_change action signal requires signal")
+		}
+		return r.Action, nil
+	case ChangeExec:
+		if r.Exec == "" {
+			return "", fmt.Errorf("on_change action exec requires exec")
+		}
+		return r.Action, nil
+	}
+	return "", fmt.Errorf("invalid on_change action: %s (expected restart, signal, exec or rebuild-only)", r.Action)
+}
+
+// Weight orders actions from least to most disruptive, so a batch of changes
+// gets the strongest reaction any of them asks for.
+func (a ChangeAction) Weight() int {
+	switch a {
+	case ChangeRebuildOnly:
+		return 1
+	case ChangeSignal, ChangeExec:
+		return 2
+	case ChangeRestart:
+		return 3
+	}
+	return 0
+}
+
 type DotenvConfig struct {
 	Enabled bool
 	Files   []string
@@ -2,6 +2,7 @@ package models
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -102,3 +103,104 @@ func TestTarget_HasSuffix(t *testing.T) {
 		})
 	}
 }
+
+func TestReadyConfig_Enabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		ready    ReadyConfig
+		expected bool
+	}{
+		{name: "no checks", ready: ReadyConfig{Timeout: time.Second}, expected: false},
+		{name: "tcp", ready: ReadyConfig{TCP: "localhost:5432"}, expected: true},
+		{name: "http", ready: ReadyConfig{HTTP: "http://localhost/health"}, expected: true},
+		{name: "log", ready: ReadyConfig{Log: "listening"}, expected: true},
+		{name: "cmd", ready: ReadyConfig{Cmd: "pg_isready"}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.ready.Enabled())
+		})
+	}
+}
+
+func TestParseRestartPolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected RestartPolicy
+		hasError bool
+	}{
+		{input: "", expected: RestartNever},
+		{input: "never", expected: RestartNever},
+		{input: "on-failure", expected: RestartOnFailure},
+		{input: "always", expected: RestartAlways},
+		{input: "sometimes", hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			policy, err := ParseRestartPolicy(tt.input)
+			if tt.hasError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, policy)
+		})
+	}
+}
+
+func TestRestartPolicy_ShouldRestart(t *testing.T) {
+	tests := []struct {
+		policy    RestartPolicy
+		onSuccess bool
+		onFailure bool
+	}{
+		{policy: RestartNever, onSuccess: false, onFailure: false},
+		{policy: RestartOnFailure, onSuccess: false, onFailure: true},
+		{policy: RestartAlways, onSuccess: true, onFailure: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			assert.Equal(t, tt.onSuccess, tt.policy.ShouldRestart(false))
+			assert.Equal(t, tt.onFailure, tt.policy.ShouldRestart(true))
+		})
+	}
+}
+
+func TestOnChangeRule_ResolveAction(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     OnChangeRule
+		expected ChangeAction
+		hasError bool
+	}{
+		{name: "default", rule: OnChangeRule{}, expected: ChangeRestart},
+		{name: "signal only", rule: OnChangeRule{Signal: "HUP"}, expected: ChangeSignal},
+		{name: "exec only", rule: OnChangeRule{Exec: "make reload"}, expected: ChangeExec},
+		{name: "rebuild only", rule: OnChangeRule{Action: ChangeRebuildOnly}, expected: ChangeRebuildOnly},
+		{name: "explicit restart", rule: OnChangeRule{Action: ChangeRestart}, expected: ChangeRestart},
+		{name: "signal without signal", rule: OnChangeRule{Action: ChangeSignal}, hasError: true},
+		{name: "exec without command", rule: OnChangeRule{Action: ChangeExec}, hasError: true},
+		{name: "unknown", rule: OnChangeRule{Action: "reload"}, hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, err := tt.rule.ResolveAction()
+			if tt.hasError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, action)
+		})
+	}
+}
+
+func TestChangeAction_Weight(t *testing.T) {
+	assert.Less(t, ChangeRebuildOnly.Weight(), ChangeSignal.Weight())
+	assert.Equal(t, ChangeSignal.Weight(), ChangeExec.Weight())
+	assert.Less(t, ChangeExec.Weight(), ChangeRestart.Weight())
+}
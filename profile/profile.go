@@ -0,0 +1,195 @@
+// Package profile records where the wall time of a run goes and writes it in
+// the Chrome trace-event format, which chrome://tracing and Perfetto load
+// directly.
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	workersPID = 1
+	waitingPID = 2
+)
+
+// Phases recorded for every target.
+const (
+	PhaseDependencyWait = "dependency wait"
+	PhaseSlotWait       = "worker wait"
+	PhaseHashing        = "hashing"
+	PhaseCacheLookup    = "cache lookup"
+	PhaseCommand        = "command"
+)
+
+type traceEvent struct {
+	Name  string         `json:"name"`
+	Cat   string         `json:"cat,omitempty"`
+	Phase string         `json:"ph"`
+	TS    int64          `json:"ts"`
+	Dur   *int64         `json:"dur,omitempty"`
+	PID   int            `json:"pid"`
+	TID   int            `json:"tid"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+type traceFile struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// Profile collects spans and counters in memory until WriteFile. A nil
+// *Profile is valid and records nothing.
+type Profile struct {
+	start    time.Time
+	events   []traceEvent
+	slots    map[int]bool
+	waiting  map[string]int
+	counters map[string]int
+	mu       sync.Mutex
+}
+
+func New() *Profile {
+	return &Profile{
+		start:    time.Now(),
+		slots:    make(map[int]bool),
+		waiting:  make(map[string]int),
+		counters: make(map[string]int),
+	}
+}
+
+// Span records a phase of target on worker slot.
+func (p *Profile) Span(slot int, target, phase string, start, end time.Time) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.slots[slot] {
+		p.slots[slot] = true
+		p.events = append(p.events, threadName(workersPID, slot+1, fmt.Sprintf("worker %d", slot+1)))
+	}
+	p.events = append(p.events, p.complete(workersPID, slot+1, target, phase, start, end))
+}
+
+// Wait records time target spent queued before it was given a worker slot.
+// Waits are drawn on one row per target, since they do not occupy a worker.
+func (p *Profile) Wait(target, phase string, start, end time.Time) {
+	if p == nil || !end.After(start) {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tid, ok := p.waiting[target]
+	if !ok {
+		tid = len(p.waiting) + 1
+		p.waiting[target] = tid
+		p.events = append(p.events, threadName(waitingPID, tid, target))
+	}
+	p.events = append(p.events, p.complete(waitingPID, tid, target, phase, start, end))
+}
+
+// Add changes counter name by delta at time t. Counters are drawn as a graph
+// of their value over time.
+func (p *Profile) Add(name string, delta int, t time.Time) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.counters[name] += delta
+	p.events = append(p.events, traceEvent{
+		Name:  name,
+		Phase: "C",
+		TS:    p.ts(t),
+		PID:   workersPID,
+		Args:  map[string]any{name: p.counters[name]},
+	})
+}
+
+func (p *Profile) WriteFile(path string) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	events := make([]traceEvent, 0, len(p.events)+2)
+	events = append(events,
+		processName(workersPID, "workers"),
+		processName(waitingPID, "waiting"),
+	)
+	events = append(events, p.events...)
+	p.mu.Unlock()
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].TS < events[j].TS
+	})
+
+	data, err := json.Marshal(&traceFile{
+		TraceEvents:     events,
+		DisplayTimeUnit: "ms",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create profile directory: %w", err)
+		}
+	}
+
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write profile %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (p *Profile) complete(pid, tid int, target, phase string, start, end time.Time) traceEvent {
+	dur := end.Sub(start).Microseconds()
+	return traceEvent{
+		Name:  phase + " " + target,
+		Cat:   phase,
+		Phase: "X",
+		TS:    p.ts(start),
+		Dur:   &dur,
+		PID:   pid,
+		TID:   tid,
+		Args:  map[string]any{"target": target},
+	}
+}
+
+func (p *Profile) ts(t time.Time) int64 {
+	return t.Sub(p.start).Microseconds()
+}
+
+func processName(pid int, name string) traceEvent {
+	return traceEvent{
+		Name:  "process_name",
+		Phase: "M",
+		PID:   pid,
+		Args:  map[string]any{"name": name},
+	}
+}
+
+func threadName(pid, tid int, name string) traceEvent {
+	return traceEvent{
+		Name:  "thread_name",
+		Phase: "M",
+		PID:   pid,
+		TID:   tid,
+		Args:  map[string]any{"name": name},
+	}
+}
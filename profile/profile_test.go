@@ -0,0 +1,86 @@
+package profile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTrace(t *testing.T, path string) []traceEvent {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var file traceFile
+	require.NoError(t, json.Unmarshal(data, &file))
+	assert.Equal(t, "ms", file.DisplayTimeUnit)
+	return file.TraceEvents
+}
+
+func TestProfile_WriteFile(t *testing.T) {
+	p := New()
+	base := p.start
+
+	p.Wait("b:build", PhaseDependencyWait, base, base.Add(10*time.Millisecond))
+	p.Wait("b:build", PhaseSlotWait, base.Add(10*time.Millisecond), base.Add(10*time.Millisecond))
+	p.Span(0, "a:build", PhaseHashing, base, base.Add(2*time.Millisecond))
+	p.Span(0, "a:build", PhaseCommand, base.Add(2*time.Millisecond), base.Add(10*time.Millisecond))
+	p.Span(1, "b:build", PhaseCommand, base.Add(10*time.Millisecond), base.Add(15*time.Millisecond))
+	p.Add("running commands", 1, base.Add(2*time.Millisecond))
+	p.Add("running commands", -1, base.Add(10*time.Millisecond))
+
+	path := filepath.Join(t.TempDir(), "out", "trace.json")
+	require.NoError(t, p.WriteFile(path))
+
+	events := readTrace(t, path)
+
+	var threads []string
+	var spans []traceEvent
+	var counters []int
+	for i, e := range events {
+		if i > 0 {
+			assert.LessOrEqual(t, events[i-1].TS, e.TS, "events are sorted by timestamp")
+		}
+		switch e.Phase {
+		case "M":
+			if e.Name == "thread_name" {
+				threads = append(threads, e.Args["name"].(string))
+			}
+		case "X":
+			spans = append(spans, e)
+		case "C":
+			counters = append(counters, int(e.Args["running commands"].(float64)))
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"b:build", "worker 1", "worker 2"}, threads)
+	assert.Equal(t, []int{1, 0}, counters)
+
+	require.Len(t, spans, 4, "zero-length waits are dropped")
+	assert.Equal(t, "dependency wait b:build", spans[0].Name)
+	assert.Equal(t, waitingPID, spans[0].PID)
+	assert.Equal(t, int64(10000), *spans[0].Dur)
+
+	command := spans[3]
+	assert.Equal(t, PhaseCommand, command.Cat)
+	assert.Equal(t, workersPID, command.PID)
+	assert.Equal(t, 2, command.TID)
+	assert.Equal(t, int64(10000), command.TS)
+	assert.Equal(t, int64(5000), *command.Dur)
+	assert.Equal(t, "b:build", command.Args["target"])
+}
+
+func TestProfile_Nil(t *testing.T) {
+	var p *Profile
+	assert.NotPanics(t, func() {
+		p.Span(0, "a", PhaseCommand, time.Now(), time.Now())
+		p.Wait("a", PhaseDependencyWait, time.Now(), time.Now().Add(time.Second))
+		p.Add("running commands", 1, time.Now())
+	})
+	assert.NoError(t, p.WriteFile(filepath.Join(t.TempDir(), "trace.json")))
+}
@@ -0,0 +1,180 @@
+// Package ready implements the readiness checks of dev targets: a TCP port
+// accepting connections, an HTTP endpoint answering 2xx, a log line matching
+// a pattern or a command exiting successfully.
+package ready
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/vcnkl/rpm/exec"
+)
+
+type Probe interface {
+	// Name describes the probe in log output and errors.
+	Name() string
+	Probe(ctx context.Context) error
+}
+
+type TCP struct {
+	Addr string
+}
+
+func (p *TCP) Name() string {
+	return "tcp " + p.Addr
+}
+
+func (p *TCP) Probe(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", p.Addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+type HTTP struct {
+	URL string
+}
+
+func (p *HTTP) Name() string {
+	return "http " + p.URL
+}
+
+func (p *HTTP) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Log passes once Observe has been called with a matching line. Observe is
+// safe to call from the goroutines reading the target's output.
+type Log struct {
+	pattern *regexp.Regexp
+	matched atomic.Bool
+}
+
+func NewLog(pattern string) (*Log, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log pattern %q: %w", pattern, err)
+	}
+	return &Log{pattern: re}, nil
+}
+
+func (p *Log) Name() string {
+	return "log /" + p.pattern.String() + "/"
+}
+
+func (p *Log) Observe(line string) {
+	if !p.matched.Load() && p.pattern.MatchString(line) {
+		p.matched.Store(true)
+	}
+}
+
+// Reset forgets earlier matches, for when the process is restarted.
+func (p *Log) Reset() {
+	p.matched.Store(false)
+}
+
+func (p *Log) Probe(ctx context.Context) error {
+	if !p.matched.Load() {
+		return fmt.Errorf("no matching line yet")
+	}
+	return nil
+}
+
+type Cmd struct {
+	Cmd  string
+	Opts exec.ShellOptions
+}
+
+func (p *Cmd) Name() string {
+	return "cmd " + p.Cmd
+}
+
+func (p *Cmd) Probe(ctx context.Context) error {
+	var out strings.Builder
+	opts := p.Opts
+	opts.Stdout = &out
+	opts.Stderr = &out
+	if err := exec.RunCommand(ctx, p.Cmd, &opts); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+type TimeoutError struct {
+	Probe   string
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("not ready after %s: %s: %v", e.Timeout, e.Probe, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Wait polls every interval until all probes pass in the same round, ctx is
+// done or timeout elapses.
+func Wait(ctx context.Context, probes []Probe, interval, timeout time.Duration) error {
+	if len(probes) == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		failed, err := round(ctx, probes, interval)
+		if failed == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return &TimeoutError{Probe: failed.Name(), Timeout: timeout, Err: err}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// round runs each probe with at most interval to answer and returns the first
+// one that failed.
+func round(ctx context.Context, probes []Probe, interval time.Duration) (Probe, error) {
+	for _, p := range probes {
+		probeCtx, cancel := context.WithTimeout(ctx, max(interval, time.Second))
+		err := p.Probe(probeCtx)
+		cancel()
+		if err != nil {
+			return p, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,144 @@
+package ready
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vcnkl/rpm/exec"
+)
+
+func TestProbes(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	shell := exec.ShellOptions{Shell: "/bin/sh", Env: []string{"PATH=" + os.Getenv("PATH")}}
+
+	tests := []struct {
+		name     string
+		probe    Probe
+		errorMsg string
+	}{
+		{
+			name:  "tcp listening",
+			probe: &TCP{Addr: listener.Addr().String()},
+		},
+		{
+			name:     "tcp closed",
+			probe:    &TCP{Addr: closedAddr},
+			errorMsg: "connection refused",
+		},
+		{
+			name:  "http 2xx",
+			probe: &HTTP{URL: server.URL + "/health"},
+		},
+		{
+			name:     "http non-2xx",
+			probe:    &HTTP{URL: server.URL + "/other"},
+			errorMsg: "status 503",
+		},
+		{
+			name:  "cmd succeeds",
+			probe: &Cmd{Cmd: "true", Opts: shell},
+		},
+		{
+			name:     "cmd fails with output",
+			probe:    &Cmd{Cmd: "echo not yet; exit 2", Opts: shell},
+			errorMsg: "command exited with status 2: not yet",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.probe.Probe(context.Background())
+			if tt.errorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestLog(t *testing.T) {
+	_, err := NewLog("(")
+	require.Error(t, err)
+
+	probe, err := NewLog(`listening on :\d+`)
+	require.NoError(t, err)
+	assert.Equal(t, `log /listening on :\d+/`, probe.Name())
+
+	require.Error(t, probe.Probe(context.Background()))
+
+	probe.Observe("starting")
+	require.Error(t, probe.Probe(context.Background()))
+
+	probe.Observe("listening on :8080")
+	require.NoError(t, probe.Probe(context.Background()))
+
+	probe.Reset()
+	require.Error(t, probe.Probe(context.Background()))
+}
+
+func TestWait(t *testing.T) {
+	t.Run("no probes", func(t *testing.T) {
+		require.NoError(t, Wait(context.Background(), nil, time.Millisecond, time.Millisecond))
+	})
+
+	t.Run("passes once all probes pass", func(t *testing.T) {
+		log, err := NewLog("ready")
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(30 * time.Millisecond)
+			log.Observe("ready")
+		}()
+
+		err = Wait(context.Background(), []Probe{log}, 10*time.Millisecond, time.Second)
+		require.NoError(t, err)
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		log, err := NewLog("ready")
+		require.NoError(t, err)
+
+		err = Wait(context.Background(), []Probe{log}, 10*time.Millisecond, 50*time.Millisecond)
+		var timeoutErr *TimeoutError
+		require.True(t, errors.As(err, &timeoutErr))
+		assert.Equal(t, "log /ready/", timeoutErr.Probe)
+		assert.Equal(t, "not ready after 50ms: log /ready/: no matching line yet", err.Error())
+	})
+
+	t.Run("stops when cancelled", func(t *testing.T) {
+		log, err := NewLog("ready")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err = Wait(ctx, []Probe{log}, 10*time.Millisecond, time.Second)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
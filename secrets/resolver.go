@@ -0,0 +1,135 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/exec"
+	"github.com/vcnkl/rpm/logger"
+)
+
+// Resolver fetches secret values on first use and caches them for the rest of
+// the invocation. Every resolved value is registered with the logger's
+// redactor so it is masked in log output.
+type Resolver struct {
+	repoRoot string
+	shell    string
+	sources  map[string]config.SecretConfig
+	log      logger.Logger
+	values   map[string]string
+	mu       sync.Mutex
+}
+
+// NewResolver resolves the secrets of repo, running secret commands with its
+// shell.
+func NewResolver(repoRoot string, repo *config.RepoConfig, log logger.Logger) *Resolver {
+	return &Resolver{
+		repoRoot: repoRoot,
+		shell:    repo.Shell,
+		sources:  repo.Secrets,
+		log:      log,
+		values:   make(map[string]string),
+	}
+}
+
+func (r *Resolver) Resolve(name string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if value, ok := r.values[name]; ok {
+		return value, nil
+	}
+
+	source, ok := r.sources[name]
+	if !ok {
+		return "", &NotFoundError{Name: name}
+	}
+
+	value, err := r.fetch(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %s: %w", name, err)
+	}
+
+	if !logger.CanRedact(value) {
+		r.log.Warn("secret is too short to be masked in output", logger.String("secret", name))
+	}
+	r.log.Redactor().Add(value)
+	r.values[name] = value
+
+	return value, nil
+}
+
+func (r *Resolver) fetch(source config.SecretConfig) (string, error) {
+	switch {
+	case source.Cmd != "":
+		var stdout bytes.Buffer
+		cmd := exec.ShellCommand(source.Cmd, &exec.ShellOptions{
+			WorkDir: r.repoRoot,
+			Shell:   r.shell,
+			Stdout:  &stdout,
+			Stderr:  os.Stderr,
+			// the command may prompt, such as for a password
+			Foreground: true,
+		})
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("command failed: %w", err)
+		}
+		return strings.TrimRight(stdout.String(), "\r\n"), nil
+	case source.File != "":
+		data, err := os.ReadFile(r.resolvePath(source.File))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case source.Env != "":
+		value, ok := os.LookupEnv(source.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", source.Env)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("no source configured (expected cmd, file or env)")
+}
+
+func (r *Resolver) resolvePath(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(r.repoRoot, path)
+}
+
+// Unresolved stands in for a Resolver where secret sources must not be
+// read, such as dry runs. It checks that secrets are defined and returns a
+// placeholder instead of their value.
+type Unresolved struct {
+	sources map[string]config.SecretConfig
+}
+
+func NewUnresolved(sources map[string]config.SecretConfig) *Unresolved {
+	return &Unresolved{sources: sources}
+}
+
+func (u *Unresolved) Resolve(name string) (string, error) {
+	if _, ok := u.sources[name]; !ok {
+		return "", &NotFoundError{Name: name}
+	}
+	return "<secret>", nil
+}
+
+type NotFoundError struct {
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return "secret not defined in repo.yml: " + e.Name
+}
@@ -0,0 +1,202 @@
+package secrets
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vcnkl/rpm/config"
+	"github.com/vcnkl/rpm/exec"
+	"github.com/vcnkl/rpm/logger"
+	"github.com/vcnkl/rpm/models"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	repoRoot := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(repoRoot, "token.txt"), []byte("file-secret\n"), 0600))
+	t.Setenv("RPM_TEST_SECRET", "env-secret")
+
+	tests := []struct {
+		name        string
+		source      config.SecretConfig
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "command source",
+			source:   config.SecretConfig{Cmd: "printf 'cmd-secret\\n'"},
+			expected: "cmd-secret",
+		},
+		{
+			name:     "file source relative to repo root",
+			source:   config.SecretConfig{File: "token.txt"},
+			expected: "file-secret",
+		},
+		{
+			name:     "env source",
+			source:   config.SecretConfig{Env: "RPM_TEST_SECRET"},
+			expected: "env-secret",
+		},
+		{
+			name:        "failing command",
+			source:      config.SecretConfig{Cmd: "exit 3"},
+			expectError: true,
+		},
+		{
+			name:        "missing file",
+			source:      config.SecretConfig{File: "missing.txt"},
+			expectError: true,
+		},
+		{
+			name:        "unset env",
+			source:      config.SecretConfig{Env: "RPM_TEST_SECRET_UNSET"},
+			expectError: true,
+		},
+		{
+			name:        "no source",
+			source:      config.SecretConfig{},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.NewWithWriter(logger.InfoLevel, io.Discard)
+			redactor := log.Redactor()
+			resolver := NewResolver(repoRoot, &config.RepoConfig{
+				Shell:   "/bin/sh",
+				Secrets: map[string]config.SecretConfig{"TOKEN": tt.source},
+			}, log)
+
+			value, err := resolver.Resolve("TOKEN")
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "TOKEN")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+			assert.Equal(t, "token=***", redactor.Redact("token="+tt.expected))
+		})
+	}
+}
+
+func TestResolver_ResolveCachesValue(t *testing.T) {
+	repoRoot := t.TempDir()
+	counter := filepath.Join(repoRoot, "count")
+
+	resolver := NewResolver(repoRoot, &config.RepoConfig{
+		Shell:   "/bin/sh",
+		Secrets: map[string]config.SecretConfig{"TOKEN": {Cmd: "echo x >> count; echo secret"}},
+	}, logger.NewWithWriter(logger.InfoLevel, io.Discard))
+
+	for i := 0; i < 3; i++ {
+		value, err := resolver.Resolve("TOKEN")
+		require.NoError(t, err)
+		assert.Equal(t, "secret", value)
+	}
+
+	data, err := os.ReadFile(counter)
+	require.NoError(t, err)
+	assert.Equal(t, "x\n", string(data))
+}
+
+func TestResolver_ResolveUndefined(t *testing.T) {
+	resolver := NewResolver(t.TempDir(), &config.RepoConfig{}, logger.NewWithWriter(logger.InfoLevel, io.Discard))
+
+	_, err := resolver.Resolve("MISSING")
+
+	var notFound *NotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, "MISSING", notFound.Name)
+}
+
+func TestResolver_UsesRepoShell(t *testing.T) {
+	if _, err := os.Stat("/bin/bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	resolver := NewResolver(t.TempDir(), &config.RepoConfig{
+		Shell:   "/bin/bash",
+		Secrets: map[string]config.SecretConfig{"TOKEN": {Cmd: "[[ -n $BASH_VERSION ]] && echo from-bash"}},
+	}, logger.NewWithWriter(logger.InfoLevel, io.Discard))
+
+	value, err := resolver.Resolve("TOKEN")
+	require.NoError(t, err)
+	assert.Equal(t, "from-bash", value)
+}
+
+func TestResolver_WarnsOnShortSecret(t *testing.T) {
+	t.Setenv("RPM_TEST_PIN", "123")
+	t.Setenv("RPM_TEST_TOKEN", "long-enough")
+
+	var buf bytes.Buffer
+	resolver := NewResolver(t.TempDir(), &config.RepoConfig{
+		Secrets: map[string]config.SecretConfig{
+			"PIN":   {Env: "RPM_TEST_PIN"},
+			"TOKEN": {Env: "RPM_TEST_TOKEN"},
+		},
+	}, logger.NewWithWriter(logger.InfoLevel, &buf))
+
+	_, err := resolver.Resolve("PIN")
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "too short to be masked")
+	assert.Contains(t, buf.String(), "PIN")
+
+	buf.Reset()
+	_, err = resolver.Resolve("TOKEN")
+	require.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
+
+func TestUnresolved(t *testing.T) {
+	repoRoot := t.TempDir()
+	unresolved := NewUnresolved(map[string]config.SecretConfig{
+		"TOKEN": {Cmd: "touch " + filepath.Join(repoRoot, "ran")},
+	})
+
+	value, err := unresolved.Resolve("TOKEN")
+	require.NoError(t, err)
+	assert.Equal(t, "<secret>", value)
+	assert.NoFileExists(t, filepath.Join(repoRoot, "ran"))
+
+	_, err = unresolved.Resolve("MISSING")
+	var notFound *NotFoundError
+	require.ErrorAs(t, err, &notFound)
+}
+
+func TestComposeEnv_Secrets(t *testing.T) {
+	t.Setenv("RPM_TEST_DB_PASSWORD", "hunter2")
+
+	repo := &config.RepoConfig{
+		Secrets: map[string]config.SecretConfig{
+			"DB_PASSWORD": {Env: "RPM_TEST_DB_PASSWORD"},
+			"UNUSED":      {Cmd: "exit 1"},
+		},
+	}
+	bundle := &models.Bundle{Name: "core", Path: "core"}
+	log := logger.NewWithWriter(logger.InfoLevel, io.Discard)
+	resolver := NewResolver("/repo", repo, log)
+
+	target := &models.Target{
+		Name:       "app_build",
+		BundleName: "core",
+		BundlePath: "core",
+		Secrets:    []string{"DB_PASSWORD"},
+	}
+
+	result, err := exec.ComposeEnv("/repo", repo, bundle, target, resolver)
+	require.NoError(t, err)
+	assert.Contains(t, result, "DB_PASSWORD=hunter2")
+	assert.Equal(t, "DB_PASSWORD=***", log.Redactor().Redact("DB_PASSWORD=hunter2"))
+
+	target.Secrets = []string{"NOT_DEFINED"}
+	_, err = exec.ComposeEnv("/repo", repo, bundle, target, resolver)
+	var notFound *NotFoundError
+	require.ErrorAs(t, err, &notFound)
+}
@@ -0,0 +1,187 @@
+// Package socket serves and calls the unix sockets rpm processes talk over.
+// Every connection carries one JSON request line, answered by one or more
+// JSON response lines.
+package socket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrInUse is returned by Listen when another process serves the socket.
+var ErrInUse = errors.New("socket is in use")
+
+// ErrUnavailable is returned by clients when nothing serves the socket.
+var ErrUnavailable = errors.New("socket is not served")
+
+// Conn is one connection to or from a socket.
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	enc  *json.Encoder
+}
+
+func newConn(conn net.Conn) *Conn {
+	return &Conn{conn: conn, r: bufio.NewReader(conn), enc: json.NewEncoder(conn)}
+}
+
+// Receive decodes the next JSON line into v. It returns io.EOF once the
+// other side closed the connection.
+func (c *Conn) Receive(v any) error {
+	line, err := c.r.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return err
+	}
+	return json.Unmarshal(line, v)
+}
+
+// Send writes v as a JSON line.
+func (c *Conn) Send(v any) error {
+	return c.enc.Encode(v)
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+type Server struct {
+	path     string
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// Listen creates the socket at path, replacing a stale one left by a
+// process that did not shut down cleanly.
+func Listen(path string) (*Server, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, ErrInUse
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	return &Server{path: path, listener: listener}, nil
+}
+
+// Serve calls handle for every connection until ctx is done, then cancels
+// the handlers' context, waits for them and removes the socket. The
+// connection is closed when handle returns.
+func (s *Server) Serve(ctx context.Context, handle func(ctx context.Context, conn *Conn)) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		s.listener.Close()
+	}()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			break
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			c := newConn(conn)
+			defer c.Close()
+			handle(ctx, c)
+		}()
+	}
+
+	cancel()
+	s.wg.Wait()
+	os.Remove(s.path)
+}
+
+type Client struct {
+	path    string
+	timeout time.Duration
+}
+
+// NewClient returns a client of the socket at path. A non-zero timeout
+// bounds connecting and every Call; 0 means no limit.
+func NewClient(path string, timeout time.Duration) *Client {
+	return &Client{path: path, timeout: timeout}
+}
+
+// Open connects and sends req, leaving the responses to the caller.
+func (c *Client) Open(req any) (*Conn, error) {
+	var conn net.Conn
+	var err error
+	if c.timeout > 0 {
+		conn, err = net.DialTimeout("unix", c.path, c.timeout)
+	} else {
+		conn, err = net.Dial("unix", c.path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	cn := newConn(conn)
+	if err = cn.Send(req); err != nil {
+		cn.Close()
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return cn, nil
+}
+
+// Call sends req and decodes the single response into resp. A response
+// whose "error" field is set is returned as an error.
+func (c *Client) Call(req, resp any) error {
+	conn, err := c.Open(req)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if c.timeout > 0 {
+		if err = conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+			return fmt.Errorf("failed to set deadline: %w", err)
+		}
+	}
+	var raw json.RawMessage
+	if err = conn.Receive(&raw); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	return Decode(raw, resp)
+}
+
+// ErrorResponse holds the error every response may carry.
+type ErrorResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Decode decodes one response line into resp, returning the error it
+// carries instead.
+func Decode(data []byte, resp any) error {
+	var e ErrorResponse
+	if err := json.Unmarshal(data, &e); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if e.Error != "" {
+		return errors.New(e.Error)
+	}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package socket
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type request struct {
+	Cmd string `json:"cmd"`
+}
+
+type response struct {
+	Error string `json:"error,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+func serve(t *testing.T, handle func(ctx context.Context, conn *Conn)) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.sock")
+	server, err := Listen(path)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.Serve(ctx, handle)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		assert.NoFileExists(t, path)
+	})
+	return path
+}
+
+func TestClient_Call(t *testing.T) {
+	path := serve(t, func(ctx context.Context, conn *Conn) {
+		var req request
+		if err := conn.Receive(&req); err != nil {
+			conn.Send(response{Error: "invalid request: " + err.Error()})
+			return
+		}
+		switch req.Cmd {
+		case "echo":
+			conn.Send(response{Value: "echo"})
+		case "hang":
+			<-ctx.Done()
+		default:
+			conn.Send(response{Error: "unknown command: " + req.Cmd})
+		}
+	})
+
+	tests := []struct {
+		name     string
+		cmd      string
+		expected string
+		err      string
+	}{
+		{name: "response", cmd: "echo", expected: "echo"},
+		{name: "error response", cmd: "nope", err: "unknown command: nope"},
+		{name: "no response in time", cmd: "hang", err: "failed to read response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp response
+			err := NewClient(path, 100*time.Millisecond).Call(request{Cmd: tt.cmd}, &resp)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, resp.Value)
+		})
+	}
+}
+
+func TestClient_Unavailable(t *testing.T) {
+	var resp response
+	err := NewClient(filepath.Join(t.TempDir(), "test.sock"), time.Second).Call(request{}, &resp)
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestListen(t *testing.T) {
+	path := serve(t, func(ctx context.Context, conn *Conn) {})
+
+	_, err := Listen(path)
+	assert.ErrorIs(t, err, ErrInUse)
+}
+
+func TestListen_StaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+
+	server, err := Listen(path)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	server.Serve(ctx, func(ctx context.Context, conn *Conn) {})
+	assert.NoFileExists(t, path)
+}
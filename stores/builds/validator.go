@@ -1,6 +1,9 @@
 package builds
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,37 +15,72 @@ import (
 type Validator struct {
 	repoRoot string
 	store    *Store
+	hasher   hashing.InputHasher
 }
 
-func NewValidator(repoRoot string, store *Store) *Validator {
+// NewValidator checks targets against store, hashing inputs with hasher,
+// or in-process if it is nil.
+func NewValidator(repoRoot string, store *Store, hasher hashing.InputHasher) *Validator {
+	if hasher == nil {
+		hasher = hashing.Local
+	}
 	return &Validator{
 		repoRoot: repoRoot,
 		store:    store,
+		hasher:   hasher,
 	}
 }
 
 func (v *Validator) ShouldBuild(target *models.Target) (bool, string, error) {
+	currentHash, err := v.HashInputs(target)
+	if err != nil {
+		return true, currentHash, err
+	}
+
+	return !v.IsCached(target, currentHash), currentHash, nil
+}
+
+func (v *Validator) HashInputs(target *models.Target) (string, error) {
 	bundleRoot := filepath.Join(v.repoRoot, target.BundlePath)
+	return v.hasher.HashInputs(bundleRoot, target.In)
+}
 
-	currentHash, err := hashing.HashInputs(bundleRoot, target.In)
+// CacheKey combines the target's input hash, command, dotenv files and the
+// environment it runs with (only ever stored hashed) with the cache keys of
+// its dependencies.
+func (v *Validator) CacheKey(target *models.Target, env []string, depKeys map[string]string) (string, error) {
+	inputHash, err := v.HashInputs(target)
 	if err != nil {
-		return true, currentHash, err
+		return "", err
 	}
 
-	entry, ok := v.store.Get(target.ID())
-	if !ok {
-		return true, currentHash, nil
+	var dotenvHash string
+	if target.Config.Dotenv.Enabled {
+		bundleRoot := filepath.Join(v.repoRoot, target.BundlePath)
+		patterns := append([]string{".env"}, target.Config.Dotenv.Files...)
+		if dotenvHash, err = v.hasher.HashInputs(bundleRoot, patterns); err != nil {
+			return "", err
+		}
 	}
 
-	if entry.InputHash != currentHash {
-		return true, currentHash, nil
+	h := sha256.New()
+	fmt.Fprintf(h, "in=%s\ncmd=%s\nenv=%s\ndotenv=%s", inputHash, target.Cmd, hashing.HashEnv(env, os.Environ()), dotenvHash)
+	return hashing.CombineHashes("sha256:"+hex.EncodeToString(h.Sum(nil)), depKeys), nil
+}
+
+// IsCached reports whether the stored entry for target matches inputHash and
+// all declared outputs still exist.
+func (v *Validator) IsCached(target *models.Target, inputHash string) bool {
+	entry, ok := v.store.Get(target.ID())
+	if !ok {
+		return false
 	}
 
-	if !v.outputsExist(target) {
-		return true, currentHash, nil
+	if entry.InputHash != inputHash {
+		return false
 	}
 
-	return false, currentHash, nil
+	return v.outputsExist(target)
 }
 
 func (v *Validator) outputsExist(target *models.Target) bool {
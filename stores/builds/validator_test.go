@@ -13,14 +13,14 @@ import (
 
 func TestNewValidator(t *testing.T) {
 	store := NewStore("")
-	v := NewValidator("/repo", store)
+	v := NewValidator("/repo", store, nil)
 	assert.NotNil(t, v)
 	assert.Equal(t, "/repo", v.repoRoot)
 	assert.Equal(t, store, v.store)
 }
 
 func TestValidator_ResolveOutputPath(t *testing.T) {
-	v := NewValidator("/repo", NewStore(""))
+	v := NewValidator("/repo", NewStore(""), nil)
 
 	tests := []struct {
 		name       string
@@ -138,7 +138,7 @@ func TestValidator_OutputsExist(t *testing.T) {
 				require.NoError(t, os.WriteFile(fullPath, []byte("content"), 0644))
 			}
 
-			v := NewValidator(tmpDir, NewStore(""))
+			v := NewValidator(tmpDir, NewStore(""), nil)
 			target := &models.Target{
 				BundlePath: bundlePath,
 				Out:        tt.outputs,
@@ -218,7 +218,7 @@ func TestValidator_ShouldBuild(t *testing.T) {
 			}
 
 			if tt.cachedHash == "MATCH" {
-				v := NewValidator(tmpDir, store)
+				v := NewValidator(tmpDir, store, nil)
 				shouldBuild, hash, _ := v.ShouldBuild(target)
 				_ = shouldBuild
 				store.Set(target.ID(), &Entry{InputHash: hash})
@@ -226,7 +226,7 @@ func TestValidator_ShouldBuild(t *testing.T) {
 				store.Set(target.ID(), &Entry{InputHash: tt.cachedHash})
 			}
 
-			v := NewValidator(tmpDir, store)
+			v := NewValidator(tmpDir, store, nil)
 			shouldBuild, _, err := v.ShouldBuild(target)
 
 			if tt.expectHashError {
@@ -238,3 +238,78 @@ func TestValidator_ShouldBuild(t *testing.T) {
 		})
 	}
 }
+
+func TestValidator_CacheKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	bundleRoot := filepath.Join(tmpDir, "core")
+	require.NoError(t, os.MkdirAll(bundleRoot, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(bundleRoot, "main.go"), []byte("package main"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(bundleRoot, ".env"), []byte("A=1\n"), 0644))
+
+	newTarget := func() *models.Target {
+		return &models.Target{
+			Name:       "unit_test",
+			BundleName: "core",
+			BundlePath: "core",
+			In:         []string{"*.go"},
+			Cmd:        "go test ./...",
+			Config:     models.TargetConfig{Dotenv: models.DotenvConfig{Enabled: true}},
+		}
+	}
+	env := []string{"PORT=8080", "TOKEN=secret"}
+	deps := map[string]string{"core:gen": "sha256:dep"}
+
+	v := NewValidator(tmpDir, NewStore(""), nil)
+	base, err := v.CacheKey(newTarget(), env, deps)
+	require.NoError(t, err)
+	assert.NotContains(t, base, "secret")
+
+	same, err := v.CacheKey(newTarget(), env, deps)
+	require.NoError(t, err)
+	assert.Equal(t, base, same)
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T, target *models.Target, env []string, deps map[string]string) ([]string, map[string]string)
+	}{
+		{
+			name: "command",
+			change: func(t *testing.T, target *models.Target, env []string, deps map[string]string) ([]string, map[string]string) {
+				target.Cmd = "go test -race ./..."
+				return env, deps
+			},
+		},
+		{
+			name: "env",
+			change: func(t *testing.T, target *models.Target, env []string, deps map[string]string) ([]string, map[string]string) {
+				return []string{"PORT=8080", "TOKEN=other"}, deps
+			},
+		},
+		{
+			name: "dependency key",
+			change: func(t *testing.T, target *models.Target, env []string, deps map[string]string) ([]string, map[string]string) {
+				return env, map[string]string{"core:gen": "sha256:changed"}
+			},
+		},
+		{
+			name: "dotenv file",
+			change: func(t *testing.T, target *models.Target, env []string, deps map[string]string) ([]string, map[string]string) {
+				require.NoError(t, os.WriteFile(filepath.Join(bundleRoot, ".env"), []byte("A=2\n"), 0644))
+				t.Cleanup(func() {
+					_ = os.WriteFile(filepath.Join(bundleRoot, ".env"), []byte("A=1\n"), 0644)
+				})
+				return env, deps
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTarget()
+			changedEnv, changedDeps := tt.change(t, target, env, deps)
+			key, err := v.CacheKey(target, changedEnv, changedDeps)
+			require.NoError(t, err)
+			assert.NotEqual(t, base, key)
+		})
+	}
+}
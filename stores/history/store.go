@@ -0,0 +1,164 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusFailed  Status = "failed"
+	StatusCached  Status = "cached"
+)
+
+type Run struct {
+	ID         string      `json:"id"`
+	Command    string      `json:"command"`
+	Args       []string    `json:"args"`
+	Commit     string      `json:"commit,omitempty"`
+	StartedAt  time.Time   `json:"started_at"`
+	DurationMs int64       `json:"duration_ms"`
+	Selected   []string    `json:"selected"`
+	Targets    []TargetRun `json:"targets"`
+}
+
+type TargetRun struct {
+	ID         string `json:"id"`
+	Status     Status `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	ExitCode   int    `json:"exit_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (r *Run) Count(status Status) int {
+	n := 0
+	for _, t := range r.Targets {
+		if t.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Store is an append-only JSON-lines file of runs, oldest first.
+type Store struct {
+	path string
+}
+
+func NewStore(path string) *Store {
+	return &Store{
+		path: path,
+	}
+}
+
+func (s *Store) Append(run *Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to serialize run: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file %s: %w", s.path, err)
+	}
+	defer f.Close()
+
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history file %s: %w", s.path, err)
+	}
+
+	return nil
+}
+
+// Load returns all recorded runs, oldest first. Lines that cannot be parsed
+// (e.g. a run interrupted mid-write) are skipped.
+func (s *Store) Load() ([]*Run, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return []*Run{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file %s: %w", s.path, err)
+	}
+
+	runs := make([]*Run, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var run Run
+		if err = json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			continue
+		}
+		runs = append(runs, &run)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file %s: %w", s.path, err)
+	}
+
+	return runs, nil
+}
+
+// Prune rewrites the file keeping only the newest keep runs.
+func (s *Store) Prune(keep int) error {
+	runs, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if len(runs) <= keep {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, run := range runs[len(runs)-keep:] {
+		data, err := json.Marshal(run)
+		if err != nil {
+			return fmt.Errorf("failed to serialize run: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err = os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write history file %s: %w", tmpPath, err)
+	}
+
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to rename history file: %w", err)
+	}
+
+	return nil
+}
+
+// Find returns the run with the given ID. "last" refers to the newest run.
+func Find(runs []*Run, id string) (*Run, bool) {
+	if id == "last" && len(runs) > 0 {
+		return runs[len(runs)-1], true
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].ID == id {
+			return runs[i], true
+		}
+	}
+	return nil, false
+}
+
+// Last returns the newest n runs, oldest first.
+func Last(runs []*Run, n int) []*Run {
+	if n <= 0 || n >= len(runs) {
+		return runs
+	}
+	return runs[len(runs)-n:]
+}
@@ -0,0 +1,131 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_AppendLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".rpm", "history.jsonl")
+	store := NewStore(path)
+
+	runs, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, store.Append(&Run{
+		ID:        "run-1",
+		Command:   "build",
+		Args:      []string{"core"},
+		Commit:    "abc",
+		StartedAt: started,
+		Selected:  []string{"core:app_build"},
+		Targets: []TargetRun{
+			{ID: "core:app_build", Status: StatusSuccess, DurationMs: 1200},
+		},
+	}))
+	require.NoError(t, store.Append(&Run{ID: "run-2", Command: "test"}))
+
+	runs, err = store.Load()
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "run-1", runs[0].ID)
+	assert.Equal(t, []string{"core"}, runs[0].Args)
+	assert.True(t, started.Equal(runs[0].StartedAt))
+	assert.Equal(t, StatusSuccess, runs[0].Targets[0].Status)
+	assert.Equal(t, "run-2", runs[1].ID)
+}
+
+func TestStore_LoadSkipsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{\"id\":\"a\"}\n{\"id\":\"b\n{\"id\":\"c\"}\n"), 0644))
+
+	runs, err := NewStore(path).Load()
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "a", runs[0].ID)
+	assert.Equal(t, "c", runs[1].ID)
+}
+
+func TestStore_Prune(t *testing.T) {
+	tests := []struct {
+		name     string
+		runs     int
+		keep     int
+		expected []string
+	}{
+		{
+			name:     "fewer runs than limit",
+			runs:     2,
+			keep:     5,
+			expected: []string{"run-0", "run-1"},
+		},
+		{
+			name:     "keeps newest runs",
+			runs:     5,
+			keep:     2,
+			expected: []string{"run-3", "run-4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(filepath.Join(t.TempDir(), "history.jsonl"))
+			for i := 0; i < tt.runs; i++ {
+				require.NoError(t, store.Append(&Run{ID: fmt.Sprintf("run-%d", i)}))
+			}
+
+			require.NoError(t, store.Prune(tt.keep))
+
+			runs, err := store.Load()
+			require.NoError(t, err)
+			var ids []string
+			for _, r := range runs {
+				ids = append(ids, r.ID)
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	runs := []*Run{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	tests := []struct {
+		name     string
+		runs     []*Run
+		id       string
+		expected string
+		found    bool
+	}{
+		{name: "by id", runs: runs, id: "b", expected: "b", found: true},
+		{name: "last", runs: runs, id: "last", expected: "c", found: true},
+		{name: "unknown id", runs: runs, id: "x", found: false},
+		{name: "last of empty history", runs: nil, id: "last", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run, ok := Find(tt.runs, tt.id)
+			assert.Equal(t, tt.found, ok)
+			if tt.found {
+				assert.Equal(t, tt.expected, run.ID)
+			}
+		})
+	}
+}
+
+func TestLast(t *testing.T) {
+	runs := []*Run{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	assert.Equal(t, runs, Last(runs, 0))
+	assert.Equal(t, runs, Last(runs, 5))
+	assert.Equal(t, runs[1:], Last(runs, 2))
+}
@@ -0,0 +1,72 @@
+package history
+
+import (
+	"sort"
+)
+
+type Trend struct {
+	Target        string  `json:"target"`
+	Runs          int     `json:"runs"`
+	AvgDurationMs int64   `json:"avg_duration_ms"`
+	CacheHitRate  float64 `json:"cache_hit_rate"`
+	FailureRate   float64 `json:"failure_rate"`
+	LastStatus    Status  `json:"last_status"`
+}
+
+// Trends summarizes every target that appears in runs. AvgDurationMs only
+// counts runs where the target's command actually ran, so cache hits do not
+// pull it down.
+func Trends(runs []*Run) []Trend {
+	type acc struct {
+		runs     int
+		executed int
+		total    int64
+		cached   int
+		failed   int
+		last     Status
+	}
+
+	byTarget := make(map[string]*acc)
+	for _, run := range runs {
+		for _, t := range run.Targets {
+			a, ok := byTarget[t.ID]
+			if !ok {
+				a = &acc{}
+				byTarget[t.ID] = a
+			}
+			a.runs++
+			a.last = t.Status
+			switch t.Status {
+			case StatusCached:
+				a.cached++
+			case StatusFailed:
+				a.failed++
+			}
+			if t.Status != StatusCached && t.DurationMs > 0 {
+				a.executed++
+				a.total += t.DurationMs
+			}
+		}
+	}
+
+	trends := make([]Trend, 0, len(byTarget))
+	for id, a := range byTarget {
+		trend := Trend{
+			Target:       id,
+			Runs:         a.runs,
+			CacheHitRate: float64(a.cached) / float64(a.runs),
+			FailureRate:  float64(a.failed) / float64(a.runs),
+			LastStatus:   a.last,
+		}
+		if a.executed > 0 {
+			trend.AvgDurationMs = a.total / int64(a.executed)
+		}
+		trends = append(trends, trend)
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		return trends[i].Target < trends[j].Target
+	})
+
+	return trends
+}
@@ -0,0 +1,48 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrends(t *testing.T) {
+	runs := []*Run{
+		{Targets: []TargetRun{
+			{ID: "core:build", Status: StatusSuccess, DurationMs: 1000},
+			{ID: "core:test", Status: StatusFailed, DurationMs: 500},
+		}},
+		{Targets: []TargetRun{
+			{ID: "core:build", Status: StatusCached, DurationMs: 5},
+			{ID: "core:test", Status: StatusSuccess, DurationMs: 700},
+		}},
+		{Targets: []TargetRun{
+			{ID: "core:build", Status: StatusSuccess, DurationMs: 3000},
+		}},
+	}
+
+	trends := Trends(runs)
+
+	assert.Equal(t, []Trend{
+		{
+			Target:        "core:build",
+			Runs:          3,
+			AvgDurationMs: 2000,
+			CacheHitRate:  1.0 / 3,
+			FailureRate:   0,
+			LastStatus:    StatusSuccess,
+		},
+		{
+			Target:        "core:test",
+			Runs:          2,
+			AvgDurationMs: 600,
+			CacheHitRate:  0,
+			FailureRate:   0.5,
+			LastStatus:    StatusSuccess,
+		},
+	}, trends)
+}
+
+func TestTrends_Empty(t *testing.T) {
+	assert.Empty(t, Trends(nil))
+}
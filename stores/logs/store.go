@@ -0,0 +1,95 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Store struct {
+	dir   string
+	runID string
+}
+
+func NewStore(dir string, runID string) *Store {
+	return &Store{
+		dir:   dir,
+		runID: runID,
+	}
+}
+
+func NewRunID() string {
+	return fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405"), os.Getpid())
+}
+
+func (s *Store) RunID() string {
+	return s.runID
+}
+
+func (s *Store) RunDir() string {
+	return filepath.Join(s.dir, s.runID)
+}
+
+func (s *Store) Path(targetID string) string {
+	return filepath.Join(s.RunDir(), FileName(targetID))
+}
+
+func (s *Store) Create(targetID string) (*os.File, error) {
+	dir := s.RunDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	path := s.Path(targetID)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log file %s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+// Prune removes all but the newest keep run directories.
+func (s *Store) Prune(keep int) error {
+	return PruneRuns(s.dir, keep)
+}
+
+// PruneRuns removes all but the newest keep directories in dir, which are
+// named by run ID and so sort by time. Callers pruning before a new run
+// keep one less than they want to end up with.
+func PruneRuns(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read run directory %s: %w", dir, err)
+	}
+
+	var runs []string
+	for _, e := range entries {
+		if e.IsDir() {
+			runs = append(runs, e.Name())
+		}
+	}
+
+	if len(runs) <= keep {
+		return nil
+	}
+
+	sort.Strings(runs)
+	for _, run := range runs[:len(runs)-keep] {
+		if err = os.RemoveAll(filepath.Join(dir, run)); err != nil {
+			return fmt.Errorf("failed to remove old run %s: %w", run, err)
+		}
+	}
+
+	return nil
+}
+
+func FileName(targetID string) string {
+	return strings.NewReplacer(":", "__", "/", "_").Replace(targetID) + ".log"
+}
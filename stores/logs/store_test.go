@@ -0,0 +1,74 @@
+package logs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		targetID string
+		expected string
+	}{
+		{
+			name:     "simple target",
+			targetID: "core:app_build",
+			expected: "core__app_build.log",
+		},
+		{
+			name:     "nested bundle name",
+			targetID: "services/api:server_dev",
+			expected: "services_api__server_dev.log",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FileName(tt.targetID))
+		})
+	}
+}
+
+func TestStore_Create(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	store := NewStore(dir, "run-1")
+
+	f, err := store.Create("core:app_build")
+	require.NoError(t, err)
+	_, err = f.WriteString("hello\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	data, err := os.ReadFile(filepath.Join(dir, "run-1", "core__app_build.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(data))
+	assert.Equal(t, filepath.Join(dir, "run-1"), store.RunDir())
+}
+
+func TestStore_Prune(t *testing.T) {
+	dir := t.TempDir()
+	for _, run := range []string{"20240101-000000-1", "20240102-000000-1", "20240103-000000-1"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, run), 0755))
+	}
+
+	store := NewStore(dir, "20240103-000000-1")
+	require.NoError(t, store.Prune(2))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"20240102-000000-1", "20240103-000000-1"}, names)
+}
+
+func TestStore_PruneMissingDir(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "missing"), "run")
+	assert.NoError(t, store.Prune(5))
+}
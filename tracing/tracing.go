@@ -0,0 +1,148 @@
+// Package tracing exports OpenTelemetry spans for rpm runs over OTLP/HTTP.
+// Tracing is off unless an endpoint is configured in repo.yml or through the
+// standard OTEL_EXPORTER_OTLP_* environment variables; when off, every helper
+// here is backed by a no-op tracer.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/vcnkl/rpm/config"
+)
+
+const instrumentationName = "github.com/vcnkl/rpm"
+
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Enabled reports whether spans should be exported for cfg and the current
+// environment. OTEL_SDK_DISABLED=true and OTEL_TRACES_EXPORTER=none always
+// turn tracing off.
+func Enabled(cfg config.TracingConfig) bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+	if exporter := os.Getenv("OTEL_TRACES_EXPORTER"); exporter != "" && exporter != "otlp" {
+		return false
+	}
+	return cfg.Endpoint != "" ||
+		os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" ||
+		os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Setup installs a global tracer provider exporting to the configured OTLP
+// endpoint. Environment variables take precedence over repo.yml. The returned
+// function flushes pending spans and must be called before exiting. When
+// tracing is not enabled Setup does nothing and returns a no-op shutdown.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	if !Enabled(cfg) {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	}
+	if len(cfg.Headers) > 0 && os.Getenv("OTEL_EXPORTER_OTLP_HEADERS") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS") == "" {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+
+	return provider.Shutdown, nil
+}
+
+// StartRun starts the root span of an rpm invocation. A TRACEPARENT in rpm's
+// own environment (e.g. set by a CI system) becomes the parent.
+func StartRun(ctx context.Context, command string, args []string) (context.Context, trace.Span) {
+	ctx = propagator.Extract(ctx, envCarrier(os.Environ()))
+	return Start(ctx, "rpm "+command,
+		attribute.String("rpm.command", command),
+		attribute.StringSlice("rpm.args", args))
+}
+
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartTarget starts the span covering all phases of one target.
+func StartTarget(ctx context.Context, targetID string) (context.Context, trace.Span) {
+	return Start(ctx, targetID, attribute.String("rpm.target", targetID))
+}
+
+// StartPhase starts a child span for one phase of a target, such as hashing or
+// command execution.
+func StartPhase(ctx context.Context, phase string) (context.Context, trace.Span) {
+	return Start(ctx, phase, attribute.String("rpm.phase", phase))
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Environ returns TRACEPARENT (and TRACESTATE, BAGGAGE when present) for the
+// span in ctx, so that commands run by rpm can attach their own spans.
+func Environ(ctx context.Context) []string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+
+	var env []string
+	for _, key := range carrier.Keys() {
+		env = append(env, strings.ToUpper(key)+"="+carrier.Get(key))
+	}
+	return env
+}
+
+func envCarrier(environ []string) propagation.MapCarrier {
+	carrier := propagation.MapCarrier{}
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "TRACEPARENT", "TRACESTATE", "BAGGAGE":
+			carrier.Set(strings.ToLower(key), value)
+		}
+	}
+	return carrier
+}
@@ -0,0 +1,222 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/vcnkl/rpm/config"
+)
+
+// collector is a minimal in-process OTLP/HTTP trace receiver.
+type collector struct {
+	server   *httptest.Server
+	spans    []*tracepb.Span
+	services []string
+	mu       sync.Mutex
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{}
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			http.NotFound(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req coltracepb.ExportTraceServiceRequest
+		if err = proto.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		c.mu.Lock()
+		for _, rs := range req.ResourceSpans {
+			for _, attr := range rs.Resource.GetAttributes() {
+				if attr.Key == "service.name" {
+					c.services = append(c.services, attr.Value.GetStringValue())
+				}
+			}
+			for _, ss := range rs.ScopeSpans {
+				c.spans = append(c.spans, ss.Spans...)
+			}
+		}
+		c.mu.Unlock()
+
+		data, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(c.server.Close)
+	return c
+}
+
+func (c *collector) span(t *testing.T, name string) *tracepb.Span {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	require.Failf(t, "span not exported", "%s", name)
+	return nil
+}
+
+func clearOtelEnv(t *testing.T) {
+	for _, key := range []string{
+		"OTEL_SDK_DISABLED",
+		"OTEL_TRACES_EXPORTER",
+		"OTEL_EXPORTER_OTLP_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_HEADERS",
+		"OTEL_EXPORTER_OTLP_TRACES_HEADERS",
+		"OTEL_SERVICE_NAME",
+		"OTEL_RESOURCE_ATTRIBUTES",
+		"TRACEPARENT",
+		"TRACESTATE",
+		"BAGGAGE",
+	} {
+		t.Setenv(key, "")
+	}
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+	})
+}
+
+func TestEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.TracingConfig
+		env      map[string]string
+		expected bool
+	}{
+		{
+			name:     "nothing configured",
+			expected: false,
+		},
+		{
+			name:     "endpoint in repo.yml",
+			cfg:      config.TracingConfig{Endpoint: "http://localhost:4318"},
+			expected: true,
+		},
+		{
+			name:     "endpoint from environment",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318"},
+			expected: true,
+		},
+		{
+			name:     "traces endpoint from environment",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://localhost:4318/v1/traces"},
+			expected: true,
+		},
+		{
+			name:     "sdk disabled",
+			cfg:      config.TracingConfig{Endpoint: "http://localhost:4318"},
+			env:      map[string]string{"OTEL_SDK_DISABLED": "true"},
+			expected: false,
+		},
+		{
+			name:     "exporter none",
+			cfg:      config.TracingConfig{Endpoint: "http://localhost:4318"},
+			env:      map[string]string{"OTEL_TRACES_EXPORTER": "none"},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearOtelEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			assert.Equal(t, tt.expected, Enabled(tt.cfg))
+		})
+	}
+}
+
+func TestSetup_ExportsSpanTree(t *testing.T) {
+	clearOtelEnv(t)
+	c := newCollector(t)
+
+	ctx := context.Background()
+	shutdown, err := Setup(ctx, config.TracingConfig{Endpoint: c.server.URL, ServiceName: "rpm-test"})
+	require.NoError(t, err)
+
+	runCtx, runSpan := StartRun(ctx, "build", []string{"core"})
+	targetCtx, targetSpan := StartTarget(runCtx, "core:app_build")
+	cmdCtx, cmdSpan := StartPhase(targetCtx, "command")
+
+	env := Environ(cmdCtx)
+	require.Len(t, env, 1)
+	parts := strings.Split(strings.TrimPrefix(env[0], "TRACEPARENT="), "-")
+	require.Len(t, parts, 4)
+
+	End(cmdSpan, errors.New("exit 1"))
+	End(targetSpan, nil)
+	runSpan.End()
+
+	require.NoError(t, shutdown(ctx))
+
+	assert.Contains(t, c.services, "rpm-test")
+
+	root := c.span(t, "rpm build")
+	target := c.span(t, "core:app_build")
+	cmd := c.span(t, "command")
+
+	assert.Empty(t, root.ParentSpanId)
+	assert.Equal(t, root.SpanId, target.ParentSpanId)
+	assert.Equal(t, target.SpanId, cmd.ParentSpanId)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, cmd.Status.Code)
+	assert.Equal(t, hex.EncodeToString(cmd.TraceId), parts[1])
+	assert.Equal(t, hex.EncodeToString(cmd.SpanId), parts[2])
+}
+
+func TestStartRun_InheritsTraceparent(t *testing.T) {
+	clearOtelEnv(t)
+	c := newCollector(t)
+
+	ctx := context.Background()
+	shutdown, err := Setup(ctx, config.TracingConfig{Endpoint: c.server.URL, ServiceName: "rpm"})
+	require.NoError(t, err)
+
+	t.Setenv("TRACEPARENT", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, span := StartRun(ctx, "test", nil)
+	span.End()
+	require.NoError(t, shutdown(ctx))
+
+	root := c.span(t, "rpm test")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(root.TraceId))
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(root.ParentSpanId))
+}
+
+func TestDisabled(t *testing.T) {
+	clearOtelEnv(t)
+
+	shutdown, err := Setup(context.Background(), config.TracingConfig{})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	ctx, span := StartRun(context.Background(), "build", nil)
+	defer span.End()
+	assert.Empty(t, Environ(ctx))
+}
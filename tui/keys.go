@@ -0,0 +1,93 @@
+package tui
+
+import "unicode/utf8"
+
+type KeyType int
+
+const (
+	KeyRune KeyType = iota
+	KeyEnter
+	KeyEsc
+	KeyBackspace
+	KeyTab
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyPgUp
+	KeyPgDown
+	KeyHome
+	KeyEnd
+	KeyCtrlC
+)
+
+type Key struct {
+	Type KeyType
+	Rune rune
+}
+
+var escapes = map[string]KeyType{
+	"[A":  KeyUp,
+	"[B":  KeyDown,
+	"[C":  KeyRight,
+	"[D":  KeyLeft,
+	"[H":  KeyHome,
+	"[F":  KeyEnd,
+	"[1~": KeyHome,
+	"[4~": KeyEnd,
+	"[5~": KeyPgUp,
+	"[6~": KeyPgDown,
+	"OA":  KeyUp,
+	"OB":  KeyDown,
+	"OC":  KeyRight,
+	"OD":  KeyLeft,
+	"OH":  KeyHome,
+	"OF":  KeyEnd,
+}
+
+// ParseKeys decodes the bytes read from a terminal in raw mode. Unknown
+// escape sequences are dropped.
+func ParseKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		switch b[0] {
+		case 0x1b:
+			n, key, ok := parseEscape(b)
+			if ok {
+				keys = append(keys, key)
+			}
+			b = b[n:]
+			continue
+		case '\r', '\n':
+			keys = append(keys, Key{Type: KeyEnter})
+		case '\t':
+			keys = append(keys, Key{Type: KeyTab})
+		case 0x7f, 0x08:
+			keys = append(keys, Key{Type: KeyBackspace})
+		case 0x03:
+			keys = append(keys, Key{Type: KeyCtrlC})
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r >= ' ' {
+				keys = append(keys, Key{Type: KeyRune, Rune: r})
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+func parseEscape(b []byte) (int, Key, bool) {
+	if len(b) == 1 || (b[1] != '[' && b[1] != 'O') {
+		return 1, Key{Type: KeyEsc}, true
+	}
+	for i := 2; i < len(b) && i < 8; i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			key, ok := escapes[string(b[1:i+1])]
+			return i + 1, Key{Type: key}, ok
+		}
+	}
+	return len(b), Key{}, false
+}
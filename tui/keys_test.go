@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []Key
+	}{
+		{
+			name:     "runes",
+			input:    "ré",
+			expected: []Key{{Type: KeyRune, Rune: 'r'}, {Type: KeyRune, Rune: 'é'}},
+		},
+		{
+			name:     "arrows",
+			input:    "\x1b[A\x1b[B\x1bOC\x1b[D",
+			expected: []Key{{Type: KeyUp}, {Type: KeyDown}, {Type: KeyRight}, {Type: KeyLeft}},
+		},
+		{
+			name:     "paging",
+			input:    "\x1b[5~\x1b[6~\x1b[H\x1b[4~",
+			expected: []Key{{Type: KeyPgUp}, {Type: KeyPgDown}, {Type: KeyHome}, {Type: KeyEnd}},
+		},
+		{
+			name:     "control keys",
+			input:    "\r\t\x7f\x03",
+			expected: []Key{{Type: KeyEnter}, {Type: KeyTab}, {Type: KeyBackspace}, {Type: KeyCtrlC}},
+		},
+		{
+			name:     "lone escape",
+			input:    "\x1b",
+			expected: []Key{{Type: KeyEsc}},
+		},
+		{
+			name:     "escape followed by rune",
+			input:    "\x1bq",
+			expected: []Key{{Type: KeyEsc}, {Type: KeyRune, Rune: 'q'}},
+		},
+		{
+			name:     "unknown sequence dropped",
+			input:    "\x1b[99Zx",
+			expected: []Key{{Type: KeyRune, Rune: 'x'}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseKeys([]byte(tt.input)))
+		})
+	}
+}
@@ -0,0 +1,391 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLines is how many lines each pane keeps.
+const maxLines = 5000
+
+// allPane is the ID of the pane showing every line, including those not
+// tied to a target.
+const allPane = ""
+
+type Status struct {
+	ID     string
+	Status string
+	PID    int
+	Since  time.Time
+}
+
+type Line struct {
+	Target string
+	Level  string
+	Text   string
+}
+
+type ActionType int
+
+const (
+	ActionNone ActionType = iota
+	ActionQuit
+	ActionRestart
+	ActionRebuild
+)
+
+// Action is what a key press asks the session to do.
+type Action struct {
+	Type   ActionType
+	Target string
+}
+
+type pane struct {
+	id    string
+	lines []Line
+	// offset is how many matching lines the view is scrolled up from the
+	// bottom.
+	offset int
+}
+
+// Model is the state of the dev UI: a pane per target plus one for all
+// output, the selected pane, scrolling and the line filter.
+type Model struct {
+	panes    []*pane
+	byID     map[string]*pane
+	statuses map[string]Status
+	selected int
+	follow   bool
+	filter   string
+	editing  bool
+	message  string
+	height   int
+}
+
+func NewModel() *Model {
+	all := &pane{id: allPane}
+	return &Model{
+		panes:    []*pane{all},
+		byID:     map[string]*pane{allPane: all},
+		statuses: make(map[string]Status),
+		follow:   true,
+	}
+}
+
+// SetStatuses records the state of every target, adding panes for targets
+// not seen before in the given order. It reports whether anything changed.
+func (m *Model) SetStatuses(statuses []Status) bool {
+	changed := false
+	for _, s := range statuses {
+		if prev, ok := m.statuses[s.ID]; !ok || prev.Status != s.Status {
+			changed = true
+		}
+		m.statuses[s.ID] = s
+		m.pane(s.ID)
+	}
+	return changed
+}
+
+// Append adds a line to its target's pane and to the all pane.
+func (m *Model) Append(line Line) {
+	if line.Target != allPane {
+		m.appendTo(m.pane(line.Target), line)
+	}
+	m.appendTo(m.byID[allPane], line)
+}
+
+func (m *Model) appendTo(p *pane, line Line) {
+	p.lines = append(p.lines, line)
+	if len(p.lines) > maxLines {
+		p.lines = p.lines[len(p.lines)-maxLines:]
+	}
+	if !m.follow && m.matches(line) {
+		p.offset++
+	}
+}
+
+func (m *Model) pane(id string) *pane {
+	p, ok := m.byID[id]
+	if !ok {
+		p = &pane{id: id}
+		m.byID[id] = p
+		m.panes = append(m.panes, p)
+	}
+	return p
+}
+
+// Selected returns the ID of the selected target, or "" for the all pane.
+func (m *Model) Selected() string {
+	return m.panes[m.selected].id
+}
+
+// HandleKey applies a key press and returns the action it asks for.
+func (m *Model) HandleKey(k Key) Action {
+	if m.editing {
+		m.editFilter(k)
+		return Action{}
+	}
+	m.message = ""
+
+	page := m.height - 3
+	if page < 1 {
+		page = 1
+	}
+
+	switch k.Type {
+	case KeyCtrlC:
+		return Action{Type: ActionQuit}
+	case KeyTab, KeyRight:
+		m.selected = (m.selected + 1) % len(m.panes)
+	case KeyLeft:
+		m.selected = (m.selected + len(m.panes) - 1) % len(m.panes)
+	case KeyUp:
+		m.scroll(1)
+	case KeyDown:
+		m.scroll(-1)
+	case KeyPgUp:
+		m.scroll(page)
+	case KeyPgDown:
+		m.scroll(-page)
+	case KeyHome:
+		m.scroll(len(m.panes[m.selected].lines))
+	case KeyEnd:
+		m.panes[m.selected].offset = 0
+		m.follow = true
+	case KeyEsc:
+		m.filter = ""
+	case KeyRune:
+		return m.handleRune(k.Rune)
+	}
+	return Action{}
+}
+
+func (m *Model) handleRune(r rune) Action {
+	switch {
+	case r == 'q':
+		return Action{Type: ActionQuit}
+	case r == 'r' || r == 'b':
+		id := m.Selected()
+		if id == allPane {
+			m.message = "select a target first"
+			return Action{}
+		}
+		if r == 'r' {
+			return Action{Type: ActionRestart, Target: id}
+		}
+		return Action{Type: ActionRebuild, Target: id}
+	case r == 'f':
+		m.follow = !m.follow
+		if m.follow {
+			m.panes[m.selected].offset = 0
+		}
+	case r == '/':
+		m.editing = true
+		m.filter = ""
+	case r == 'k':
+		m.scroll(1)
+	case r == 'j':
+		m.scroll(-1)
+	case r == 'G':
+		m.panes[m.selected].offset = 0
+		m.follow = true
+	case r >= '0' && r <= '9':
+		if i := int(r - '0'); i < len(m.panes) {
+			m.selected = i
+		}
+	}
+	return Action{}
+}
+
+func (m *Model) editFilter(k Key) {
+	switch k.Type {
+	case KeyEnter:
+		m.editing = false
+	case KeyEsc, KeyCtrlC:
+		m.editing = false
+		m.filter = ""
+	case KeyBackspace:
+		if m.filter != "" {
+			_, size := utf8.DecodeLastRuneInString(m.filter)
+			m.filter = m.filter[:len(m.filter)-size]
+		}
+	case KeyRune:
+		m.filter += string(k.Rune)
+	}
+	for _, p := range m.panes {
+		p.offset = 0
+	}
+}
+
+// scroll moves the view of the selected pane up by n lines, or down for a
+// negative n. Scrolling up stops following new output.
+func (m *Model) scroll(n int) {
+	p := m.panes[m.selected]
+	p.offset += n
+	if max := len(m.visible(p)) - 1; p.offset > max {
+		p.offset = max
+	}
+	if p.offset < 0 {
+		p.offset = 0
+	}
+	if n > 0 {
+		m.follow = false
+	}
+}
+
+// SetMessage shows msg in the footer until the next key press.
+func (m *Model) SetMessage(msg string) {
+	m.message = msg
+}
+
+func (m *Model) matches(line Line) bool {
+	return m.filter == "" || strings.Contains(strings.ToLower(line.Text), strings.ToLower(m.filter))
+}
+
+func (m *Model) visible(p *pane) []Line {
+	if m.filter == "" {
+		return p.lines
+	}
+	var lines []Line
+	for _, line := range p.lines {
+		if m.matches(line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Render draws the model as height rows of at most width columns: the tab
+// bar, the selected pane's lines and a footer.
+func (m *Model) Render(width, height int) []string {
+	m.height = height
+	rows := make([]string, 0, height)
+	rows = append(rows, m.renderTabs(width))
+
+	p := m.panes[m.selected]
+	lines := m.visible(p)
+	body := height - 2
+	if body < 0 {
+		body = 0
+	}
+	end := len(lines) - p.offset
+	if end < 0 {
+		end = 0
+	}
+	start := end - body
+	if start < 0 {
+		start = 0
+	}
+	for _, line := range lines[start:end] {
+		rows = append(rows, m.renderLine(line, p.id == allPane, width))
+	}
+	for len(rows) < height-1 {
+		rows = append(rows, "")
+	}
+
+	if height > 1 {
+		rows = append(rows, m.renderFooter(width))
+	}
+	return rows
+}
+
+func (m *Model) renderTabs(width int) string {
+	var b strings.Builder
+	used := 0
+	for i, p := range m.panes {
+		label := fmt.Sprintf(" %d all ", i)
+		badge := ""
+		if p.id != allPane {
+			label = fmt.Sprintf(" %d %s ", i, p.id)
+			if s, ok := m.statuses[p.id]; ok {
+				badge = s.Status
+			}
+		}
+		n := utf8.RuneCountInString(label)
+		if badge != "" {
+			n += utf8.RuneCountInString(badge) + 1
+		}
+		if used+n > width {
+			break
+		}
+		used += n
+
+		if i == m.selected {
+			b.WriteString("\x1b[7m" + label + "\x1b[0m")
+		} else {
+			b.WriteString(label)
+		}
+		if badge != "" {
+			b.WriteString(colorize(badgeColor(badge), badge) + " ")
+		}
+	}
+	return b.String()
+}
+
+func (m *Model) renderLine(line Line, prefixed bool, width int) string {
+	text := line.Text
+	if prefixed && line.Target != "" {
+		text = line.Target + " | " + text
+	}
+	text = truncate(text, width)
+
+	switch line.Level {
+	case "error", "fatal", "panic":
+		return colorize("31", text)
+	case "warn":
+		return colorize("33", text)
+	case "debug":
+		return colorize("2", text)
+	}
+	return text
+}
+
+func (m *Model) renderFooter(width int) string {
+	if m.editing {
+		return truncate("/"+m.filter+"█", width)
+	}
+
+	var parts []string
+	if m.message != "" {
+		parts = append(parts, m.message)
+	}
+	if m.follow {
+		parts = append(parts, "following")
+	} else {
+		parts = append(parts, "paused")
+	}
+	if m.filter != "" {
+		parts = append(parts, "filter: "+m.filter)
+	}
+	parts = append(parts, "←/→ target  r restart  b rebuild  f follow  / filter  q quit")
+	return colorize("2", truncate(strings.Join(parts, " | "), width))
+}
+
+func badgeColor(status string) string {
+	switch status {
+	case "ready":
+		return "32"
+	case "failed":
+		return "31"
+	case "exited", "waiting":
+		return "2"
+	}
+	return "33"
+}
+
+func colorize(code, s string) string {
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width])
+}
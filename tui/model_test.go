@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ansi = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func plain(rows []string) []string {
+	out := make([]string, len(rows))
+	for i, row := range rows {
+		out[i] = ansi.ReplaceAllString(row, "")
+	}
+	return out
+}
+
+func runeKey(r rune) Key {
+	return Key{Type: KeyRune, Rune: r}
+}
+
+func TestModel_Panes(t *testing.T) {
+	m := NewModel()
+	m.SetStatuses([]Status{{ID: "api:server_dev", Status: "ready"}, {ID: "web:app_dev", Status: "starting"}})
+	m.Append(Line{Target: "api:server_dev", Text: "listening"})
+	m.Append(Line{Target: "web:app_dev", Text: "compiled"})
+	m.Append(Line{Text: "all services ready"})
+
+	rows := plain(m.Render(80, 6))
+	require.Len(t, rows, 6)
+	assert.Contains(t, rows[0], "0 all")
+	assert.Contains(t, rows[0], "1 api:server_dev ready")
+	assert.Contains(t, rows[0], "2 web:app_dev starting")
+	assert.Equal(t, "api:server_dev | listening", rows[1])
+	assert.Equal(t, "web:app_dev | compiled", rows[2])
+	assert.Equal(t, "all services ready", rows[3])
+
+	m.HandleKey(Key{Type: KeyTab})
+	assert.Equal(t, "api:server_dev", m.Selected())
+	rows = plain(m.Render(80, 6))
+	assert.Equal(t, "listening", rows[1])
+	assert.Equal(t, "", rows[2])
+
+	m.HandleKey(runeKey('2'))
+	assert.Equal(t, "web:app_dev", m.Selected())
+	m.HandleKey(Key{Type: KeyRight})
+	assert.Equal(t, "", m.Selected())
+	m.HandleKey(Key{Type: KeyLeft})
+	assert.Equal(t, "web:app_dev", m.Selected())
+}
+
+func TestModel_SetStatuses(t *testing.T) {
+	m := NewModel()
+	assert.True(t, m.SetStatuses([]Status{{ID: "a:dev", Status: "starting"}}))
+	assert.False(t, m.SetStatuses([]Status{{ID: "a:dev", Status: "starting"}}))
+	assert.True(t, m.SetStatuses([]Status{{ID: "a:dev", Status: "ready"}}))
+}
+
+func TestModel_Actions(t *testing.T) {
+	m := NewModel()
+	m.SetStatuses([]Status{{ID: "a:dev", Status: "ready"}})
+
+	assert.Equal(t, Action{}, m.HandleKey(runeKey('r')))
+	assert.Contains(t, plain(m.Render(80, 4))[3], "select a target first")
+
+	m.HandleKey(runeKey('1'))
+	assert.Equal(t, Action{Type: ActionRestart, Target: "a:dev"}, m.HandleKey(runeKey('r')))
+	assert.Equal(t, Action{Type: ActionRebuild, Target: "a:dev"}, m.HandleKey(runeKey('b')))
+	assert.Equal(t, Action{Type: ActionQuit}, m.HandleKey(runeKey('q')))
+	assert.Equal(t, Action{Type: ActionQuit}, m.HandleKey(Key{Type: KeyCtrlC}))
+}
+
+func TestModel_Filter(t *testing.T) {
+	m := NewModel()
+	m.Append(Line{Text: "GET /health 200"})
+	m.Append(Line{Text: "POST /orders 500"})
+	m.Append(Line{Text: "GET /orders 200"})
+
+	for _, k := range ParseKeys([]byte("/ORDERS")) {
+		assert.Equal(t, Action{}, m.HandleKey(k))
+	}
+	assert.Equal(t, "/ORDERS█", plain(m.Render(80, 5))[4])
+
+	m.HandleKey(Key{Type: KeyEnter})
+	rows := plain(m.Render(80, 5))
+	assert.Equal(t, []string{"POST /orders 500", "GET /orders 200", ""}, rows[1:4])
+	assert.Contains(t, rows[4], "filter: ORDERS")
+
+	m.HandleKey(Key{Type: KeyEsc})
+	rows = plain(m.Render(80, 5))
+	assert.Equal(t, "GET /health 200", rows[1])
+}
+
+func TestModel_Follow(t *testing.T) {
+	m := NewModel()
+	for i := 0; i < 10; i++ {
+		m.Append(Line{Text: fmt.Sprintf("line %d", i)})
+	}
+
+	rows := plain(m.Render(80, 5))
+	assert.Equal(t, []string{"line 7", "line 8", "line 9"}, rows[1:4])
+
+	m.HandleKey(Key{Type: KeyUp})
+	rows = plain(m.Render(80, 5))
+	assert.Equal(t, []string{"line 6", "line 7", "line 8"}, rows[1:4])
+	assert.Contains(t, rows[4], "paused")
+
+	m.Append(Line{Text: "line 10"})
+	rows = plain(m.Render(80, 5))
+	assert.Equal(t, []string{"line 6", "line 7", "line 8"}, rows[1:4])
+
+	m.HandleKey(runeKey('f'))
+	m.Append(Line{Text: "line 11"})
+	rows = plain(m.Render(80, 5))
+	assert.Equal(t, []string{"line 9", "line 10", "line 11"}, rows[1:4])
+	assert.Contains(t, rows[4], "following")
+
+	m.HandleKey(Key{Type: KeyHome})
+	rows = plain(m.Render(80, 5))
+	assert.Equal(t, "line 0", rows[1])
+}
+
+func TestModel_Truncates(t *testing.T) {
+	m := NewModel()
+	m.Append(Line{Text: strings.Repeat("x", 100)})
+
+	rows := plain(m.Render(20, 3))
+	assert.Equal(t, strings.Repeat("x", 20), rows[1])
+}
+
+func TestParseLogLine(t *testing.T) {
+	line, ok := ParseLogLine([]byte(`{"level":"warn","target":"a:dev","restarts":2,"time":"not a time","message":"not restarting"}`))
+	require.True(t, ok)
+	assert.Equal(t, Line{Target: "a:dev", Level: "warn", Text: "not restarting restarts=2"}, line)
+
+	_, ok = ParseLogLine([]byte("plain text"))
+	assert.False(t, ok)
+}
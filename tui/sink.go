@@ -0,0 +1,47 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ParseLogLine turns a JSON log line into a pane line: the time, the message
+// and any extra fields as key=value.
+func ParseLogLine(b []byte) (Line, bool) {
+	var fields map[string]any
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return Line{}, false
+	}
+
+	line := Line{}
+	line.Target, _ = fields["target"].(string)
+	line.Level, _ = fields["level"].(string)
+	message, _ := fields["message"].(string)
+
+	var b2 strings.Builder
+	if ts, ok := fields["time"].(string); ok {
+		if t, err := time.Parse(time.RFC3339, ts); err == nil {
+			b2.WriteString(t.Local().Format("15:04:05") + " ")
+		}
+	}
+	b2.WriteString(message)
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		switch k {
+		case "target", "level", "message", "time":
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b2, " %s=%v", k, fields[k])
+	}
+
+	line.Text = b2.String()
+	return line, true
+}
@@ -0,0 +1,167 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// refreshInterval is how often the UI polls target states and redraws.
+const refreshInterval = 100 * time.Millisecond
+
+// Controller is the dev session the UI displays and acts on.
+type Controller interface {
+	Statuses() []Status
+	Restart(targetID string) error
+	Rebuild(targetID string) error
+}
+
+// UI is a full-screen terminal view of a dev session. It is an io.Writer
+// for the session's JSON log lines, which it sorts into panes.
+type UI struct {
+	in    *os.File
+	out   *os.File
+	model *Model
+	mu    sync.Mutex
+	dirty bool
+}
+
+func New(in, out *os.File) *UI {
+	return &UI{in: in, out: out, model: NewModel()}
+}
+
+// Supported reports whether in and out are terminals the UI can take over.
+func Supported(in, out *os.File) bool {
+	return term.IsTerminal(int(in.Fd())) && term.IsTerminal(int(out.Fd()))
+}
+
+func (u *UI) Write(p []byte) (int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, raw := range bytes.Split(p, []byte("\n")) {
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+		line, ok := ParseLogLine(raw)
+		if !ok {
+			line = Line{Text: strings.TrimSpace(string(raw))}
+		}
+		u.model.Append(line)
+	}
+	u.dirty = true
+	return len(p), nil
+}
+
+// Run takes over the terminal until ctx is done or the user quits, in which
+// case quit is called. The terminal is restored before Run returns.
+func (u *UI) Run(ctx context.Context, ctrl Controller, quit func()) error {
+	state, err := term.MakeRaw(int(u.in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(u.in.Fd()), state)
+
+	u.out.WriteString("\x1b[?1049h\x1b[?25l")
+	defer u.out.WriteString("\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan []Key)
+	go u.readKeys(keys)
+
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer signal.Stop(resize)
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	u.redraw(ctrl, true)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-resize:
+			u.redraw(ctrl, true)
+		case <-ticker.C:
+			u.redraw(ctrl, false)
+		case ks := <-keys:
+			for _, k := range ks {
+				if u.handleKey(ctrl, k) {
+					quit()
+					return nil
+				}
+			}
+			u.redraw(ctrl, true)
+		}
+	}
+}
+
+func (u *UI) readKeys(keys chan<- []Key) {
+	buf := make([]byte, 64)
+	for {
+		n, err := u.in.Read(buf)
+		if err != nil {
+			return
+		}
+		keys <- ParseKeys(buf[:n])
+	}
+}
+
+// handleKey applies k and reports whether the user quit.
+func (u *UI) handleKey(ctrl Controller, k Key) bool {
+	u.mu.Lock()
+	action := u.model.HandleKey(k)
+	u.mu.Unlock()
+
+	var err error
+	switch action.Type {
+	case ActionQuit:
+		return true
+	case ActionRestart:
+		err = ctrl.Restart(action.Target)
+	case ActionRebuild:
+		err = ctrl.Rebuild(action.Target)
+	}
+	if err != nil {
+		u.mu.Lock()
+		u.model.SetMessage(err.Error())
+		u.mu.Unlock()
+	}
+	return false
+}
+
+func (u *UI) redraw(ctrl Controller, force bool) {
+	statuses := ctrl.Statuses()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if changed := u.model.SetStatuses(statuses); !force && !u.dirty && !changed {
+		return
+	}
+	u.dirty = false
+
+	width, height, err := term.GetSize(int(u.out.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, row := range u.model.Render(width, height) {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(row)
+		b.WriteString("\x1b[K")
+	}
+	b.WriteString("\x1b[J")
+	u.out.WriteString(b.String())
+}
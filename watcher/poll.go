@@ -0,0 +1,63 @@
+package watcher
+
+import (
+	"context"
+	"io/fs"
+	"time"
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	mode    fs.FileMode
+}
+
+// poll compares the files under the watched paths every PollInterval and
+// reports those created, changed or removed since the previous scan.
+func (w *Watcher) poll(ctx context.Context) error {
+	interval := w.opts.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	prev := w.scan()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		cur := w.scan()
+		for path, state := range cur {
+			if old, ok := prev[path]; !ok || old != state {
+				w.add(path)
+			}
+		}
+		for path := range prev {
+			if _, ok := cur[path]; !ok {
+				w.add(path)
+			}
+		}
+		prev = cur
+	}
+}
+
+func (w *Watcher) scan() map[string]fileState {
+	files := make(map[string]fileState)
+	for _, root := range w.paths {
+		w.walk(root, func(path string, d fs.DirEntry) {
+			if d.IsDir() {
+				return
+			}
+			info, err := d.Info()
+			if err != nil {
+				return
+			}
+			files[path] = fileState{modTime: info.ModTime(), size: info.Size(), mode: info.Mode()}
+		})
+	}
+	return files
+}
@@ -3,8 +3,10 @@ package watcher
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -12,117 +14,292 @@ import (
 	"github.com/fsnotify/fsnotify"
 )
 
+// defaultPollInterval is used when polling without an interval.
+const defaultPollInterval = time.Second
+
+type Options struct {
+	// Debounce is how long the watcher waits for further changes before
+	// reporting a batch.
+	Debounce time.Duration
+	// Poll scans the watched paths every PollInterval instead of relying on
+	// file system notifications, for file systems where those do not work.
+	Poll         bool
+	PollInterval time.Duration
+	// SkipDirs are directory names never watched, wherever they appear.
+	SkipDirs []string
+}
+
+// Batch holds the paths that changed within one debounce window. Rescan is
+// set when events were lost and every watched path may have changed.
+type Batch struct {
+	Paths  []string
+	Rescan bool
+}
+
 type Watcher struct {
-	paths    []string
-	ignore   []string
-	onChange func(path string)
-	fsw      *fsnotify.Watcher
-	mu       sync.Mutex
+	paths     []string
+	ignore    []string
+	opts      Options
+	onChange  func(Batch)
+	onError   func(error)
+	fsw       *fsnotify.Watcher
+	debouncer *Debouncer
+	mu        sync.Mutex
+	pending   map[string]bool
+	rescan    bool
+	// files are the watched files seen so far, so that those inside a
+	// directory that is removed or moved away can be reported.
+	files map[string]bool
 }
 
-func NewWatcher(paths []string, ignore []string) (*Watcher, error) {
+// NewWatcher watches paths recursively. It falls back to polling when file
+// system notifications are not available or the paths cannot all be
+// watched.
+func NewWatcher(paths []string, ignore []string, opts Options) (*Watcher, error) {
+	w := &Watcher{
+		paths:     paths,
+		ignore:    ignore,
+		opts:      opts,
+		debouncer: NewDebouncer(opts.Debounce),
+		pending:   make(map[string]bool),
+		files:     make(map[string]bool),
+	}
+	if opts.Poll {
+		return w, nil
+	}
+
 	fsw, err := fsnotify.NewWatcher()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+		w.opts.Poll = true
+		return w, nil
 	}
+	w.fsw = fsw
+	return w, nil
+}
 
-	return &Watcher{
-		paths:  paths,
-		ignore: ignore,
-		fsw:    fsw,
-	}, nil
+// Polling reports whether the watcher polls instead of using file system
+// notifications.
+func (w *Watcher) Polling() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.opts.Poll
 }
 
-func (w *Watcher) OnChange(fn func(path string)) {
+// OnChange sets the function called with each batch of changes.
+func (w *Watcher) OnChange(fn func(Batch)) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	w.onChange = fn
 }
 
+// OnError sets the function called with errors the watcher recovered from
+// by rescanning.
+func (w *Watcher) OnError(fn func(error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = fn
+}
+
 func (w *Watcher) Start(ctx context.Context) error {
+	if w.opts.Poll {
+		return w.poll(ctx)
+	}
+
 	for _, path := range w.paths {
 		if err := w.addRecursive(path); err != nil {
-			return fmt.Errorf("failed to watch path %s: %w", path, err)
-		}
-	}
-
-	debouncer := NewDebouncer(100 * time.Millisecond)
-
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case event, ok := <-w.fsw.Events:
-				if !ok {
-					return
-				}
-
-				if w.shouldIgnore(event.Name) {
-					continue
-				}
-
-				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove) != 0 {
-					debouncer.Trigger(func() {
-						w.mu.Lock()
-						fn := w.onChange
-						w.mu.Unlock()
-
-						if fn != nil {
-							fn(event.Name)
-						}
-					})
-				}
-
-				if event.Op&fsnotify.Create != 0 {
-					info, err := os.Stat(event.Name)
-					if err == nil && info.IsDir() {
-						w.addRecursive(event.Name)
-					}
-				}
-			case err, ok := <-w.fsw.Errors:
-				if !ok {
-					return
-				}
-				if err != nil {
-				}
-			}
+			// Most likely out of inotify watches; polling still works.
+			w.reportError(err)
+			w.fsw.Close()
+			w.mu.Lock()
+			w.opts.Poll = true
+			w.mu.Unlock()
+			return w.poll(ctx)
 		}
-	}()
+	}
 
-	<-ctx.Done()
-	return nil
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			w.handleEvent(event)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			w.reportError(err)
+			w.rescanAll()
+		}
+	}
 }
 
 func (w *Watcher) Stop() {
-	w.fsw.Close()
+	if w.fsw != nil {
+		w.fsw.Close()
+	}
+}
+
+func (w *Watcher) handleEvent(event fsnotify.Event) {
+	// Paths inside skipped directories produce no events, as those are not
+	// watched, but the directories themselves do.
+	if w.shouldIgnore(event.Name) || w.skipDir(filepath.Base(event.Name)) {
+		return
+	}
+
+	switch {
+	case event.Op&fsnotify.Create != 0:
+		info, err := os.Stat(event.Name)
+		if err != nil || !info.IsDir() {
+			w.add(event.Name)
+			return
+		}
+		// Files moved or written into a new directory before it was watched
+		// produce no events of their own.
+		if err = w.addRecursive(event.Name); err != nil {
+			w.reportError(err)
+		}
+		w.walk(event.Name, func(path string, d fs.DirEntry) {
+			if !d.IsDir() {
+				w.add(path)
+			}
+		})
+	case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		// A renamed directory keeps its watch under the old name.
+		_ = w.fsw.Remove(event.Name)
+		w.add(event.Name)
+		// A directory moved away produces no events for the files in it.
+		for _, path := range w.forgetUnder(event.Name) {
+			w.add(path)
+		}
+	case event.Op&(fsnotify.Write|fsnotify.Chmod) != 0:
+		w.add(event.Name)
+	}
+}
+
+// forgetUnder removes the known files below dir and returns them.
+func (w *Watcher) forgetUnder(dir string) []string {
+	prefix := strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var paths []string
+	for path := range w.files {
+		if strings.HasPrefix(path, prefix) {
+			paths = append(paths, path)
+			delete(w.files, path)
+		}
+	}
+	return paths
+}
+
+// rescanAll re-adds every watched directory, picking up those created while
+// events were lost, and reports a rescan.
+func (w *Watcher) rescanAll() {
+	for _, path := range w.paths {
+		if err := w.addRecursive(path); err != nil {
+			w.reportError(err)
+		}
+	}
+
+	w.mu.Lock()
+	w.rescan = true
+	w.mu.Unlock()
+	w.debouncer.Trigger(w.flush)
+}
+
+func (w *Watcher) add(path string) {
+	w.mu.Lock()
+	w.pending[path] = true
+	if w.fsw != nil {
+		if _, err := os.Lstat(path); err == nil {
+			w.files[path] = true
+		} else {
+			delete(w.files, path)
+		}
+	}
+	w.mu.Unlock()
+	w.debouncer.Trigger(w.flush)
+}
+
+func (w *Watcher) flush() {
+	w.mu.Lock()
+	batch := Batch{Rescan: w.rescan}
+	for path := range w.pending {
+		batch.Paths = append(batch.Paths, path)
+	}
+	w.pending = make(map[string]bool)
+	w.rescan = false
+	fn := w.onChange
+	w.mu.Unlock()
+
+	if fn == nil || (len(batch.Paths) == 0 && !batch.Rescan) {
+		return
+	}
+	sort.Strings(batch.Paths)
+	fn(batch)
+}
+
+func (w *Watcher) reportError(err error) {
+	w.mu.Lock()
+	fn := w.onError
+	w.mu.Unlock()
+
+	if fn != nil {
+		fn(err)
+	}
 }
 
 func (w *Watcher) addRecursive(root string) error {
-	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+	var addErr error
+	w.walk(root, func(path string, d fs.DirEntry) {
+		if !d.IsDir() {
+			w.mu.Lock()
+			w.files[path] = true
+			w.mu.Unlock()
+			return
+		}
+		if addErr != nil {
+			return
+		}
+		if err := w.fsw.Add(path); err != nil && !os.IsNotExist(err) {
+			addErr = fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+	})
+	return addErr
+}
+
+// walk calls fn for every path under root that is not ignored.
+func (w *Watcher) walk(root string, fn func(path string, d fs.DirEntry)) {
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil
 		}
-
-		if info.IsDir() {
-			base := filepath.Base(path)
-			if base == ".git" || base == "node_modules" || base == ".venv" || base == "__pycache__" {
-				return filepath.SkipDir
-			}
-
-			if w.shouldIgnore(path) {
+		if d.IsDir() && path != root && w.skipDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		if w.shouldIgnore(path) {
+			if d.IsDir() {
 				return filepath.SkipDir
 			}
-
-			if err = w.fsw.Add(path); err != nil {
-				return nil
-			}
+			return nil
 		}
-
+		fn(path, d)
 		return nil
 	})
 }
 
+func (w *Watcher) skipDir(name string) bool {
+	for _, dir := range w.opts.SkipDirs {
+		if name == dir {
+			return true
+		}
+	}
+	return false
+}
+
 func (w *Watcher) shouldIgnore(path string) bool {
 	for _, pattern := range w.ignore {
 		if strings.Contains(pattern, "**") {
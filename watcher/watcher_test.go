@@ -0,0 +1,167 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+// startWatcher starts a watcher on root and returns a channel receiving its
+// batches.
+func startWatcher(t *testing.T, root string, opts Options) (*Watcher, <-chan Batch) {
+	if opts.Debounce == 0 {
+		opts.Debounce = 50 * time.Millisecond
+	}
+	w, err := NewWatcher([]string{root}, nil, opts)
+	require.NoError(t, err)
+
+	batches := make(chan Batch, 16)
+	w.OnChange(func(b Batch) { batches <- b })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		w.Stop()
+	})
+	go w.Start(ctx)
+	// let the watcher register its watches or take its first scan
+	time.Sleep(150 * time.Millisecond)
+	return w, batches
+}
+
+// collect returns the paths of the batches received until none arrives for
+// a while.
+func collect(t *testing.T, batches <-chan Batch) ([]Batch, []string) {
+	var received []Batch
+	var paths []string
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case b := <-batches:
+			received = append(received, b)
+			paths = append(paths, b.Paths...)
+		case <-time.After(400 * time.Millisecond):
+			if len(received) > 0 {
+				return received, paths
+			}
+		case <-timeout:
+			t.Fatal("no changes reported")
+		}
+	}
+}
+
+func TestWatcher_BatchesChanges(t *testing.T) {
+	root := t.TempDir()
+	_, batches := startWatcher(t, root, Options{})
+
+	for _, name := range []string{"c.go", "a.go", "b.go"} {
+		writeFile(t, filepath.Join(root, name), "package x")
+	}
+
+	received, _ := collect(t, batches)
+	require.Len(t, received, 1)
+	assert.Equal(t, []string{
+		filepath.Join(root, "a.go"),
+		filepath.Join(root, "b.go"),
+		filepath.Join(root, "c.go"),
+	}, received[0].Paths)
+	assert.False(t, received[0].Rescan)
+}
+
+func TestWatcher_NewDirectory(t *testing.T) {
+	root := t.TempDir()
+	_, batches := startWatcher(t, root, Options{})
+
+	staged := filepath.Join(t.TempDir(), "pkg")
+	writeFile(t, filepath.Join(staged, "sub", "a.go"), "package sub")
+	require.NoError(t, os.Rename(staged, filepath.Join(root, "pkg")))
+
+	_, paths := collect(t, batches)
+	assert.Contains(t, paths, filepath.Join(root, "pkg", "sub", "a.go"))
+	assert.NotContains(t, paths, filepath.Join(root, "pkg"))
+
+	// files in the new directory are watched
+	writeFile(t, filepath.Join(root, "pkg", "sub", "b.go"), "package sub")
+	_, paths = collect(t, batches)
+	assert.Equal(t, []string{filepath.Join(root, "pkg", "sub", "b.go")}, paths)
+}
+
+func TestWatcher_RemoveAndRename(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "main.go"), "package main")
+	writeFile(t, filepath.Join(root, "pkg", "sub", "a.go"), "package sub")
+	writeFile(t, filepath.Join(root, "pkg", "b.go"), "package pkg")
+	_, batches := startWatcher(t, root, Options{})
+
+	require.NoError(t, os.Remove(filepath.Join(root, "main.go")))
+	_, paths := collect(t, batches)
+	assert.Equal(t, []string{filepath.Join(root, "main.go")}, paths)
+
+	require.NoError(t, os.Rename(filepath.Join(root, "pkg"), filepath.Join(t.TempDir(), "moved")))
+	_, paths = collect(t, batches)
+	assert.Contains(t, paths, filepath.Join(root, "pkg"))
+	assert.Contains(t, paths, filepath.Join(root, "pkg", "b.go"))
+	assert.Contains(t, paths, filepath.Join(root, "pkg", "sub", "a.go"))
+}
+
+func TestWatcher_SkipDirs(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "node_modules", "x"), 0755))
+	_, batches := startWatcher(t, root, Options{SkipDirs: []string{"node_modules"}})
+
+	writeFile(t, filepath.Join(root, "node_modules", "x", "index.js"), "")
+	writeFile(t, filepath.Join(root, "main.go"), "package main")
+
+	_, paths := collect(t, batches)
+	assert.Equal(t, []string{filepath.Join(root, "main.go")}, paths)
+}
+
+func TestWatcher_RescanOnError(t *testing.T) {
+	root := t.TempDir()
+	w, batches := startWatcher(t, root, Options{})
+
+	reported := make(chan error, 1)
+	w.OnError(func(err error) { reported <- err })
+
+	w.fsw.Errors <- errors.New("queue overflow")
+
+	received, _ := collect(t, batches)
+	require.Len(t, received, 1)
+	assert.True(t, received[0].Rescan)
+	select {
+	case err := <-reported:
+		assert.EqualError(t, err, "queue overflow")
+	default:
+		t.Fatal("error not reported")
+	}
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "existing.go")
+	removed := filepath.Join(root, "removed.go")
+	writeFile(t, existing, "package x")
+	writeFile(t, removed, "package x")
+
+	w, batches := startWatcher(t, root, Options{Poll: true, PollInterval: 20 * time.Millisecond})
+	assert.True(t, w.Polling())
+
+	created := filepath.Join(root, "dir", "created.go")
+	writeFile(t, created, "package dir")
+	writeFile(t, existing, "package x // changed")
+	require.NoError(t, os.Remove(removed))
+
+	_, paths := collect(t, batches)
+	assert.ElementsMatch(t, []string{created, existing, removed}, paths)
+}